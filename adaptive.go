@@ -0,0 +1,76 @@
+package rolling
+
+import "time"
+
+const (
+	// adaptInterval is how often adaptive rotation samples free space.
+	adaptInterval = time.Minute
+	// defaultAdaptiveHorizon is the AdaptiveHorizon used when it isn't set.
+	defaultAdaptiveHorizon = 6 * time.Hour
+	// maxAdaptShift bounds how far adaptive rotation tightens MaxSize: to
+	// 1/2^maxAdaptShift of the configured size.
+	maxAdaptShift = 3
+)
+
+// adaptState tracks the free space trend on the log volume for adaptive
+// rotation. It is protected by the write mutex.
+type adaptState struct {
+	sampledAt time.Time
+	free      int64
+	// rate is a moving average of how fast free space is shrinking, in bytes
+	// per second; negative if it is growing.
+	rate  float64
+	shift uint
+}
+
+// adapt samples free space if it's time to and adjusts the effective MaxSize:
+// halving it whenever the volume is projected to fill up within
+// AdaptiveHorizon at the current rate, and doubling it back, up to MaxSize,
+// once it's projected to last more than twice that.
+func (l *Logger) adapt() {
+	now := currentTime()
+	a := &l.adaptive
+	if !a.sampledAt.IsZero() && now.Sub(a.sampledAt) < adaptInterval {
+		return
+	}
+	free, err := diskFree(l.LogPath)
+	if err != nil {
+		return
+	}
+	if a.sampledAt.IsZero() {
+		a.sampledAt, a.free = now, free
+		return
+	}
+
+	rate := float64(a.free-free) / now.Sub(a.sampledAt).Seconds()
+	a.rate = (a.rate + rate) / 2
+	a.sampledAt, a.free = now, free
+
+	horizon := l.AdaptiveHorizon
+	if horizon <= 0 {
+		horizon = defaultAdaptiveHorizon
+	}
+	var left time.Duration
+	if a.rate > 0 {
+		left = secondsToDuration(float64(free) / a.rate)
+	}
+	switch {
+	case a.rate > 0 && left < horizon:
+		if a.shift < maxAdaptShift {
+			a.shift++
+		}
+	case a.rate <= 0 || left > 2*horizon:
+		if a.shift > 0 {
+			a.shift--
+		}
+	}
+}
+
+// rotateSize returns the size in bytes at which the file is rotated: MaxSize,
+// unless adaptive rotation has tightened it.
+func (l *Logger) rotateSize() int64 {
+	if !l.AdaptiveRotation {
+		return l.max()
+	}
+	return l.max() >> l.adaptive.shift
+}
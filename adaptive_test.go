@@ -0,0 +1,54 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveRotation(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	free := int64(1000000)
+	diskFree = func(string) (int64, error) { return free, nil }
+	defer func() { diskFree = freeSpace }()
+
+	dir := makeTempDir("TestAdaptiveRotation", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(80),
+		WithAdaptiveRotation(time.Hour))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	step := func(delta int64) int64 {
+		fakeCurrentTime = fakeCurrentTime.Add(time.Minute)
+		free += delta
+		_, err := l.Write([]byte("x"))
+		isNil(err, t)
+		return l.Stats().RotationSize
+	}
+
+	equals(int64(80), step(0), t)
+	// losing 1000 bytes a second leaves well under an hour
+	equals(int64(40), step(-60000), t)
+	equals(int64(20), step(-60000), t)
+	equals(int64(10), step(-60000), t)
+	equals(int64(10), step(-60000), t)
+
+	// space is freed up again
+	equals(int64(20), step(600000), t)
+	equals(int64(40), step(0), t)
+	equals(int64(80), step(0), t)
+	equals(int64(80), step(0), t)
+}
@@ -0,0 +1,103 @@
+package rolling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ageSuffix is appended to the name of backups encrypted with age.
+const ageSuffix = ".age"
+
+// AgeEncrypter encrypts backups in the age format (https://age-encryption.org)
+// to a set of recipients, typically X25519 public keys, so that only the
+// holders of the matching identities can read them and the process itself
+// never has a secret. It's kept this small so that this package doesn't
+// depend on an age implementation; with filippo.io/age:
+//
+//	type ageRecipients []age.Recipient
+//
+//	func (r ageRecipients) Encrypt(dst io.Writer) (io.WriteCloser, error) {
+//		return age.Encrypt(dst, r...)
+//	}
+//
+// with the recipients from age.ParseX25519Recipient. Closing the returned
+// writer must finish the encrypted file, without closing dst.
+type AgeEncrypter interface {
+	Encrypt(dst io.Writer) (io.WriteCloser, error)
+}
+
+// AgeDecrypter may be implemented by an AgeEncrypter that also holds the
+// identities of its recipients, such as a test or a tool reading backups;
+// with filippo.io/age, Decrypt returns age.Decrypt(src, identities...).
+// Without one, backups encrypted with age can still be read with the age
+// command, but OpenBackup, Verify and PurgeRecords refuse them.
+type AgeDecrypter interface {
+	Decrypt(src io.Reader) (io.Reader, error)
+}
+
+// encrypts reports whether backups are encrypted, with keys or with age.
+func (l *Logger) encrypts() bool {
+	return l.keys != nil || l.age != nil
+}
+
+// encryptBackup encrypts the file at src, with age if useAge is set or else
+// with the current key, into a file named after it and removes src. It
+// returns the path of the encrypted file.
+func (l *Logger) encryptBackup(src string, useAge bool) (string, error) {
+	if !useAge {
+		if l.keys == nil {
+			return "", errors.New("no encryption keys configured")
+		}
+		return src + encryptSuffix, encryptLogFile(src, src+encryptSuffix, l.keys)
+	}
+	if l.age == nil {
+		return "", errors.New("no age recipients configured")
+	}
+	return src + ageSuffix, ageEncryptLogFile(src, src+ageSuffix, l.age)
+}
+
+// decrypt returns a reader for the plaintext of the encrypted backup f, read
+// from r.
+func (l *Logger) decrypt(r io.Reader, f logInfo) (io.Reader, error) {
+	if !f.age {
+		if l.keys == nil {
+			return nil, errNoKeys
+		}
+		return newDecryptReader(r, l.keys)
+	}
+	dec, ok := l.age.(AgeDecrypter)
+	if !ok {
+		return nil, errNoAgeIdentities
+	}
+	// no identity matching is what a header that can't be opened most
+	// likely means, not damage
+	pr, err := dec.Decrypt(r)
+	if err != nil {
+		return nil, &keyError{Err: err}
+	}
+	return pr, nil
+}
+
+// ageEncryptLogFile encrypts src into dst with enc and removes src, like
+// encryptLogFile.
+func ageEncryptLogFile(src, dst string, enc AgeEncrypter) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+	if err := writeSealed(dst, fi, f, enc.Encrypt); err != nil {
+		return fmt.Errorf("failed to encrypt log file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,119 @@
+package rolling
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// fakeAge stands in for filippo.io/age, with a header naming the recipient
+// and a keystream that's just the recipient's first byte.
+type fakeAge struct {
+	recipient string
+}
+
+const fakeAgeHeader = "age-encryption.org/v1\n"
+
+func (a fakeAge) Encrypt(dst io.Writer) (io.WriteCloser, error) {
+	if _, err := io.WriteString(dst, fakeAgeHeader+a.recipient+"\n"); err != nil {
+		return nil, err
+	}
+	return &xorWriter{w: dst, key: a.recipient[0]}, nil
+}
+
+func (a fakeAge) Decrypt(src io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(src)
+	header, err := br.ReadString('\n')
+	if err != nil || header != fakeAgeHeader {
+		return nil, errors.New("not an age file")
+	}
+	if recipient, err := br.ReadString('\n'); err != nil || recipient != a.recipient+"\n" {
+		return nil, errors.New("no identity matched any of the recipients")
+	}
+	b, err := ioutil.ReadAll(br)
+	for i := range b {
+		b[i] ^= a.recipient[0]
+	}
+	return bytes.NewReader(b), err
+}
+
+type xorWriter struct {
+	w   io.Writer
+	key byte
+}
+
+func (x *xorWriter) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	for i := range p {
+		b[i] = p[i] ^ x.key
+	}
+	return x.w.Write(b)
+}
+
+func (x *xorWriter) Close() error { return nil }
+
+// ageRecipientsOnly is an AgeEncrypter without identities, as in production.
+type ageRecipientsOnly struct {
+	age fakeAge
+}
+
+func (a ageRecipientsOnly) Encrypt(dst io.Writer) (io.WriteCloser, error) {
+	return a.age.Encrypt(dst)
+}
+
+func TestAgeEncryption(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestAgeEncryption", t)
+	defer os.RemoveAll(dir)
+
+	age := fakeAge{recipient: "age1recipient"}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress(),
+		WithSynchronousMill(), WithAgeEncryption(age))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	// compressed, then encrypted
+	backup := backupFile(dir) + compressSuffix + ageSuffix
+	exists(backup, t)
+	notExist(backupFile(dir), t)
+	notExist(backupFile(dir)+compressSuffix, t)
+	b, err := ioutil.ReadFile(backup)
+	isNil(err, t)
+	equals(true, bytes.HasPrefix(b, []byte(fakeAgeHeader)), t)
+
+	r, err := l.OpenBackup(backup)
+	isNil(err, t)
+	b, err = ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals([]byte("boo!"), b, t)
+
+	corrupt, err := l.Verify()
+	isNil(err, t)
+	equals(0, len(corrupt), t)
+
+	// without identities the backups can't be read back
+	l2 := &Logger{Config: l.Config, age: ageRecipientsOnly{age}}
+	_, err = l2.OpenBackup(backup)
+	notNil(err, t)
+}
+
+func TestAgeAndKeysExclusive(t *testing.T) {
+	dir := makeTempDir("TestAgeAndKeysExclusive", t)
+	defer os.RemoveAll(dir)
+
+	_, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithEncryption(testKey("a")),
+		WithAgeEncryption(fakeAge{recipient: "age1recipient"}))
+	notNil(err, t)
+}
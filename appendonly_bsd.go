@@ -0,0 +1,37 @@
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package rolling
+
+import (
+	"os"
+	"syscall"
+)
+
+// ufAppend is the user append-only flag of chflags(2).
+const ufAppend = 0x4
+
+// setAppendOnly sets or clears the append-only flag of the file at path. It
+// does nothing on filesystems without file flags.
+func setAppendOnly(path string, on bool) error {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	flags := int(st.Flags)
+	if (flags&ufAppend != 0) == on {
+		return nil
+	}
+	if on {
+		flags |= ufAppend
+	} else {
+		flags &^= ufAppend
+	}
+	if err := syscall.Chflags(path, flags); err != nil {
+		if err == syscall.EOPNOTSUPP {
+			return nil
+		}
+		return &os.PathError{Op: "chflags", Path: path, Err: err}
+	}
+	return nil
+}
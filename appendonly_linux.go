@@ -0,0 +1,67 @@
+//go:build linux
+// +build linux
+
+package rolling
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl requests from linux/fs.h, which are declared as taking a long but
+// are implemented as reading and writing an int.
+var (
+	fsIocGetflags = ioc(2, 1)
+	fsIocSetflags = ioc(1, 2)
+)
+
+const fsAppendFl = 0x20
+
+// ioc encodes the ioctl request number nr of the "f" family for dir, 1 for
+// write and 2 for read, as the _IOR and _IOW macros do.
+func ioc(dir, nr uintptr) uintptr {
+	return dir<<30 | unsafe.Sizeof(uintptr(0))<<16 | 'f'<<8 | nr
+}
+
+// setAppendOnly sets or clears the append-only attribute of the file at
+// path. It does nothing on filesystems without attributes.
+func setAppendOnly(path string, on bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var flags int32
+	if err := ioctl(f, fsIocGetflags, &flags); err != nil {
+		if unsupported(err) {
+			return nil
+		}
+		return &os.PathError{Op: "get attributes", Path: path, Err: err}
+	}
+	if (flags&fsAppendFl != 0) == on {
+		return nil
+	}
+	if on {
+		flags |= fsAppendFl
+	} else {
+		flags &^= fsAppendFl
+	}
+	if err := ioctl(f, fsIocSetflags, &flags); err != nil {
+		return &os.PathError{Op: "set attributes", Path: path, Err: err}
+	}
+	return nil
+}
+
+func ioctl(f *os.File, req uintptr, flags *int32) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(flags)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func unsupported(err error) bool {
+	return err == syscall.ENOTTY || err == syscall.EOPNOTSUPP || err == syscall.ENOSYS || err == syscall.EINVAL
+}
@@ -0,0 +1,76 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func appendOnly(path string, t testing.TB) bool {
+	f, err := os.Open(path)
+	isNilUp(err, t, 1)
+	defer f.Close()
+	var flags int32
+	isNilUp(ioctl(f, fsIocGetflags, &flags), t, 1)
+	return flags&fsAppendFl != 0
+}
+
+func TestAppendOnly(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestAppendOnly", t)
+	defer func() {
+		files, _ := filepath.Glob(filepath.Join(dir, "*"))
+		for _, f := range files {
+			_ = setAppendOnly(f, false)
+		}
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	probe := filepath.Join(dir, "probe")
+	isNil(ioutil.WriteFile(probe, nil, 0644), t)
+	if err := setAppendOnly(probe, true); err != nil || !appendOnly(probe, t) {
+		t.Skipf("can't set the append-only attribute here: %v", err)
+	}
+	isNil(setAppendOnly(probe, false), t)
+	isNil(os.Remove(probe), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(1),
+		WithCompress(), WithAppendOnly(), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	equals(true, appendOnly(logFile(dir), t), t)
+	// the file can't be tampered with
+	notNil(os.Truncate(logFile(dir), 0), t)
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	first := backupFile(dir) + compressSuffix
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+	exists(first, t)
+	equals(true, appendOnly(first, t), t)
+	notNil(os.Remove(first), t)
+
+	// retention still removes backups
+	newFakeTime()
+	_, err = l.Write([]byte("1111111!"))
+	isNil(err, t)
+	notExist(first, t)
+	fileCount(dir, 2, t)
+
+	backups, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	_, err = l.PurgeRecords(backups, func([]byte) bool { return true })
+	equals(errAppendOnly, err, t)
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package rolling
+
+// setAppendOnly does nothing on platforms without an append-only attribute.
+func setAppendOnly(path string, on bool) error {
+	return nil
+}
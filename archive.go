@@ -0,0 +1,98 @@
+package rolling
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// backupDir returns the directory backups are kept in: ArchiveDir if set,
+// LogPath otherwise.
+func (l *Logger) backupDir() string {
+	if l.ArchiveDir != "" {
+		return l.ArchiveDir
+	}
+	return l.LogPath
+}
+
+// BackupDir returns the absolute path of the directory backups are kept in:
+// ArchiveDir if set, LogPath otherwise, resolved against the current working
+// directory if relative.
+func (l *Logger) BackupDir() string {
+	dir, err := filepath.Abs(l.backupDir())
+	if err != nil {
+		return l.backupDir()
+	}
+	return dir
+}
+
+// archiveBackups moves the backups left in LogPath by rotation to
+// ArchiveDir. l.millMu must be held.
+func (l *Logger) archiveBackups() error {
+	if l.ArchiveDir == "" || filepath.Clean(l.ArchiveDir) == filepath.Clean(l.LogPath) {
+		return nil
+	}
+	files, err := l.logFilesIn(l.LogPath)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	if err := os.MkdirAll(l.ArchiveDir, l.dirMode()); err != nil {
+		return err
+	}
+	for _, f := range files {
+		src := filepath.Join(l.LogPath, f.Name())
+		if err := l.move(src, filepath.Join(l.ArchiveDir, f.Name())); err != nil {
+			return fmt.Errorf("can't archive backup %s: %v", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// move moves the file at src to dst, copying it if it can't simply be
+// renamed, as when dst is on another volume. A copy only takes the name dst
+// once complete and synced to disk, and src is removed after that.
+func (l *Logger) move(src, dst string) error {
+	if err := l.rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + tmpSuffix
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if l.AppendOnly {
+		if err := setAppendOnly(dst, true); err != nil {
+			return err
+		}
+	}
+	_ = in.Close()
+	return l.remove(src)
+}
@@ -0,0 +1,71 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiveDir(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestArchiveDir", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(1),
+		WithCompress(), WithArchiveDir(archive), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := filepath.Base(backupFile(dir))
+	notExist(filepath.Join(dir, first), t)
+	exists(filepath.Join(archive, first+compressSuffix), t)
+
+	// retention applies to the archive
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	notExist(filepath.Join(archive, first+compressSuffix), t)
+	exists(filepath.Join(archive, filepath.Base(backupFile(dir))+compressSuffix), t)
+	fileCount(archive, 1, t)
+	existsWithContent(logFile(dir), []byte("baz!qux!"), t)
+
+	u, err := l.DiskUsage()
+	isNil(err, t)
+	equals(int64(8), u.Active, t)
+	assert(u.Compressed > 0, t, "archived backup not counted")
+}
+
+func TestMoveAcrossVolumes(t *testing.T) {
+	dir := makeTempDir("TestMoveAcrossVolumes", t)
+	defer os.RemoveAll(dir)
+	other, err := ioutil.TempDir("/dev/shm", "TestMoveAcrossVolumes")
+	if err != nil {
+		t.Skip("no /dev/shm")
+	}
+	defer os.RemoveAll(other)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(other, "dst")
+	isNil(ioutil.WriteFile(src, []byte("boo!"), 0600), t)
+	mtime := fakeTime().Truncate(time.Second)
+	isNil(os.Chtimes(src, mtime, mtime), t)
+
+	l := &Logger{}
+	isNil(l.move(src, dst), t)
+	notExist(src, t)
+	notExist(dst+tmpSuffix, t)
+	existsWithContent(dst, []byte("boo!"), t)
+	fi, err := os.Stat(dst)
+	isNil(err, t)
+	equals(mtime.Unix(), fi.ModTime().Unix(), t)
+	equals(os.FileMode(0600), fi.Mode(), t)
+}
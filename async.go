@@ -0,0 +1,295 @@
+package rolling
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var errClosed = errors.New("log file is closed")
+
+// defaultAsyncQueueSize is the number of writes an async Logger queues when
+// AsyncQueueSize isn't set.
+const defaultAsyncQueueSize = 1024
+
+// asyncQueue holds the writes of an async Logger until its writer goroutine
+// gets to them. It is a ring of limit writes which writers add to without
+// taking a lock, claiming a slot with a compare-and-swap, so that many
+// goroutines logging at once don't contend for a mutex; only the writer
+// goroutine takes writes out. To absorb a short spike rather than drop it,
+// up to burst more writes go to an overflow list, grown as needed while the
+// ring is full and let go once the writer goroutine has taken them out.
+type asyncQueue struct {
+	// head is the position the next write goes to, and tail the one the
+	// writer goroutine reads next, which only it touches. enqueued counts
+	// the writes queued. They come first to be 64-bit aligned.
+	head     uint64
+	tail     uint64
+	enqueued uint64
+
+	slots []asyncSlot
+	size  uint64
+	limit int
+
+	// queued is the number of writes in the queue, and above is set while
+	// it's over limit, until the writer goroutine catches up.
+	queued int64
+	above  int32
+
+	// overflowing is set while overflow holds writes, so that the writes
+	// that follow queue behind them rather than in the ring. Both are
+	// guarded by overflowMu.
+	overflowing int32
+	overflowMu  sync.Mutex
+	overflow    [][]byte
+	burst       int
+
+	// closed is set by closeAsync, and producers counts the writers between
+	// checking it and being done with the queue, so that the writer
+	// goroutine doesn't leave before they are.
+	closed    int32
+	producers int32
+
+	// sleeping is set while the writer goroutine waits on wake for writes.
+	sleeping int32
+	wake     chan struct{}
+	done     chan struct{}
+
+	// spare is the writer goroutine's batch, kept between passes.
+	spare [][]byte
+
+	// written counts the writes done, and drained is signalled as it
+	// catches up with enqueued, for wait.
+	mu      sync.Mutex
+	written uint64
+	drained *sync.Cond
+}
+
+// asyncSlot is a place in the ring. seq tells whose turn it is: the slot is
+// free for the write at position p when seq is p, and holds it when seq is
+// p+1.
+type asyncSlot struct {
+	seq  uint64
+	data []byte
+}
+
+func newAsyncQueue(limit, burst int) *asyncQueue {
+	if limit <= 0 {
+		limit = defaultAsyncQueueSize
+	}
+	if burst < 0 {
+		burst = 0
+	}
+	q := &asyncQueue{
+		slots: make([]asyncSlot, limit),
+		size:  uint64(limit),
+		limit: limit,
+		burst: burst,
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+		spare: make([][]byte, 0, limit),
+	}
+	for i := range q.slots {
+		q.slots[i].seq = uint64(i)
+	}
+	q.drained = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds b to the queue, unless it's full even with the burst allowance.
+func (q *asyncQueue) push(b []byte) bool {
+	if atomic.LoadInt32(&q.overflowing) == 0 && q.pushRing(b) {
+		return true
+	}
+	return q.pushOverflow(b)
+}
+
+// pushRing adds b to the ring, unless it's full.
+func (q *asyncQueue) pushRing(b []byte) bool {
+	for {
+		pos := atomic.LoadUint64(&q.head)
+		slot := &q.slots[pos%q.size]
+		seq := atomic.LoadUint64(&slot.seq)
+		switch {
+		case seq == pos:
+			if atomic.CompareAndSwapUint64(&q.head, pos, pos+1) {
+				slot.data = b
+				atomic.StoreUint64(&slot.seq, pos+1)
+				return true
+			}
+		case seq < pos:
+			// the slot still holds the write from a lap before
+			return false
+		}
+		// another writer got there first
+	}
+}
+
+// pushOverflow adds b to the overflow, unless it already holds burst writes.
+func (q *asyncQueue) pushOverflow(b []byte) bool {
+	q.overflowMu.Lock()
+	defer q.overflowMu.Unlock()
+	if len(q.overflow) >= q.burst {
+		return false
+	}
+	q.overflow = append(q.overflow, b)
+	atomic.StoreInt32(&q.overflowing, 1)
+	return true
+}
+
+// takeOverflow appends the writes in the overflow to batch and lets the
+// overflow go, back to the ring's bounded size. Only the writer goroutine may
+// call it, once it has taken out the writes in the ring, which came first.
+func (q *asyncQueue) takeOverflow(batch [][]byte) [][]byte {
+	if atomic.LoadInt32(&q.overflowing) == 0 {
+		return batch
+	}
+	q.overflowMu.Lock()
+	defer q.overflowMu.Unlock()
+	batch = append(batch, q.overflow...)
+	atomic.AddInt64(&q.queued, -int64(len(q.overflow)))
+	q.overflow = nil
+	atomic.StoreInt32(&q.overflowing, 0)
+	return batch
+}
+
+// pop takes the next write out of the ring, if there is one. Only the
+// writer goroutine may call it.
+func (q *asyncQueue) pop() ([]byte, bool) {
+	slot := &q.slots[q.tail%q.size]
+	if atomic.LoadUint64(&slot.seq) != q.tail+1 {
+		return nil, false
+	}
+	b := slot.data
+	slot.data = nil
+	atomic.StoreUint64(&slot.seq, q.tail+q.size)
+	q.tail++
+	atomic.AddInt64(&q.queued, -1)
+	return b, true
+}
+
+// ready reports whether the next write is in the queue. Only the writer
+// goroutine may call it.
+func (q *asyncQueue) ready() bool {
+	return atomic.LoadUint64(&q.slots[q.tail%q.size].seq) == q.tail+1 ||
+		atomic.LoadInt32(&q.overflowing) != 0
+}
+
+// notify wakes up the writer goroutine if it's waiting.
+func (q *asyncQueue) notify() {
+	if atomic.LoadInt32(&q.sleeping) == 1 && atomic.CompareAndSwapInt32(&q.sleeping, 1, 0) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// enqueue queues a copy of p to be written by l's writer goroutine. Writes
+// that don't fit, even with the burst allowance, are dropped and counted.
+func (l *Logger) enqueue(p []byte) (int, error) {
+	q := l.async
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	atomic.AddInt32(&q.producers, 1)
+	if atomic.LoadInt32(&q.closed) != 0 {
+		atomic.AddInt32(&q.producers, -1)
+		q.notify()
+		return 0, errClosed
+	}
+	ok := q.push(b)
+	var queued int64
+	if ok {
+		atomic.AddUint64(&q.enqueued, 1)
+		queued = atomic.AddInt64(&q.queued, 1)
+	}
+	atomic.AddInt32(&q.producers, -1)
+	q.notify()
+
+	if !ok {
+		atomic.AddUint64(&l.stats.droppedWrites, 1)
+		atomic.AddUint64(&l.stats.droppedBytes, uint64(len(p)))
+		return len(p), nil
+	}
+	if queued > int64(q.limit) && atomic.CompareAndSwapInt32(&q.above, 0, 1) && l.onHighWatermark != nil {
+		l.onHighWatermark(int(queued))
+	}
+	return len(p), nil
+}
+
+// runAsync writes out queued writes, a batch of all those waiting at a time,
+// until the queue is closed and empty.
+func (l *Logger) runAsync() {
+	q := l.async
+	defer close(q.done)
+	for {
+		batch := q.spare[:0]
+		for {
+			b, ok := q.pop()
+			if !ok {
+				break
+			}
+			batch = append(batch, b)
+		}
+		batch = q.takeOverflow(batch)
+
+		if len(batch) == 0 {
+			if atomic.LoadInt32(&q.closed) != 0 && atomic.LoadInt32(&q.producers) == 0 && !q.ready() {
+				return
+			}
+			// announce the wait before looking again, so that a write
+			// queued in between wakes us up
+			atomic.StoreInt32(&q.sleeping, 1)
+			if q.ready() || (atomic.LoadInt32(&q.closed) != 0 && atomic.LoadInt32(&q.producers) == 0) {
+				atomic.StoreInt32(&q.sleeping, 0)
+				continue
+			}
+			<-q.wake
+			continue
+		}
+		atomic.StoreInt32(&q.above, 0)
+
+		n := len(batch)
+		for i, p := range batch {
+			_, _, err := l.write(p, nil)
+			l.reportError(err)
+			batch[i] = nil
+		}
+
+		// let the batch that absorbed a burst go rather than keeping it
+		// around at its peak size
+		if cap(batch) > q.limit {
+			batch = make([][]byte, 0, q.limit)
+		}
+		q.spare = batch[:0]
+		q.mu.Lock()
+		q.written += uint64(n)
+		q.drained.Broadcast()
+		q.mu.Unlock()
+	}
+}
+
+// wait waits for the writes queued so far to be written out. It must not be
+// called from the writer goroutine, as from a callback.
+func (q *asyncQueue) wait() {
+	target := atomic.LoadUint64(&q.enqueued)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.written < target {
+		q.drained.Wait()
+	}
+}
+
+// closeAsync stops accepting writes and waits for the queued ones to be
+// written out.
+func (l *Logger) closeAsync() {
+	q := l.async
+	atomic.StoreInt32(&q.closed, 1)
+	atomic.StoreInt32(&q.sleeping, 0)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	<-q.done
+}
@@ -0,0 +1,94 @@
+package rolling
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsyncBurstBuffer(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestAsyncBurstBuffer", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var marks []int
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1000),
+		WithAsyncQueueSize(4), WithBurstBuffer(8, func(n int) { marks = append(marks, n) }))
+	isNil(err, t)
+
+	// hold the write mutex, and wait for the writer goroutine to block on it
+	// with a first write in hand
+	l.mu.Lock()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	for atomic.LoadInt64(&l.async.queued) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 13; i++ {
+		n, err := l.Write([]byte(fmt.Sprintf("%04d", i)))
+		isNil(err, t)
+		equals(4, n, t)
+	}
+	equals(int64(12), atomic.LoadInt64(&l.async.queued), t)
+	// the ring stays at its size, and the burst went to the overflow
+	equals(4, len(l.async.slots), t)
+	equals(8, len(l.async.overflow), t)
+	l.mu.Unlock()
+
+	equals([]int{5}, marks, t)
+	equals(uint64(1), l.Stats().DroppedWrites, t)
+
+	isNil(l.Close(), t)
+	// in order across the ring and the overflow, the last write dropped
+	existsWithContent(logFile(dir), []byte("boo!000000010002000300040005000600070008000900100011"), t)
+	equals(true, cap(l.async.spare) <= 4, t)
+	equals(true, l.async.overflow == nil, t)
+
+	_, err = l.Write([]byte("late"))
+	equals(errClosed, err, t)
+}
+
+func TestAsyncConcurrentWriters(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestAsyncConcurrentWriters", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1000),
+		WithAsyncQueueSize(8))
+	isNil(err, t)
+
+	// every write either makes it to the file or is counted as dropped
+	const writers, writes = 50, 100
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				_, err := l.Write([]byte("x\n"))
+				isNil(err, t)
+			}
+		}()
+	}
+	wg.Wait()
+	isNil(l.Close(), t)
+
+	b, err := ioutil.ReadFile(logFile(dir))
+	isNil(err, t)
+	written := uint64(bytes.Count(b, []byte("\n")))
+	equals(uint64(writers*writes), written+l.Stats().DroppedWrites, t)
+	equals(written, l.Stats().Writes, t)
+}
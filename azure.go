@@ -0,0 +1,48 @@
+package rolling
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// AzureBlobClient is what the Azure archiver needs from an Azure Blob
+// Storage client: uploading a file as a block blob. Authentication is the
+// client's business, so a SAS token and a managed identity work alike. With
+// github.com/Azure/azure-sdk-for-go/sdk/storage/azblob:
+//
+//	// with a SAS token
+//	client, err := azblob.NewClientWithNoCredential("https://account.blob.core.windows.net/?"+sas, nil)
+//	// or with a managed identity
+//	cred, err := azidentity.NewManagedIdentityCredential(nil)
+//	client, err := azblob.NewClient("https://account.blob.core.windows.net/", cred, nil)
+//
+//	type azureUploader struct{ client *azblob.Client }
+//
+//	func (u azureUploader) UploadFile(ctx context.Context, container, blob string, file *os.File) error {
+//		_, err := u.client.UploadFile(ctx, container, blob, file, nil)
+//		return err
+//	}
+type AzureBlobClient interface {
+	UploadFile(ctx context.Context, container, blob string, file *os.File) error
+}
+
+// azureArchiver uploads backups to container, under prefix.
+type azureArchiver struct {
+	client    AzureBlobClient
+	container string
+	prefix    string
+}
+
+func (a *azureArchiver) Archive(ctx context.Context, localPath string) error {
+	blob := path.Join(a.prefix, filepath.Base(localPath))
+	return retry(ctx, uploadAttempts, uploadBackoff, func() error {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return a.client.UploadFile(ctx, a.container, blob, f)
+	})
+}
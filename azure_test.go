@@ -0,0 +1,49 @@
+package rolling
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// fakeAzure is an AzureBlobClient keeping blobs in memory.
+type fakeAzure struct {
+	blobs map[string][]byte
+}
+
+func (c *fakeAzure) UploadFile(ctx context.Context, container, blob string, file *os.File) error {
+	b, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	c.blobs[container+"/"+blob] = b
+	return nil
+}
+
+func TestAzureArchive(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestAzureArchive", t)
+	defer os.RemoveAll(dir)
+
+	azure := &fakeAzure{blobs: make(map[string][]byte)}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithSynchronousMill(),
+		WithCompress(), WithAzureArchive(azure, "logs", "app"))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	// the compressed backup is what gets uploaded
+	name := backupFile(dir)[len(dir)+1:] + compressSuffix
+	b, ok := azure.blobs["logs/app/"+name]
+	assert(ok, t, "backup not uploaded: %v", azure.blobs)
+	local, err := ioutil.ReadFile(backupFile(dir) + compressSuffix)
+	isNil(err, t)
+	equals(local, b, t)
+}
@@ -0,0 +1,87 @@
+package rolling
+
+import "time"
+
+// writeFile writes p to the current file, through the write buffer if
+// BufferSize is set. Writes at least as big as the buffer bypass it once
+// what's already buffered has been flushed. l.mu must be held.
+func (l *Logger) writeFile(p []byte) (int, error) {
+	if l.BufferSize <= 0 {
+		return l.file.Write(p)
+	}
+	if len(l.buf)+len(p) > l.BufferSize {
+		if err := l.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if len(p) >= l.BufferSize {
+		return l.file.Write(p)
+	}
+	if l.FlushInterval > 0 && l.SynchronousMill && len(l.buf) > 0 &&
+		currentTime().Sub(l.bufferedAt) >= l.FlushInterval {
+		// no timer in synchronous mode, so data is only flushed on time
+		// when written after
+		if err := l.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if l.buf == nil {
+		l.buf = make([]byte, 0, l.BufferSize)
+	}
+	if len(l.buf) == 0 {
+		l.bufferedAt = currentTime()
+		l.armFlush()
+	}
+	l.buf = append(l.buf, p...)
+	return len(p), nil
+}
+
+// armFlush schedules a flush FlushInterval from now, as the buffer starts
+// filling up again. There's no goroutine, nor timer, while nothing is
+// buffered. l.mu must be held.
+func (l *Logger) armFlush() {
+	if l.FlushInterval <= 0 || l.SynchronousMill {
+		return
+	}
+	if l.flushTimer == nil {
+		l.flushTimer = time.AfterFunc(l.FlushInterval, l.timedFlush)
+		return
+	}
+	l.flushTimer.Reset(l.FlushInterval)
+}
+
+// timedFlush is run by the flush timer. If the flush fails the timer is
+// armed again, so what's left is retried.
+func (l *Logger) timedFlush() {
+	l.mu.Lock()
+	err := l.flush()
+	if err != nil && l.file != nil {
+		l.armFlush()
+	}
+	l.mu.Unlock()
+	if err != nil {
+		l.reportError(err)
+	}
+}
+
+// flush writes the buffered data out to the current file. Unlike a
+// bufio.Writer, a failed flush isn't sticky: what couldn't be written stays
+// buffered and is retried by the next flush. l.mu must be held.
+func (l *Logger) flush() error {
+	if len(l.buf) == 0 || l.file == nil {
+		return nil
+	}
+	n, err := l.file.Write(l.buf)
+	l.buf = l.buf[:copy(l.buf, l.buf[n:])]
+	return err
+}
+
+// Flush writes out whatever is held in the write buffer set up by
+// WithBufferSize. The buffer is also flushed before the file is rotated or
+// closed, and every FlushInterval if set, so Flush is only needed to make
+// recent writes visible sooner.
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flush()
+}
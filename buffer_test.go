@@ -0,0 +1,83 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBufferSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestBufferSize", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(12), WithBufferSize(8))
+	isNil(err, t)
+	defer l.Close()
+
+	// held in memory until flushed
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte{}, t)
+	isNil(l.Flush(), t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	_, err = l.Write([]byte("bar!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+
+	// buffered bytes count towards the size, and are written out before
+	// the file is rotated
+	_, err = l.Write([]byte("baz!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("boo!foo!bar!"), t)
+	existsWithContent(logFile(dir), []byte{}, t)
+
+	// writes as big as the buffer go straight to the file
+	_, err = l.Write([]byte("12345678"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("baz!12345678"), t)
+}
+
+func TestBufferFlushedOnClose(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestBufferFlushedOnClose", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithBufferSize(64))
+	isNil(err, t)
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+}
+
+func TestFlushInterval(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestFlushInterval", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100),
+		WithBufferSize(64), WithFlushInterval(10*time.Millisecond))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b, err := ioutil.ReadFile(logFile(dir))
+		isNil(err, t)
+		if string(b) == "boo!" {
+			break
+		}
+		assert(time.Now().Before(deadline), t, "buffer not flushed after the interval")
+		time.Sleep(5 * time.Millisecond)
+	}
+}
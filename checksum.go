@@ -0,0 +1,120 @@
+package rolling
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// checksumSuffix is appended to the name of a backup for its checksum
+// sidecar.
+const checksumSuffix = ".sha256"
+
+// ChecksumMismatchError reports a backup whose content doesn't match its
+// checksum sidecar.
+type ChecksumMismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", e.Path, e.Expected, e.Actual)
+}
+
+// VerifyChecksum checks the file at path against the SHA-256 checksum in its
+// sidecar, path with ".sha256" appended, as written with Checksums. It
+// returns a *ChecksumMismatchError if they don't match.
+func VerifyChecksum(path string) error {
+	b, err := ioutil.ReadFile(path + checksumSuffix)
+	if err != nil {
+		return err
+	}
+	fields := bytes.Fields(b)
+	if len(fields) == 0 || len(fields[0]) != hex.EncodedLen(sha256.Size) {
+		return fmt.Errorf("invalid checksum file %s", path+checksumSuffix)
+	}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if expected := string(fields[0]); sum != expected {
+		return &ChecksumMismatchError{Path: path, Expected: expected, Actual: sum}
+	}
+	return nil
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeChecksum writes the checksum sidecar of the file at path, in the
+// format of sha256sum so that `sha256sum -c` can check it, with the mode of
+// the file. It replaces any existing sidecar atomically.
+func writeChecksum(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	sidecar := path + checksumSuffix
+	tmp := sidecar + tmpSuffix
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	if err := ioutil.WriteFile(tmp, []byte(line), fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, sidecar); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// removeChecksum removes the checksum sidecar of the file at path, if any,
+// once the file has been removed or replaced under another name.
+func removeChecksum(path string) error {
+	if err := os.Remove(path + checksumSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeChecksums writes the checksum sidecar of every backup which doesn't
+// have one yet. l.millMu must be held.
+func (l *Logger) writeChecksums() error {
+	if !l.Checksums {
+		return nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		path := filepath.Join(l.backupDir(), f.Name())
+		if _, errStat := os.Stat(path + checksumSuffix); !os.IsNotExist(errStat) {
+			continue
+		}
+		if errSum := writeChecksum(path); errSum != nil && err == nil {
+			err = fmt.Errorf("can't write checksum of %s: %v", f.Name(), errSum)
+		}
+	}
+	return err
+}
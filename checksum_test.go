@@ -0,0 +1,62 @@
+package rolling
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestChecksums", t)
+	defer os.RemoveAll(dir)
+
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(2),
+		WithCompress(), WithCompressAfter(1), WithChecksums(), WithSynchronousMill(), WithS3Archive(s3, "bucket", "logs"))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := backupFile(dir)
+
+	// the newest backup is left uncompressed, with a sidecar of its own
+	b, err := ioutil.ReadFile(first + checksumSuffix)
+	isNil(err, t)
+	sum := sha256.Sum256([]byte("boo!"))
+	equals(hex.EncodeToString(sum[:])+"  "+filepath.Base(first)+"\n", string(b), t)
+	isNil(VerifyChecksum(first), t)
+
+	// compression replaces it with one for the compressed backup, which is
+	// uploaded along with it
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	notExist(first+checksumSuffix, t)
+	compressed := first + compressSuffix
+	isNil(VerifyChecksum(compressed), t)
+	exists(backupFile(dir)+checksumSuffix, t)
+	_, ok := s3.objects["bucket/logs/"+filepath.Base(compressed)+checksumSuffix]
+	equals(true, ok, t)
+
+	// a modified backup is caught
+	isNil(ioutil.WriteFile(compressed, []byte("tampered"), 0644), t)
+	var mismatch *ChecksumMismatchError
+	equals(true, errors.As(VerifyChecksum(compressed), &mismatch), t)
+
+	// and sidecars are removed with their backups
+	newFakeTime()
+	_, err = l.Write([]byte("quux!"))
+	isNil(err, t)
+	notExist(compressed, t)
+	notExist(compressed+checksumSuffix, t)
+}
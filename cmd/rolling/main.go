@@ -0,0 +1,54 @@
+// Command rolling works on the log files written by github.com/cnof/rolling.
+//
+// Usage:
+//
+//	rolling repair <backup> <output>
+//
+// repair salvages the readable part of a truncated or corrupt compressed
+// backup into a new, valid compressed file.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cnof/rolling"
+)
+
+const usage = `usage: rolling <command> [arguments]
+
+commands:
+  repair <backup> <output>   salvage a truncated compressed backup
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "repair":
+		err = repair(args)
+	default:
+		fmt.Fprintf(os.Stderr, "rolling: unknown command %q\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rolling:", err)
+		os.Exit(1)
+	}
+}
+
+func repair(args []string) error {
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	n, err := rolling.RepairBackup(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("salvaged %d bytes into %s\n", n, args[1])
+	return nil
+}
@@ -0,0 +1,154 @@
+package rolling
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// codec is a compression format backups can be compressed with.
+type codec struct {
+	name   string
+	suffix string
+	magic  []byte
+	// compress writes the compressed content of src, described by fi, to w
+	// at the given level, 0 meaning the default.
+	compress func(w io.Writer, src io.Reader, fi os.FileInfo, level int) error
+	// newReader returns a reader decompressing r.
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	gzipCodec = &codec{
+		name:   "gzip",
+		suffix: compressSuffix,
+		magic:  []byte{0x1f, 0x8b},
+		compress: func(w io.Writer, src io.Reader, fi os.FileInfo, level int) error {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			gz, err := gzip.NewWriterLevel(w, level)
+			if err != nil {
+				return err
+			}
+			gz.Name = fi.Name()
+			gz.ModTime = fi.ModTime()
+			gz.Extra = sizeExtra(fi.Size())
+			if _, err := io.Copy(gz, src); err != nil {
+				return err
+			}
+			return gz.Close()
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	}
+
+	zstdCodec = &codec{
+		name:   "zstd",
+		suffix: ".zst",
+		magic:  []byte{0x28, 0xb5, 0x2f, 0xfd},
+		compress: func(w io.Writer, src io.Reader, fi os.FileInfo, level int) error {
+			speed := zstd.SpeedDefault
+			if level > 0 {
+				speed = zstd.EncoderLevelFromZstd(level)
+			}
+			enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(speed), zstd.WithEncoderConcurrency(1))
+			if err != nil {
+				return err
+			}
+			// recording the size lets readers check they got everything
+			enc.ResetContentSize(w, fi.Size())
+			if _, err := io.Copy(enc, src); err != nil {
+				_ = enc.Close()
+				return err
+			}
+			return enc.Close()
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return dec.IOReadCloser(), nil
+		},
+	}
+
+	lz4Codec = &codec{
+		name:   "lz4",
+		suffix: ".lz4",
+		magic:  []byte{0x04, 0x22, 0x4d, 0x18},
+		compress: func(w io.Writer, src io.Reader, fi os.FileInfo, level int) error {
+			lw := lz4.NewWriter(w)
+			options := []lz4.Option{lz4.SizeOption(uint64(fi.Size())), lz4.ConcurrencyOption(1)}
+			if level > 0 {
+				options = append(options, lz4.CompressionLevelOption(lz4.Level1<<(level-1)))
+			}
+			if err := lw.Apply(options...); err != nil {
+				return err
+			}
+			if _, err := io.Copy(lw, src); err != nil {
+				return err
+			}
+			return lw.Close()
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(lz4.NewReader(r)), nil
+		},
+	}
+
+	codecs = []*codec{gzipCodec, zstdCodec, lz4Codec}
+)
+
+// codecByName returns the codec called name, gzip if name is empty.
+func codecByName(name string) (*codec, error) {
+	if name == "" {
+		return gzipCodec, nil
+	}
+	for _, c := range codecs {
+		if c.name == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown compression %q", name)
+}
+
+// codec returns the codec backups are compressed with.
+func (l *Logger) codec() *codec {
+	c, err := codecByName(l.Compression)
+	if err != nil {
+		// rejected by open
+		return gzipCodec
+	}
+	return c
+}
+
+// detectCodec returns the codec the data in br was compressed with, going
+// by its first bytes.
+func detectCodec(br *bufio.Reader) (*codec, error) {
+	for _, c := range codecs {
+		if magic, err := br.Peek(len(c.magic)); err == nil && bytes.Equal(magic, c.magic) {
+			return c, nil
+		}
+	}
+	return nil, errors.New("unknown compression format")
+}
+
+// newDecompressor returns a reader decompressing r, whatever codec it was
+// compressed with.
+func newDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	c, err := detectCodec(br)
+	if err != nil {
+		return nil, err
+	}
+	return c.newReader(br)
+}
@@ -0,0 +1,154 @@
+package rolling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestZstdCompression(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestZstdCompression", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxAge(0),
+		WithCompression("zstd"), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+	zst := backupFile(dir) + ".zst"
+	exists(zst, t)
+	notExist(backupFile(dir), t)
+
+	r, err := l.OpenBackup(zst)
+	isNil(err, t)
+	got, err := ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals(b, got, t)
+
+	// switching back to gzip still recognizes the zstd backups
+	l.Compression = "gzip"
+	backups, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	equals(true, backups[0].Compressed, t)
+	newFakeTime()
+	_, err = l.Write([]byte("1111111!"))
+	isNil(err, t)
+	exists(backupFile(dir)+compressSuffix, t)
+	exists(zst, t)
+
+	_, err = NewWriter(WithLogPath(dir), WithFilename("bad.log"), WithCompression("rar"))
+	notNil(err, t)
+}
+
+func TestZstdVerifyAndRepair(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestZstdVerifyAndRepair", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	newFakeTime()
+	name := backupFile(dir)
+	data := bytes.Repeat([]byte("some log line\n"), 100000)
+	isNil(ioutil.WriteFile(name, data, 0644), t)
+	isNil(compressLogFile(name, name+".zst", zstdCodec, 0), t)
+	b, err := ioutil.ReadFile(name + ".zst")
+	isNil(err, t)
+	isNil(ioutil.WriteFile(name+".zst", b[:len(b)-10], 0644), t)
+
+	repaired := filepath.Join(dir, "repaired.zst")
+	n, err := RepairBackup(name+".zst", repaired)
+	isNil(err, t)
+	equals(true, n > 0 && n < int64(len(data)), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompression("zstd"), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	corrupt, err := l.Verify()
+	isNil(err, t)
+	equals(1, len(corrupt), t)
+	notExist(name+".zst", t)
+
+	// the repaired file is valid
+	isNil(os.Rename(repaired, name+".zst"), t)
+	corrupt, err = l.Verify()
+	isNil(err, t)
+	equals(0, len(corrupt), t)
+}
+
+func TestLz4Compression(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestLz4Compression", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	data := bytes.Repeat([]byte("some log line\n"), 100000)
+	for _, level := range []int{0, 9} {
+		newFakeTime()
+		name := backupFile(dir)
+		isNil(ioutil.WriteFile(name, data, 0644), t)
+		isNil(compressLogFile(name, name+".lz4", lz4Codec, level), t)
+
+		l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompression("lz4"), WithMaxAge(0))
+		isNil(err, t)
+		r, err := l.OpenBackup(name + ".lz4")
+		isNil(err, t)
+		got, err := ioutil.ReadAll(r)
+		isNil(err, t)
+		isNil(r.Close(), t)
+		equals(true, bytes.Equal(data, got), t)
+		isNil(l.Close(), t)
+	}
+
+	// a flipped bit fails the checksum
+	name := backupFile(dir) + ".lz4"
+	b, err := ioutil.ReadFile(name)
+	isNil(err, t)
+	b[len(b)/2] ^= 1
+	isNil(ioutil.WriteFile(name, b, 0644), t)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompression("lz4"), WithMaxAge(0))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	corrupt, err := l.Verify()
+	isNil(err, t)
+	equals(1, len(corrupt), t)
+}
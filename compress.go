@@ -0,0 +1,259 @@
+package rolling
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// compressionWorkers returns how many backups may be processed at once for
+// a backlog of n: one per backlog item, up to half the available CPUs, or
+// MaxCompressionWorkers if set. Synchronous mode always uses one.
+func (l *Logger) compressionWorkers(n int) int {
+	limit := l.MaxCompressionWorkers
+	if limit <= 0 {
+		if limit = runtime.GOMAXPROCS(0) / 2; limit < 1 {
+			limit = 1
+		}
+	}
+	if l.SynchronousMill {
+		limit = 1
+	}
+	if n < limit {
+		return n
+	}
+	return limit
+}
+
+// CompressBackups compresses and/or encrypts, as configured, every backup
+// that isn't yet, right away rather than on the next mill pass and whatever
+// CompressAfter says, as before taking a snapshot, or after enabling
+// Compress on a directory full of plain backups. With ArchiveDir, that
+// includes backups still in LogPath waiting to be moved there. The work is
+// spread over the compression workers like a mill pass's, and checksums are
+// written and ownership set afterwards. It does nothing without Compress or
+// encryption.
+func (l *Logger) CompressBackups() error {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	files, err := l.allLogFiles()
+	if err != nil {
+		return err
+	}
+	var process []logInfo
+	for _, f := range files {
+		if l.needsProcessing(f) {
+			process = append(process, f)
+		}
+	}
+	err = l.processAll(process)
+	if errSum := l.writeChecksums(); err == nil {
+		err = errSum
+	}
+	if errChown := l.chownBackups(); err == nil {
+		err = errChown
+	}
+	return err
+}
+
+// processAll compresses and/or encrypts the given backups, spreading them
+// over as many workers as compressionWorkers allows, and returns the first
+// error.
+func (l *Logger) processAll(files []logInfo) error {
+	workers := l.compressionWorkers(len(files))
+	if workers <= 1 {
+		var err error
+		for _, f := range files {
+			if errCompress := l.processBackup(f); err == nil {
+				err = errCompress
+			}
+		}
+		return err
+	}
+
+	work := make(chan logInfo)
+	errs := make(chan error, len(files))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range work {
+				errs <- l.processBackup(f)
+			}
+		}()
+	}
+	for _, f := range files {
+		work <- f
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	var err error
+	for errCompress := range errs {
+		if err == nil {
+			err = errCompress
+		}
+	}
+	return err
+}
+
+// needsProcessing reports whether the backup f is still to be compressed or
+// encrypted.
+func (l *Logger) needsProcessing(f logInfo) bool {
+	return (l.Compress && !f.compressed && !f.encrypted) || (l.encrypts() && !f.encrypted)
+}
+
+// processBackup compresses the backup f, then encrypts it, as configured.
+// Each step replaces the previous file with one named after it.
+func (l *Logger) processBackup(f logInfo) error {
+	fn := l.backupPath(f)
+	return l.background(func() error {
+		// the source is removed once processed, so it must be released
+		// in append-only mode, and the result protected
+		if l.AppendOnly {
+			if err := setAppendOnly(fn, false); err != nil {
+				return err
+			}
+		}
+		if l.Checksums {
+			if err := removeChecksum(fn); err != nil {
+				return err
+			}
+		}
+		if l.Compress && !f.compressed {
+			dst := l.compressedName(fn)
+			start := time.Now()
+			if err := compressLogFile(fn, dst, l.codec(), l.CompressionLevel); err != nil {
+				return err
+			}
+			atomic.AddUint64(&l.stats.compressions, 1)
+			atomic.AddInt64(&l.stats.compressNanos, int64(time.Since(start)))
+			fn = dst
+		}
+		if l.encrypts() {
+			var err error
+			if fn, err = l.encryptBackup(fn, l.age != nil); err != nil {
+				return err
+			}
+		}
+		if l.AppendOnly {
+			return setAppendOnly(fn, true)
+		}
+		return nil
+	})
+}
+
+// sizeExtraID identifies the gzip extra subfield in which compressLogFile
+// records the uncompressed size of a backup. The gzip trailer only keeps the
+// size modulo 4GiB, which isn't good enough for large daily logs.
+var sizeExtraID = [2]byte{'R', 'S'}
+
+// compressLogFile compresses src into dst with c at the given level, 0 for the
+// default, and removes src. The compressed file keeps the original's
+// permissions and modification time, and a gzip header records the original
+// name, mtime and size, so that tools looking at dst still see when the data
+// was written rather than when compression ran. src is only removed once dst
+// is complete and synced to disk.
+func compressLogFile(src, dst string, c *codec, level int) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+
+	// the compressed file only takes its name once complete and on disk, so
+	// a crash never leaves a truncated one next to, or instead of, the
+	// original
+	tmp := dst + tmpSuffix
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return fmt.Errorf("failed to open compressed log file: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+			err = fmt.Errorf("failed to compress log file: %v", err)
+		}
+	}()
+
+	if err := c.compress(out, f, fi, level); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// sizeExtra encodes size as a gzip extra subfield (RFC 1952, section 2.3.1.1).
+func sizeExtra(size int64) []byte {
+	b := make([]byte, 4+8)
+	b[0], b[1] = sizeExtraID[0], sizeExtraID[1]
+	binary.LittleEndian.PutUint16(b[2:], 8)
+	binary.LittleEndian.PutUint64(b[4:], uint64(size))
+	return b
+}
+
+// originalSize returns the uncompressed size recorded in the gzip header of
+// the compressed backup at path.
+func originalSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	if size, ok := sizeFromExtra(gz.Extra); ok {
+		return size, nil
+	}
+	return 0, errors.New("original size not recorded")
+}
+
+// sizeFromExtra finds the size recorded by sizeExtra in a gzip extra field.
+func sizeFromExtra(extra []byte) (int64, bool) {
+	for len(extra) >= 4 {
+		n := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+n {
+			break
+		}
+		if extra[0] == sizeExtraID[0] && extra[1] == sizeExtraID[1] && n == 8 {
+			return int64(binary.LittleEndian.Uint64(extra[4:])), true
+		}
+		extra = extra[4+n:]
+	}
+	return 0, false
+}
@@ -0,0 +1,334 @@
+package rolling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCompressPreservesModTime(t *testing.T) {
+	dir := makeTempDir("TestCompressPreservesModTime", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	src := backupFile(dir)
+	data := []byte("some old log data\n")
+	isNil(ioutil.WriteFile(src, data, 0600), t)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	isNil(os.Chtimes(src, mtime, mtime), t)
+
+	dst := src + compressSuffix
+	isNil(compressLogFile(src, dst, gzipCodec, 0), t)
+	notExist(src, t)
+
+	info, err := os.Stat(dst)
+	isNil(err, t)
+	equals(true, info.ModTime().Equal(mtime), t)
+	equals(os.FileMode(0600), info.Mode().Perm(), t)
+
+	size, err := originalSize(dst)
+	isNil(err, t)
+	equals(int64(len(data)), size, t)
+
+	f, err := os.Open(dst)
+	isNil(err, t)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	isNil(err, t)
+	equals(true, gz.ModTime.Equal(mtime), t)
+	b, err := ioutil.ReadAll(gz)
+	isNil(err, t)
+	equals(data, b, t)
+}
+
+func TestCompressOnRotate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressOnRotate", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	filename := logFile(dir)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+
+	newFakeTime()
+
+	b2 := []byte("0000000!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+
+	<-time.After(10 * time.Millisecond)
+	existsWithContent(filename, b2, t)
+	notExist(backupFile(dir), t)
+	size, err := originalSize(backupFile(dir) + compressSuffix)
+	isNil(err, t)
+	equals(int64(len(b)), size, t)
+	fileCount(dir, 2, t)
+}
+
+func TestCompressReplaceExt(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressReplaceExt", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(1),
+		WithCompress(), WithCompressSuffix(".gzip"), WithCompressReplaceExt(), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+
+	newFakeTime()
+	first := filepath.Join(dir, "foobar-"+fakeTime().UTC().Format(backupTimeFormat)+".gzip")
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+
+	exists(first, t)
+	notExist(backupFile(dir), t)
+	fileCount(dir, 2, t)
+
+	newFakeTime()
+	second := filepath.Join(dir, "foobar-"+fakeTime().UTC().Format(backupTimeFormat)+".gzip")
+	_, err = l.Write([]byte("1111111!"))
+	isNil(err, t)
+
+	notExist(first, t)
+	exists(second, t)
+	fileCount(dir, 2, t)
+}
+
+func TestCompressBacklog(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressBacklog", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var backups []string
+	for i := 0; i < 10; i++ {
+		newFakeTime()
+		backups = append(backups, backupFile(dir))
+		isNil(ioutil.WriteFile(backupFile(dir), []byte("data"), 0644), t)
+	}
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxRemain(0), WithMaxAge(0),
+		WithCompress(), WithMaxCompressionWorkers(3))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	equals(3, l.compressionWorkers(10), t)
+	equals(2, l.compressionWorkers(2), t)
+
+	isNil(l.millRunOnce(), t)
+	for _, b := range backups {
+		notExist(b, t)
+		exists(b+compressSuffix, t)
+	}
+	fileCount(dir, 11, t)
+}
+
+func TestCompressIsAtomic(t *testing.T) {
+	dir := makeTempDir("TestCompressIsAtomic", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	src := backupFile(dir)
+	data := []byte("some log data\n")
+	isNil(ioutil.WriteFile(src, data, 0644), t)
+	dst := src + compressSuffix
+
+	// left over by a compression interrupted by a crash
+	isNil(ioutil.WriteFile(dst+tmpSuffix, []byte("garbage"), 0644), t)
+	isNil(compressLogFile(src, dst, gzipCodec, 0), t)
+	notExist(dst+tmpSuffix, t)
+	notExist(src, t)
+	size, err := originalSize(dst)
+	isNil(err, t)
+	equals(int64(len(data)), size, t)
+
+	// a failed compression leaves the original alone and no partial file
+	isNil(ioutil.WriteFile(src, data, 0644), t)
+	isNil(os.Mkdir(dst+tmpSuffix, 0755), t)
+	notNil(compressLogFile(src, dst, gzipCodec, 0), t)
+	existsWithContent(src, data, t)
+	size, err = originalSize(dst)
+	isNil(err, t)
+	equals(int64(len(data)), size, t)
+}
+
+func TestCompressionLevel(t *testing.T) {
+	dir := makeTempDir("TestCompressionLevel", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompressionLevel(gzip.BestSpeed))
+	isNil(err, t)
+	isNil(l.Close(), t)
+	equals(gzip.BestSpeed, l.CompressionLevel, t)
+
+	// the level reaches the compressor: the same backup comes out smaller
+	// at the best compression than with Huffman coding only
+	currentTime = fakeTime
+	megabyte = 1024 * 1024
+	data := bytes.Repeat([]byte("0123456789 some log line\n"), 1000)
+	sizes := make(map[int]int64)
+	for _, level := range []int{gzip.HuffmanOnly, gzip.BestCompression} {
+		levelDir := filepath.Join(dir, strconv.Itoa(level))
+		l, err := NewWriter(WithLogPath(levelDir), WithFilename(logName()), WithCompress(),
+			WithCompressionLevel(level), WithSynchronousMill())
+		isNil(err, t)
+		_, err = l.Write(data)
+		isNil(err, t)
+		isNil(l.Rotate(""), t)
+		isNil(l.Close(), t)
+		fi, err := os.Stat(backupFile(levelDir) + compressSuffix)
+		isNil(err, t)
+		sizes[level] = fi.Size()
+	}
+	assert(sizes[gzip.BestCompression] < sizes[gzip.HuffmanOnly], t,
+		"expected a smaller backup at level %d: %v", gzip.BestCompression, sizes)
+
+	for _, level := range []int{-3, 10} {
+		_, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompressionLevel(level))
+		notNil(err, t)
+	}
+}
+
+func TestCompressAfter(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressAfter", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress(),
+		WithCompressAfter(1), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := backupFile(dir)
+	existsWithContent(first, []byte("boo!"), t)
+
+	// the newest backup stays plain, the one before gets compressed
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("foo!bar!"), t)
+	notExist(first, t)
+	exists(first+compressSuffix, t)
+}
+
+func TestCompressBackups(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressBackups", t)
+	defer os.RemoveAll(dir)
+
+	// plain backups from before compression was enabled
+	var backups []string
+	for i := 0; i < 3; i++ {
+		newFakeTime()
+		backups = append(backups, backupFile(dir))
+		isNil(ioutil.WriteFile(backupFile(dir), []byte("boo!"), 0644), t)
+	}
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompress(),
+		WithCompressAfter(5), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	// CompressAfter would keep them all plain
+	isNil(l.CompressBackups(), t)
+	for _, b := range backups {
+		notExist(b, t)
+		exists(b+compressSuffix, t)
+	}
+	infos, err := l.Backups()
+	isNil(err, t)
+	equals(3, len(infos), t)
+	equals(true, infos[0].Compressed, t)
+}
+
+func TestCompressBackupsPendingArchival(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressBackupsPendingArchival", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+	isNil(os.MkdirAll(archive, 0755), t)
+
+	// one backup archived, and a newer one the mill hasn't moved yet
+	archived := filepath.Join(archive, filepath.Base(backupFile(dir)))
+	isNil(ioutil.WriteFile(archived, []byte("boo!"), 0644), t)
+	newFakeTime()
+	pending := backupFile(dir)
+	isNil(ioutil.WriteFile(pending, []byte("foo!"), 0644), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithArchiveDir(archive),
+		WithCompress(), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	isNil(l.CompressBackups(), t)
+	notExist(archived, t)
+	exists(archived+compressSuffix, t)
+	notExist(pending, t)
+	exists(pending+compressSuffix, t)
+}
@@ -0,0 +1,345 @@
+package rolling
+
+import (
+	"os"
+	"time"
+)
+
+// Config holds a Logger's settings. Every field can be set with an Option as
+// well; Config exists so that settings can be deserialized, logged and
+// compared as one plain value. It is embedded in Logger, so the fields can
+// also be read directly off a Logger.
+type Config struct {
+	LogPath  string `json:"logPath" yaml:"logPath"`
+	Filename string `json:"filename" yaml:"filename"`
+
+	// MaxAge is the maximum number of days to retain old log files based on the
+	// timestamp encoded in their filename.  Note that a day is defined as 24
+	// hours and may not exactly correspond to calendar days due to daylight
+	// savings, leap seconds, etc. The default is not to remove old log files
+	// based on age.
+	MaxAge int `json:"maxAge" yaml:"maxAge"`
+	// MaxAgeDuration is MaxAge for retention periods that aren't a whole
+	// number of days. It takes precedence over MaxAge when set.
+	MaxAgeDuration time.Duration `json:"max_age_duration"`
+	// MaxRemain will auto clear the rolling file list, set 0 will disable auto clean
+	MaxRemain int `json:"max_remain"`
+	// MaxTotalSize caps the combined size in bytes of the active file and
+	// the backups: the oldest backups are removed until they fit. Unlike
+	// MaxRemain and MaxAge it bounds disk usage however big the backups get.
+	// Zero disables the cap.
+	MaxTotalSize int64 `json:"max_total_size"`
+
+	// RollingPolicy give out the rolling policy
+	// We got 4 policies:
+	//
+	//	1. WithoutRolling: no rolling will happen
+	//	2. TimeRolling: rolling by time
+	//	3. VolumeRolling: rolling by file size
+	//	4. HybridRolling: rolling by time, and by size whenever the file
+	//	   reaches MaxSize in between, so that a busy period can't produce
+	//	   an unbounded file
+	//
+	// Whatever triggered it, a rotation names, processes and retains
+	// backups the same way.
+	//
+	// TimePattern is the schedule of TimeRolling, a cron expression with a
+	// leading seconds field in the format of github.com/robfig/cron, such as
+	// "0 0 * * * *" for every hour, or a descriptor such as "@hourly" or
+	// "@every 6h". It defaults to every day at midnight and is checked when
+	// the Logger is opened, unless a custom Scheduler is used, which gets it
+	// as is.
+	//
+	// TimeZone is the IANA name of the time zone TimePattern is evaluated in,
+	// such as "Europe/Paris"; the default is the local time zone. It isn't
+	// passed on to a custom Scheduler.
+	RollingPolicy int    `json:"rolling_policy"`
+	TimePattern   string `json:"time_pattern"`
+	TimeZone      string `json:"time_zone"`
+	MaxSize       int    `json:"max_size"`
+
+	// FileMode is the mode log files are created with, DefaultFileMode if
+	// zero. Without it, a new file after a rotation takes the mode of the
+	// one rotated out. DirMode is the mode of the directories created for
+	// them, 0744 if zero. Both are subject to the umask.
+	FileMode os.FileMode `json:"file_mode"`
+	DirMode  os.FileMode `json:"dir_mode"`
+
+	// ArchiveDir, if set, is the directory backups are kept in, possibly on
+	// another volume than LogPath: every mill pass moves the files rotated
+	// out of LogPath there before retention and compression, which then
+	// only look at ArchiveDir. It can't be combined with SequentialNames.
+	ArchiveDir string `json:"archive_dir"`
+
+	// KeepUploaded, if positive, is how many of the backups already
+	// uploaded by the Archiver, such as those set up by WithS3Archive,
+	// WithGCSArchive and WithAzureArchive, are kept locally; older ones are
+	// removed once uploaded. Retention by MaxRemain, MaxAge and
+	// MaxTotalSize still applies to every backup.
+	KeepUploaded int `json:"keep_uploaded"`
+	// DeleteAfterUpload keeps every backup the Archiver hasn't uploaded yet,
+	// whatever MaxRemain, MaxAge and MaxTotalSize say, so that nothing is
+	// lost while remote storage is down: such backups are left out of
+	// retention like held ones, and only become eligible for removal from
+	// the mill pass after they were uploaded. It has no effect without an
+	// Archiver.
+	DeleteAfterUpload bool `json:"delete_after_upload"`
+
+	// Checksums writes a sidecar next to every backup, its name followed by
+	// ".sha256", holding its SHA-256 checksum in the format of sha256sum.
+	// Sidecars are written once backups are compressed and encrypted as
+	// configured, kept up to date when backups are rewritten, removed with
+	// them, and uploaded after them by the Archiver. See VerifyChecksum. It
+	// can't be combined with SequentialNames.
+	Checksums bool `json:"checksums"`
+
+	// SizeSinceOpen applies MaxSize to the bytes written by this Logger since
+	// the file was opened instead of the file's actual size, so content
+	// already in a shared or appended-to file doesn't force a rotation.
+	SizeSinceOpen bool `json:"size_since_open"`
+
+	// MaxLines, if positive, rotates the file once it holds MaxLines lines,
+	// whatever its size and the RollingPolicy, so that every backup holds
+	// the same number of records. A Write of several lines is cut after the
+	// one filling the file, the rest going to the next one. Lines already in
+	// the file when it is opened count too.
+	MaxLines int `json:"max_lines"`
+
+	// AtomicRecords guarantees that a record is never split between two
+	// files: a Write is always made whole to one file, and a rotation due
+	// while the last Write didn't end with a newline waits for the Write
+	// that finishes the line, so that lines written in several pieces stay
+	// together too. A Write bigger than MaxSize, which is otherwise refused,
+	// is then made to a file of its own, over MaxSize.
+	AtomicRecords bool `json:"atomic_records"`
+
+	// OversizedWrites sets what becomes of a Write bigger than MaxSize,
+	// which is refused by default: with OversizedSplit it is cut into
+	// pieces filling the current file and as many new ones as it takes, and
+	// with OversizedOwnFile it is made whole to a file of its own, as with
+	// AtomicRecords.
+	OversizedWrites string `json:"oversized_writes"`
+
+	// SplitOversized, if set, deals with a log file found to be more than
+	// SplitOversized times MaxSize when opened, as when rotation is adopted
+	// by a service which has been writing to the same file for years: it is
+	// moved aside and split in the background into backups of MaxSize,
+	// cutting at line ends, which are then retained and compressed as usual.
+	SplitOversized int `json:"split_oversized"`
+
+	// AppendOnly makes the log file and its backups tamper-resistant where
+	// the filesystem supports it, by giving them the append-only attribute
+	// (chattr +a on Linux, chflags uappend on BSDs) which is only lifted for
+	// the Logger's own renames and deletions. Files are never opened with
+	// truncation, and operations rewriting backups are refused. Setting the
+	// attribute usually takes privileges, such as CAP_LINUX_IMMUTABLE.
+	AppendOnly bool `json:"append_only"`
+
+	// SynchronousIO opens the log file with O_DSYNC, or O_SYNC where that
+	// isn't available, so that every write reaches stable storage before it
+	// returns, as audit logs may have to. Unlike SyncEveryWrite, it costs no
+	// extra system call, but writes can't be batched into a single flush.
+	SynchronousIO bool `json:"synchronous_io"`
+
+	// Preallocate reserves the disk space for MaxSize bytes whenever a log
+	// file is opened, with fallocate on Linux, so that the file isn't
+	// fragmented and writes don't find a nearly full volume out of room. The
+	// file keeps its size, but backups hold on to the space left unused
+	// until they're compressed or removed. It does nothing on other
+	// platforms, nor on filesystems which can't.
+	Preallocate bool `json:"preallocate"`
+
+	// Compress will compress log file with gzip
+	Compress bool `json:"compress"`
+	// Compression is the format backups are compressed in: "gzip", the
+	// default, "zstd", or "lz4", the fastest to compress. Each has its own
+	// suffix, ".gz", ".zst" and ".lz4", unless CompressSuffix is set.
+	// Backups compressed in another format before a change are still
+	// recognized.
+	Compression string `json:"compression"`
+	// CompressionLevel is the level backups are compressed at, from
+	// gzip.BestSpeed (1) to gzip.BestCompression (9), or gzip.HuffmanOnly
+	// (-2). Zero, like gzip.DefaultCompression (-1), uses the default level;
+	// to not compress at all, leave Compress unset. For zstd and lz4, levels
+	// are mapped to the closest of their own; lz4 defaults to its fastest.
+	CompressionLevel int `json:"compression_level"`
+	// CompressAfter leaves the newest CompressAfter backups as they are, so
+	// they can still be read with grep or tail; only older ones are
+	// compressed, and encrypted if enabled.
+	CompressAfter int `json:"compress_after"`
+	// MaxCompressionWorkers caps how many backups are compressed in parallel
+	// when several are waiting. By default up to half the available CPUs are
+	// used.
+	MaxCompressionWorkers int `json:"max_compression_workers"`
+	// LowPriority runs compression and other heavy background work at a
+	// lower CPU and I/O priority, so it doesn't compete with the application
+	// for the disk. Only Linux supports this per thread; elsewhere, and in
+	// synchronous mode, it has no effect.
+	LowPriority bool `json:"low_priority"`
+	// CompressSuffix is appended to the name of compressed backups, ".gz" by
+	// default. If CompressReplaceExt is set it replaces the log file's
+	// extension (app-<time>.gz) instead of following it (app-<time>.log.gz).
+	CompressSuffix     string `json:"compress_suffix"`
+	CompressReplaceExt bool   `json:"compress_replace_ext"`
+
+	// BackupNamePattern, if set, is a text/template for the names of
+	// backups, such as "{{.Prefix}}.{{.Date}}.{{.Seq}}{{.Ext}}", with the
+	// fields Prefix and Ext, the log file's name without and with only its
+	// extension, Date, the time of the rotation, and Seq, a number from 1 up
+	// telling apart backups with the same Date. Date must appear once. The
+	// compressed or encrypted suffix takes the place of Ext. The default is
+	// "{{.Prefix}}-{{.Date}}{{.Ext}}".
+	BackupNamePattern string `json:"backup_name_pattern"`
+
+	// SequentialNames names backups the classic way, app.log.1 for the most
+	// recent, app.log.2 for the one before and so on, renaming them all on
+	// every rotation, for tooling which expects that. Compressed backups
+	// become app.log.1.gz and so on. Backup times are then taken from the
+	// files' modification time. Backups can't be held, and BackupNamePattern
+	// and SplitOversized aren't supported in this mode.
+	SequentialNames bool `json:"sequential_names"`
+
+	// LocalTime determines if the time used for formatting the timestamps in
+	// backup files is the computer's local time.  The default is to use UTC
+	// time.
+	LocalTime bool `json:"localtime" yaml:"localtime"`
+
+	// CopyTruncate, if a rotation can't rename the log file, as happens on
+	// Windows while another process such as an antivirus scanner has it
+	// open, even after retrying for a while, copies the file to the backup
+	// and truncates it instead. It has no effect in append-only mode.
+	CopyTruncate bool `json:"copy_truncate"`
+
+	// RotateBackoff is how long to wait before retrying a failed rotation,
+	// doubling on every consecutive failure up to MaxRotateBackoff. Writes keep
+	// going to the current file in the meantime.
+	RotateBackoff    time.Duration `json:"rotate_backoff"`
+	MaxRotateBackoff time.Duration `json:"max_rotate_backoff"`
+
+	// MinFreeSpace is the number of bytes on the log volume the Logger will
+	// never eat into. When a write would breach the reserve, retention runs
+	// immediately; if the reserve is still breached afterwards the write is
+	// dropped and counted in Stats. Zero disables the check.
+	MinFreeSpace int64 `json:"min_free_space"`
+
+	// RateLimit caps writes at that many bytes per second on average, with
+	// bursts of up to RateBurst bytes, RateLimit if zero. Writes over the
+	// limit wait for their turn, or with RateLimitDrop are dropped and
+	// counted in Stats, as are writes bigger than the burst unless the
+	// allowance is whole. Zero disables the limit.
+	RateLimit     int64 `json:"rate_limit"`
+	RateBurst     int64 `json:"rate_burst"`
+	RateLimitDrop bool  `json:"rate_limit_drop"`
+
+	// DedupInterval turns on duplicate suppression: a write identical to the
+	// one before is counted rather than written, and a line saying "last
+	// message repeated N times" stands for the run once a different write
+	// comes, the Logger is closed, the run has lasted DedupInterval, or it
+	// has reached DedupMaxRepeats repeats if set. Runs are spotted per
+	// write, which loggers make a line each.
+	DedupInterval   time.Duration `json:"dedup_interval"`
+	DedupMaxRepeats int           `json:"dedup_max_repeats"`
+
+	// WriteTimeout bounds how long Write waits for a write, and the fsync
+	// or rotation that goes with it, as on a hung network mount. Past it,
+	// Write returns ErrWriteTimeout, and so do further writes at once until
+	// the stalled one is over, rather than queue up behind it. Each write
+	// then runs on a goroutine of its own, with a copy of its data. Zero
+	// waits as long as it takes.
+	WriteTimeout time.Duration `json:"write_timeout"`
+
+	// LowDiskWatermark is the free space on the log volume below which the
+	// Logger cleans up ahead of retention, checked at most every second while
+	// writing and on every mill pass. The oldest backups are removed, held
+	// ones excepted, until there is that much free space again or none is
+	// left, then the others are compressed or encrypted as configured without
+	// waiting for CompressAfter. Zero disables the check.
+	LowDiskWatermark int64 `json:"low_disk_watermark"`
+
+	// SyncEveryWrite fsyncs the file before Write returns. Concurrent writers
+	// share fsyncs: one fsync covers every write made before it started, and
+	// GroupCommitWindow, if set, is how long to wait for more writers to join
+	// before issuing it, trading a little latency for fewer fsyncs.
+	SyncEveryWrite    bool          `json:"sync_every_write"`
+	GroupCommitWindow time.Duration `json:"group_commit_window"`
+
+	// SyncInterval, if set, fsyncs the file at most that long after a write,
+	// a cheaper bound on what a crash can lose than SyncEveryWrite. In
+	// synchronous mode this is only checked on Write.
+	SyncInterval time.Duration `json:"sync_interval"`
+
+	// BufferSize, if positive, buffers up to that many bytes of writes in
+	// memory so that Write doesn't make a system call every time. The buffer
+	// is flushed when full, on Flush, and before the file is rotated or
+	// closed; what's buffered is lost if the process crashes.
+	// FlushInterval bounds that loss by also flushing the buffer at most
+	// that long after data was first buffered; in synchronous mode this is
+	// only checked on Write.
+	BufferSize    int           `json:"buffer_size"`
+	FlushInterval time.Duration `json:"flush_interval"`
+
+	// Async makes Write queue a copy of the data and return, leaving the
+	// actual write, and any rotation, to a background goroutine which takes
+	// the queued writes in batches. Queueing normally takes no lock, so that
+	// writers don't contend with one another. The queue holds AsyncQueueSize
+	// writes (1024 by default); to absorb a burst it grows by up to
+	// AsyncBurstSize more, which it lets go once they are written. Writes
+	// that still don't fit are dropped and counted in Stats.
+	Async          bool `json:"async"`
+	AsyncQueueSize int  `json:"async_queue_size"`
+	AsyncBurstSize int  `json:"async_burst_size"`
+
+	// ReopenOnMove checks, at most every second on Write, that the log file
+	// is still where it should be, and opens a new one there if it was moved
+	// away or deleted by another process, such as an operator or an external
+	// logrotate, rather than writing to the orphaned file forever. The file
+	// is identified by device and inode, or file ID on Windows.
+	ReopenOnMove bool `json:"reopen_on_move"`
+
+	// RotateOnHUP makes the Logger reopen its file when the process receives
+	// SIGHUP, as described on Reopen, for tools such as logrotate which move
+	// the file away and then signal the process. It has no effect on Windows.
+	RotateOnHUP bool `json:"rotate_on_hup"`
+
+	// SynchronousMill runs retention and compression inline, when the file is
+	// rotated or closed, and, unless a Scheduler is given, checks the rolling
+	// schedule on Write instead of from a timer, so the Logger never starts
+	// any goroutines. Meant for short lived processes that could exit in the
+	// middle of background work.
+	SynchronousMill bool `json:"synchronous_mill"`
+
+	// AdaptiveRotation watches how fast free space on the log volume is
+	// shrinking and, whenever it's projected to run out within
+	// AdaptiveHorizon (6 hours by default), halves the size at which the file
+	// is rotated, down to an eighth of MaxSize, so that backups get compressed
+	// and pruned sooner. The size is relaxed back to MaxSize as the pressure
+	// goes away.
+	AdaptiveRotation bool          `json:"adaptive_rotation"`
+	AdaptiveHorizon  time.Duration `json:"adaptive_horizon"`
+
+	// LockMetrics records how long writers wait for the write mutex and how
+	// many queue up behind it, reported in Stats().LockWait. It costs a couple
+	// of atomic operations and a clock read per Write.
+	LockMetrics bool `json:"lock_metrics"`
+
+	// Expvar, if set, is the name under which the Logger's Stats are
+	// published with the expvar package, and so served on /debug/vars. The
+	// Loggers of a Router, Sharded or LevelWriter each append "." and their
+	// key, shard number or level to it.
+	Expvar string `json:"expvar"`
+}
+
+// DefaultConfig returns the configuration NewWriter starts from before
+// applying its options.
+func DefaultConfig() Config {
+	return Config{
+		LogPath:       os.TempDir(),
+		Filename:      "all.log",
+		MaxAge:        30,
+		MaxRemain:     30,
+		RollingPolicy: VolumeRolling,
+		MaxSize:       15,
+		Compress:      false,
+		LocalTime:     false,
+	}
+}
@@ -0,0 +1,102 @@
+package rolling
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestNewFromConfig", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var cfg Config
+	err := json.Unmarshal([]byte(`{"logPath": "`+dir+`", "filename": "foobar.log", "rolling_policy": 2, "max_size": 10}`), &cfg)
+	isNil(err, t)
+
+	l, err := New(cfg)
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	equals(cfg, l.Config, t)
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+
+	newFakeTime()
+
+	b2 := []byte("0000000!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	existsWithContent(logFile(dir), b2, t)
+	existsWithContent(backupFile(dir), b, t)
+}
+
+func TestNewDefaultsPathAndFilename(t *testing.T) {
+	l, err := New(Config{})
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	def := DefaultConfig()
+	equals(def.LogPath, l.LogPath, t)
+	equals(def.Filename, l.Filename, t)
+	equals(WithoutRolling, l.RollingPolicy, t)
+}
+
+func TestClone(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestClone", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(3))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	_, err = l.Clone()
+	notNil(err, t)
+
+	c, err := l.Clone(WithFilename("other.log"))
+	isNil(err, t)
+	defer func() {
+		err := c.Close()
+		if err != nil {
+			return
+		}
+	}()
+	equals("other.log", c.Filename, t)
+	equals(l.MaxSize, c.MaxSize, t)
+	equals(l.MaxRemain, c.MaxRemain, t)
+
+	b := []byte("boo!")
+	_, err = c.Write(b)
+	isNil(err, t)
+	existsWithContent(filepath.Join(dir, "other.log"), b, t)
+}
@@ -0,0 +1,221 @@
+package rolling
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeSchedule gives the activation times of a time rolling schedule.
+type timeSchedule interface {
+	// Next returns the first activation time after t, in t's location, or
+	// the zero time if there is none within five years.
+	Next(t time.Time) time.Time
+}
+
+// cronSpec is a parsed cron expression, each field a set of allowed values
+// with bit i standing for the value i.
+type cronSpec struct {
+	second, minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set if the day of month or of week field was
+	// '*' or '?'. When neither is, a day matching either field is taken, as
+	// in crontab(5).
+	domAny, dowAny bool
+}
+
+// cronField is the range of values of a cron expression field, and the
+// names it accepts for them.
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var cronFields = [...]cronField{
+	{name: "second", max: 59},
+	{name: "minute", max: 59},
+	{name: "hour", max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", max: 6, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// cronDescriptors are the shorthands parseCron accepts, besides "@every".
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// parseCron parses a time rolling schedule in the format of
+// github.com/robfig/cron: six fields, for the second, minute, hour, day of
+// month, month and day of week, the last of which may be left out. A field
+// is '*' or '?' for any value, or a list of values, ranges such as "1-5"
+// and steps such as "*/15" or "10-40/10"; months and days of the week can
+// be given by their first three letters. It also takes the descriptors
+// "@hourly", "@daily" or "@midnight", "@weekly", "@monthly", "@yearly" or
+// "@annually", and "@every <duration>", such as "@every 90m".
+func parseCron(spec string) (timeSchedule, error) {
+	if strings.HasPrefix(spec, "@") {
+		if expr, ok := cronDescriptors[spec]; ok {
+			return parseCron(expr)
+		}
+		if d := strings.TrimPrefix(spec, "@every "); d != spec {
+			every, err := time.ParseDuration(strings.TrimSpace(d))
+			if err != nil {
+				return nil, err
+			}
+			return newEverySchedule(every), nil
+		}
+		return nil, fmt.Errorf("unknown descriptor %s", spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append(fields, "*")
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields, found %d", len(fields))
+	}
+	s := &cronSpec{}
+	bits := [...]*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, f := range cronFields {
+		var star bool
+		var err error
+		if *bits[i], star, err = f.parse(fields[i]); err != nil {
+			return nil, err
+		}
+		switch i {
+		case 3:
+			s.domAny = star
+		case 5:
+			s.dowAny = star
+		}
+	}
+	return s, nil
+}
+
+// parse returns the set of values expr allows, and whether it uses '*' or
+// '?' rather than only listing values.
+func (f cronField) parse(expr string) (bits uint64, star bool, err error) {
+	for _, item := range strings.Split(expr, ",") {
+		lo, hi, step := f.min, f.max, uint(1)
+		rng := item
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			if step, err = parseCronNumber(item[i+1:]); err != nil || step == 0 {
+				return 0, false, fmt.Errorf("invalid step in %s field %q", f.name, item)
+			}
+			rng = item[:i]
+		}
+		if rng == "*" || rng == "?" {
+			star = true
+		} else {
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, false, err
+			}
+			switch {
+			case len(bounds) == 2:
+				if hi, err = f.value(bounds[1]); err != nil {
+					return 0, false, err
+				}
+			case step == 1:
+				// a single value, while "5/15" runs to the end of the range
+				hi = lo
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, false, fmt.Errorf("%s field %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, star, nil
+}
+
+// value parses a value of the field, as a number or a name.
+func (f cronField) value(s string) (uint, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := parseCronNumber(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	return v, nil
+}
+
+func parseCronNumber(s string) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 8)
+	return uint(v), err
+}
+
+func (s *cronSpec) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = later(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !s.dayMatches(t):
+			t = later(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			// by elapsed time, as the next hour by the clock may not exist
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case s.second&(1<<uint(t.Second())) == 0:
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// later returns next, the start of a later month or day, unless a daylight
+// saving change skipped over it and time.Date put it before t, in which case
+// t an hour later.
+func later(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Hour)
+}
+
+// dayMatches reports whether t's day is allowed by the day of month and day
+// of week fields.
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// everySchedule activates at a fixed interval, of whole seconds.
+type everySchedule time.Duration
+
+func newEverySchedule(d time.Duration) everySchedule {
+	if d < time.Second {
+		d = time.Second
+	}
+	return everySchedule(d.Truncate(time.Second))
+}
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(e))
+}
@@ -0,0 +1,43 @@
+package rolling
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// dedupe reports whether p repeats the last write and is to be counted
+// rather than written, for DedupInterval. A run of repeats p ends has its
+// summary written first. l.mu must be held.
+func (l *Logger) dedupe(p []byte) bool {
+	if l.lastWrite == nil || !bytes.Equal(p, l.lastWrite) {
+		l.flushRepeats()
+		l.lastWrite = append(l.lastWrite[:0:0], p...)
+		return false
+	}
+
+	now := currentTime()
+	if l.repeats == 0 {
+		l.repeatsSince = now
+	}
+	l.repeats++
+	if now.Sub(l.repeatsSince) >= l.DedupInterval ||
+		(l.DedupMaxRepeats > 0 && l.repeats >= l.DedupMaxRepeats) {
+		// the summary stands for p too, and the next repeat starts a new
+		// run
+		l.flushRepeats()
+	}
+	return true
+}
+
+// flushRepeats writes the summary of the current run of repeats, if any.
+// l.mu must be held.
+func (l *Logger) flushRepeats() {
+	if l.repeats == 0 {
+		return
+	}
+	summary := fmt.Sprintf("last message repeated %d times\n", l.repeats)
+	l.repeats = 0
+	if _, _, err := l.writeLocked([]byte(summary), nil); err != nil {
+		l.reportError(fmt.Errorf("can't write repeat summary: %v", err))
+	}
+}
@@ -0,0 +1,47 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDedup", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1000),
+		WithDedup(time.Minute, 3))
+	isNil(err, t)
+
+	write := func(s string) {
+		n, err := l.Write([]byte(s))
+		isNil(err, t)
+		equals(len(s), n, t)
+	}
+	write("retry\n")
+	write("retry\n")
+	write("retry\n")
+	write("done\n")
+	existsWithContent(logFile(dir), []byte("retry\nlast message repeated 2 times\ndone\n"), t)
+
+	// a run reaching the maximum is summarized and starts over
+	for i := 0; i < 4; i++ {
+		write("done\n")
+	}
+	existsWithContent(logFile(dir), []byte("retry\nlast message repeated 2 times\ndone\n"+
+		"last message repeated 3 times\n"), t)
+
+	// and so is one lasting the interval
+	newFakeTime()
+	write("done\n")
+	existsWithContent(logFile(dir), []byte("retry\nlast message repeated 2 times\ndone\n"+
+		"last message repeated 3 times\nlast message repeated 2 times\n"), t)
+
+	write("done\n")
+	isNil(l.Close(), t)
+	existsWithContent(logFile(dir), []byte("retry\nlast message repeated 2 times\ndone\n"+
+		"last message repeated 3 times\nlast message repeated 2 times\nlast message repeated 1 times\n"), t)
+}
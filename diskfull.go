@@ -0,0 +1,68 @@
+package rolling
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// WriteErrorPolicy tells what a write does when the disk is full.
+type WriteErrorPolicy int
+
+const (
+	// WriteErrorReturn returns the error to the caller, as any other.
+	WriteErrorReturn WriteErrorPolicy = iota
+	// WriteErrorDrop discards what didn't make it to the file, counting it
+	// in DroppedWrites and DroppedBytes, and reports success.
+	WriteErrorDrop
+	// WriteErrorBlock retries the write, backing off up to
+	// maxDiskFullBackoff between attempts, until there is room for it. All
+	// writers, and Close, wait meanwhile.
+	WriteErrorBlock
+)
+
+func (p WriteErrorPolicy) String() string {
+	switch p {
+	case WriteErrorReturn:
+		return "return"
+	case WriteErrorDrop:
+		return "drop"
+	case WriteErrorBlock:
+		return "block"
+	}
+	return fmt.Sprintf("WriteErrorPolicy(%d)", int(p))
+}
+
+const (
+	minDiskFullBackoff = 10 * time.Millisecond
+	maxDiskFullBackoff = time.Second
+)
+
+// diskFullWait waits before the next attempt at a write which found the disk
+// full. It is a variable so tests can skip the wait.
+var diskFullWait = time.Sleep
+
+// retryDiskFull writes p to the file, waiting for room as long as it takes,
+// for WriteErrorBlock. Errors other than a full disk end it. l.mu must be
+// held.
+func (l *Logger) retryDiskFull(p []byte) (n int, err error) {
+	backoff := minDiskFullBackoff
+	for {
+		diskFullWait(backoff)
+		m, err := l.writeFile(p[n:])
+		n += m
+		if err == nil || !isDiskFull(err) {
+			return n, err
+		}
+		if backoff *= 2; backoff > maxDiskFullBackoff {
+			backoff = maxDiskFullBackoff
+		}
+	}
+}
+
+// dropDiskFull counts p, which the disk had no room for, as dropped, for
+// WriteErrorDrop.
+func (l *Logger) dropDiskFull(p []byte) {
+	atomic.AddUint64(&l.stats.droppedWrites, 1)
+	atomic.AddUint64(&l.stats.droppedBytes, uint64(len(p)))
+}
@@ -0,0 +1,78 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// fullDiskLogger returns a Logger whose file is a link to /dev/full, where
+// every write fails with ENOSPC.
+func fullDiskLogger(dir string, t *testing.T, options ...Option) *Logger {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+	isNil(os.Symlink("/dev/full", logFile(dir)), t)
+	l, err := NewWriter(append([]Option{WithLogPath(dir), WithFilename(logName()), WithMaxSize(100)}, options...)...)
+	isNil(err, t)
+	return l
+}
+
+func TestWriteErrorPolicyReturn(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWriteErrorPolicyReturn", t)
+	defer os.RemoveAll(dir)
+
+	l := fullDiskLogger(dir, t)
+	defer l.Close()
+	n, err := l.Write([]byte("boo!"))
+	assert(isDiskFull(err), t, "expected a full disk, got %v", err)
+	equals(0, n, t)
+}
+
+func TestWriteErrorPolicyDrop(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWriteErrorPolicyDrop", t)
+	defer os.RemoveAll(dir)
+
+	l := fullDiskLogger(dir, t, WithWriteErrorPolicy(WriteErrorDrop))
+	defer l.Close()
+	n, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals(4, n, t)
+	stats := l.Stats()
+	equals(uint64(1), stats.DroppedWrites, t)
+	equals(uint64(4), stats.DroppedBytes, t)
+	equals(uint64(0), stats.WriteErrors, t)
+}
+
+func TestWriteErrorPolicyBlock(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWriteErrorPolicyBlock", t)
+	defer os.RemoveAll(dir)
+
+	l := fullDiskLogger(dir, t, WithWriteErrorPolicy(WriteErrorBlock))
+	defer l.Close()
+
+	// room is made on the third attempt
+	var waits []time.Duration
+	defer func() { diskFullWait = time.Sleep }()
+	diskFullWait = func(d time.Duration) {
+		waits = append(waits, d)
+		if len(waits) == 3 {
+			f, err := os.Create(backupFile(dir))
+			isNil(err, t)
+			_ = l.file.Close()
+			l.file = f
+		}
+	}
+
+	n, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals(4, n, t)
+	equals([]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}, waits, t)
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+}
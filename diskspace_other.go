@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package rolling
+
+import "errors"
+
+// freeSpace is not implemented on this platform.
+func freeSpace(dir string) (int64, error) {
+	return 0, errors.New("free space query not supported on this platform")
+}
+
+// isDiskFull is not implemented on this platform, where no error is taken
+// for a full disk.
+func isDiskFull(err error) bool {
+	return false
+}
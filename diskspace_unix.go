@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package rolling
+
+import (
+	"errors"
+	"syscall"
+)
+
+// freeSpace returns the number of bytes available to an unprivileged user on
+// the volume holding dir.
+func freeSpace(dir string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// isDiskFull reports whether err is due to the volume being full.
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
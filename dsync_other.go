@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package rolling
+
+import "os"
+
+// oDsync falls back to O_SYNC where O_DSYNC isn't available.
+const oDsync = os.O_SYNC
@@ -0,0 +1,10 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package rolling
+
+import "syscall"
+
+// oDsync makes every write wait for its data to reach stable storage, but
+// not for metadata such as the modification time, for SynchronousIO.
+const oDsync = syscall.O_DSYNC
@@ -0,0 +1,487 @@
+package rolling
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Encrypted backups are made of a header followed by a sequence of chunks.
+// The header is encryptMagic, the length of the key ID as a single byte, the
+// key ID and a random nonce prefix. Each chunk is the big-endian 32-bit
+// length of its ciphertext followed by up to encryptChunkSize bytes of data
+// sealed with AES-256-GCM. A chunk's nonce is the nonce prefix, the chunk's
+// index and a flag marking the final chunk, so that chunks can't be
+// reordered, dropped or truncated without detection.
+const (
+	encryptSuffix    = ".enc"
+	encryptMagic     = "RLENC1"
+	encryptChunkSize = 64 * 1024
+	noncePrefixSize  = 7
+)
+
+// EncryptionKey is one version of the key backups are encrypted with.
+type EncryptionKey struct {
+	// ID names the key version. It is recorded in every backup encrypted with
+	// the key so readers know which key to use, and must be 1 to 255 bytes
+	// long.
+	ID string
+	// Secret is the 32 byte AES-256 key.
+	Secret []byte
+}
+
+// KeyProvider supplies the keys backups are encrypted with. New backups are
+// encrypted with CurrentKey, which may change over time; GetKey must be able
+// to return any key backups still on disk were encrypted with. Methods may be
+// called concurrently.
+type KeyProvider interface {
+	CurrentKey() (EncryptionKey, error)
+	GetKey(id string) (EncryptionKey, error)
+}
+
+// keyring is the KeyProvider for a fixed list of key versions, the newest
+// last.
+type keyring struct {
+	keys []EncryptionKey
+}
+
+// StaticKeys returns a KeyProvider for a fixed list of key versions, the
+// newest last.
+func StaticKeys(keys ...EncryptionKey) (KeyProvider, error) {
+	return newKeyring(keys)
+}
+
+func newKeyring(keys []EncryptionKey) (*keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys")
+	}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if err := k.validate(); err != nil {
+			return nil, err
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("duplicate encryption key ID %q", k.ID)
+		}
+		seen[k.ID] = true
+	}
+	return &keyring{keys: keys}, nil
+}
+
+func (k EncryptionKey) validate() error {
+	if len(k.ID) == 0 || len(k.ID) > 255 {
+		return fmt.Errorf("invalid encryption key ID %q", k.ID)
+	}
+	if len(k.Secret) != 32 {
+		return fmt.Errorf("encryption key %q must be 32 bytes", k.ID)
+	}
+	return nil
+}
+
+// CurrentKey returns the newest key.
+func (k *keyring) CurrentKey() (EncryptionKey, error) {
+	return k.keys[len(k.keys)-1], nil
+}
+
+// GetKey returns the key with the given ID.
+func (k *keyring) GetKey(id string) (EncryptionKey, error) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return EncryptionKey{}, fmt.Errorf("unknown encryption key %q", id)
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptWriter encrypts what is written to it into w.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix [noncePrefixSize]byte
+	index  uint32
+	buf    []byte
+}
+
+func newEncryptWriter(w io.Writer, key EncryptionKey) (*encryptWriter, error) {
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+	ew := &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, encryptChunkSize)}
+	if _, err := io.ReadFull(rand.Reader, ew.prefix[:]); err != nil {
+		return nil, err
+	}
+	header := append([]byte(encryptMagic), byte(len(key.ID)))
+	header = append(header, key.ID...)
+	header = append(header, ew.prefix[:]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// a full chunk is only sealed once more data shows it isn't the last
+		if len(ew.buf) == encryptChunkSize {
+			if err := ew.seal(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(ew.buf[len(ew.buf):encryptChunkSize], p)
+		ew.buf = ew.buf[:len(ew.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Close seals the final chunk. It doesn't close the underlying writer.
+func (ew *encryptWriter) Close() error {
+	return ew.seal(true)
+}
+
+func (ew *encryptWriter) seal(last bool) error {
+	ct := ew.aead.Seal(nil, chunkNonce(ew.prefix, ew.index, last), ew.buf, nil)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(ct)))
+	if _, err := ew.w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := ew.w.Write(ct); err != nil {
+		return err
+	}
+	ew.index++
+	ew.buf = ew.buf[:0]
+	return nil
+}
+
+func chunkNonce(prefix [noncePrefixSize]byte, index uint32, last bool) []byte {
+	nonce := make([]byte, noncePrefixSize+5)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], index)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+var (
+	errNoKeys          = errors.New("backup is encrypted but no keys are configured")
+	errNoAgeIdentities = errors.New("backup is encrypted with age but no identities are configured")
+)
+
+// keyError reports that the key a backup was encrypted with can't be had or
+// used, which says nothing about the backup itself.
+type keyError struct {
+	ID  string
+	Err error
+}
+
+func (e *keyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *keyError) Unwrap() error {
+	return e.Err
+}
+
+// readKeyID reads the header of an encrypted backup up to and including the
+// key ID.
+func readKeyID(r io.Reader) (string, error) {
+	header := make([]byte, len(encryptMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("can't read encryption header: %v", err)
+	}
+	if string(header[:len(encryptMagic)]) != encryptMagic {
+		return "", errors.New("not an encrypted log file")
+	}
+	id := make([]byte, header[len(encryptMagic)])
+	if _, err := io.ReadFull(r, id); err != nil {
+		return "", fmt.Errorf("can't read encryption header: %v", err)
+	}
+	return string(id), nil
+}
+
+// decryptReader decrypts an encrypted backup.
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	prefix [noncePrefixSize]byte
+	index  uint32
+	buf    []byte
+	done   bool
+}
+
+// newDecryptReader reads the header of the encrypted backup in r and returns
+// a reader for its plaintext, using whichever key in keys it was encrypted
+// with.
+func newDecryptReader(r io.Reader, keys KeyProvider) (*decryptReader, error) {
+	br := bufio.NewReader(r)
+	id, err := readKeyID(br)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keys.GetKey(id)
+	if err != nil {
+		return nil, &keyError{ID: id, Err: err}
+	}
+	aead, err := newAEAD(key.Secret)
+	if err != nil {
+		return nil, &keyError{ID: id, Err: err}
+	}
+	dr := &decryptReader{r: br, aead: aead}
+	if _, err := io.ReadFull(br, dr.prefix[:]); err != nil {
+		return nil, fmt.Errorf("can't read encryption header: %v", err)
+	}
+	return dr, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err := dr.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+// open decrypts the next chunk.
+func (dr *decryptReader) open() error {
+	var size [4]byte
+	if _, err := io.ReadFull(dr.r, size[:]); err != nil {
+		return fmt.Errorf("encrypted log file is truncated: %v", err)
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > uint32(encryptChunkSize+dr.aead.Overhead()) {
+		return errors.New("encrypted log file is corrupt: chunk too large")
+	}
+	ct := make([]byte, n)
+	if _, err := io.ReadFull(dr.r, ct); err != nil {
+		return fmt.Errorf("encrypted log file is truncated: %v", err)
+	}
+	_, err := dr.r.Peek(1)
+	last := err == io.EOF
+	pt, err := dr.aead.Open(ct[:0], chunkNonce(dr.prefix, dr.index, last), ct, nil)
+	if err != nil {
+		return fmt.Errorf("encrypted log file is corrupt or truncated: %v", err)
+	}
+	dr.index++
+	dr.buf, dr.done = pt, last
+	return nil
+}
+
+// encryptLogFile encrypts src into dst with the current key and removes src.
+// Like compressLogFile, it keeps the original's permissions and mtime.
+func encryptLogFile(src, dst string, keys KeyProvider) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+	key, err := keys.CurrentKey()
+	if err != nil {
+		return fmt.Errorf("failed to get encryption key: %v", err)
+	}
+	if err := writeEncrypted(dst, fi, f, key); err != nil {
+		return fmt.Errorf("failed to encrypt log file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// writeEncrypted encrypts what it reads from r with key into a new file at
+// dst, as writeSealed does.
+func writeEncrypted(dst string, fi os.FileInfo, r io.Reader, key EncryptionKey) error {
+	return writeSealed(dst, fi, r, func(w io.Writer) (io.WriteCloser, error) {
+		return newEncryptWriter(w, key)
+	})
+}
+
+// writeSealed encrypts what it reads from r into a new file at dst, with the
+// writer seal wraps the file in, and the mode and mtime of fi. The file is
+// written and synced under a temporary name first, so dst is either replaced
+// whole or not at all.
+func writeSealed(dst string, fi os.FileInfo, r io.Reader, seal func(io.Writer) (io.WriteCloser, error)) (err error) {
+	tmp := dst + tmpSuffix
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	ew, err := seal(out)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ew, r); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// OpenBackup opens the backup at path for reading, transparently decrypting
+// and decompressing it.
+func (l *Logger) OpenBackup(path string) (io.ReadCloser, error) {
+	prefix, ext := l.prefixAndExt()
+	info, ok := l.parseBackupName(filepath.Base(path), prefix, ext)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a backup of %s", path, l.Filename)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = f
+	if info.encrypted {
+		if r, err = l.decrypt(r, info); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	if info.compressed {
+		dr, err := newDecompressor(r)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return readCloser{dr, []io.Closer{dr, f}}, nil
+	}
+	return readCloser{r, []io.Closer{f}}, nil
+}
+
+// readCloser reads from a decoding reader and closes it along with the
+// underlying file.
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (rc readCloser) Close() error {
+	var err error
+	for _, c := range rc.closers {
+		if errClose := c.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// RetireKey re-encrypts every backup encrypted with the key id using the
+// current key, after which the retired key can be dropped from the
+// KeyProvider. Each backup is replaced atomically.
+func (l *Logger) RetireKey(id string) error {
+	if l.AppendOnly {
+		return errAppendOnly
+	}
+	if l.keys == nil {
+		return errors.New("no encryption keys configured")
+	}
+	current, err := l.keys.CurrentKey()
+	if err != nil {
+		return err
+	}
+	if current.ID == id {
+		return errors.New("can't retire the current encryption key")
+	}
+
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	// backups still waiting in LogPath for their move to ArchiveDir too
+	files, err := l.allLogFiles()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !f.encrypted {
+			continue
+		}
+		if errKey := l.reencrypt(l.backupPath(f), id, current); err == nil {
+			err = errKey
+		}
+	}
+	return err
+}
+
+// reencrypt re-encrypts the backup at path with key if it is encrypted with
+// the key id.
+func (l *Logger) reencrypt(path, id string, key EncryptionKey) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if keyID, err := readKeyID(f); err != nil || keyID != id {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	dr, err := newDecryptReader(f, l.keys)
+	if err != nil {
+		return err
+	}
+
+	if err := writeEncrypted(path, fi, dr, key); err != nil {
+		return fmt.Errorf("failed to re-encrypt %s: %v", path, err)
+	}
+	if l.Checksums {
+		return writeChecksum(path)
+	}
+	return nil
+}
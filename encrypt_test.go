@@ -0,0 +1,141 @@
+package rolling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(id string) EncryptionKey {
+	return EncryptionKey{ID: id, Secret: bytes.Repeat([]byte(id[:1]), 32)}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	keys, err := newKeyring([]EncryptionKey{testKey("a")})
+	isNil(err, t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), encryptChunkSize/8+3)
+	var buf bytes.Buffer
+	key, err := keys.CurrentKey()
+	isNil(err, t)
+	ew, err := newEncryptWriter(&buf, key)
+	isNil(err, t)
+	_, err = ew.Write(data)
+	isNil(err, t)
+	isNil(ew.Close(), t)
+	enc := buf.Bytes()
+
+	dr, err := newDecryptReader(bytes.NewReader(enc), keys)
+	isNil(err, t)
+	b, err := ioutil.ReadAll(dr)
+	isNil(err, t)
+	equals(true, bytes.Equal(data, b), t)
+
+	// dropping the final chunk must not go unnoticed
+	last := len(enc) - (len(data) - 2*encryptChunkSize) - (4 + 16)
+	dr, err = newDecryptReader(bytes.NewReader(enc[:last]), keys)
+	isNil(err, t)
+	_, err = ioutil.ReadAll(dr)
+	notNil(err, t)
+
+	_, err = newKeyring([]EncryptionKey{{ID: "short", Secret: []byte("x")}})
+	notNil(err, t)
+}
+
+func TestEncryptedBackupsAndKeyRotation(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestEncryptedBackupsAndKeyRotation", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress(),
+		WithEncryption(testKey("old")))
+	isNil(err, t)
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+	<-time.After(10 * time.Millisecond)
+	isNil(l.Close(), t)
+
+	backup := backupFile(dir) + compressSuffix + encryptSuffix
+	exists(backup, t)
+	notExist(backupFile(dir), t)
+	notExist(backupFile(dir)+compressSuffix, t)
+	fileCount(dir, 2, t)
+
+	// a new key is added and the old one retired
+	l, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress(),
+		WithEncryption(testKey("old"), testKey("new")))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	notNil(l.RetireKey("new"), t)
+	isNil(l.RetireKey("old"), t)
+
+	f, err := os.Open(backup)
+	isNil(err, t)
+	id, err := readKeyID(f)
+	isNil(err, t)
+	isNil(f.Close(), t)
+	equals("new", id, t)
+
+	l.keys, err = StaticKeys(testKey("new"))
+	isNil(err, t)
+	r, err := l.OpenBackup(backup)
+	isNil(err, t)
+	got, err := ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals(b, got, t)
+	fileCount(dir, 2, t)
+}
+
+func TestRetireKeyPendingArchival(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRetireKeyPendingArchival", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithEncryption(testKey("old")), WithSynchronousMill())
+	isNil(err, t)
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+	backup := backupFile(dir) + encryptSuffix
+	exists(backup, t)
+
+	// the backup is still in LogPath, waiting for the mill to move it to
+	// the archive directory
+	l, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithArchiveDir(archive),
+		WithEncryption(testKey("old"), testKey("new")))
+	isNil(err, t)
+	defer l.Close()
+	isNil(l.RetireKey("old"), t)
+
+	f, err := os.Open(backup)
+	isNil(err, t)
+	id, err := readKeyID(f)
+	isNil(err, t)
+	isNil(f.Close(), t)
+	equals("new", id, t)
+}
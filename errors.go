@@ -0,0 +1,29 @@
+package rolling
+
+// errorsBuffer is how many errors the Errors channel holds for a slow
+// reader before further ones are dropped.
+const errorsBuffer = 64
+
+// Errors returns a channel receiving the errors which have no caller to be
+// returned to, as passed to the WithErrorHandler handler: failed background
+// rotations, mill passes, flushes and fsyncs, async writes and the like.
+// Errors are dropped rather than block the Logger while the channel is full,
+// and it is never closed.
+func (l *Logger) Errors() <-chan error {
+	return l.errs
+}
+
+// reportError passes err to the error handler, if there is one, and to the
+// Errors channel unless it's full. A nil err is ignored.
+func (l *Logger) reportError(err error) {
+	if err == nil {
+		return
+	}
+	if l.onError != nil {
+		l.onError(err)
+	}
+	select {
+	case l.errs <- err:
+	default:
+	}
+}
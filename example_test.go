@@ -2,12 +2,16 @@ package rolling
 
 import (
 	"log"
+	"os"
 	"testing"
 )
 
 func TestExample(t *testing.T) {
+	dir := makeTempDir("TestExample", t)
+	defer os.RemoveAll(dir)
+
 	writer, _ := NewWriter(
-		WithLogPath("E:\\public\\public_project\\files\\log"),
+		WithLogPath(dir),
 		WithFilename("all.log"),
 		WithMaxRemain(100), // 保留 10 个文件
 		WithMaxSize(3),     // 每个文件最大为 10M
@@ -17,6 +21,8 @@ func TestExample(t *testing.T) {
 		WithTimeRolling(),
 		WithTimePattern("*/5 * * * * ?"),
 	)
+	defer writer.Close()
 	log.SetOutput(writer)
+	defer log.SetOutput(os.Stderr)
 	log.Println("Hello World")
 }
@@ -0,0 +1,60 @@
+package rolling
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// expvars maps the expvar names published for Loggers to the Logger
+// currently behind each. expvar has no way to remove a variable, so a name is
+// published once and then reused by whichever Logger asks for it next.
+var expvars = struct {
+	sync.Mutex
+	loggers map[string]*Logger
+}{loggers: make(map[string]*Logger)}
+
+// publishExpvar publishes l's Stats under name, taking it over from any
+// other Logger.
+func publishExpvar(name string, l *Logger) error {
+	expvars.Lock()
+	defer expvars.Unlock()
+	if _, ok := expvars.loggers[name]; !ok {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("expvar %q is already published", name)
+		}
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			expvars.Lock()
+			l := expvars.loggers[name]
+			expvars.Unlock()
+			if l == nil {
+				return nil
+			}
+			return l.Stats()
+		}))
+	}
+	expvars.loggers[name] = l
+	return nil
+}
+
+// withExpvarSuffix has a Logger derived from a shared configuration, as by a
+// Manager, Router, Sharded or LevelWriter, publish its Stats under the
+// configured expvar name followed by "." and suffix, rather than every such
+// Logger taking the name over in turn.
+func withExpvarSuffix(suffix string) Option {
+	return func(logger *Logger) {
+		if logger.Expvar != "" {
+			logger.Expvar += "." + suffix
+		}
+	}
+}
+
+// unpublishExpvar stops publishing l's Stats under name, which then reads
+// as null until another Logger takes it over.
+func unpublishExpvar(name string, l *Logger) {
+	expvars.Lock()
+	defer expvars.Unlock()
+	if expvars.loggers[name] == l {
+		expvars.loggers[name] = nil
+	}
+}
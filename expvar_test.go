@@ -0,0 +1,78 @@
+package rolling
+
+import (
+	"encoding/json"
+	"expvar"
+	"os"
+	"testing"
+)
+
+func TestExpvar(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestExpvar", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithExpvar("TestExpvar"))
+	isNil(err, t)
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	var s Stats
+	isNil(json.Unmarshal([]byte(expvar.Get("TestExpvar").String()), &s), t)
+	equals(uint64(2), s.Writes, t)
+	equals(uint64(12), s.BytesWritten, t)
+	equals(uint64(1), s.Rotations, t)
+	equals(fakeTime().UnixNano(), s.LastRotation.UnixNano(), t)
+
+	// a second Logger takes the name over
+	l2, err := NewWriter(WithLogPath(dir), WithFilename("other.log"), WithExpvar("TestExpvar"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+	isNil(json.Unmarshal([]byte(expvar.Get("TestExpvar").String()), &s), t)
+	equals(uint64(0), s.Writes, t)
+	isNil(l2.Close(), t)
+	equals("null", expvar.Get("TestExpvar").String(), t)
+
+	// names published by others are left alone
+	if expvar.Get("TestExpvarTaken") == nil {
+		expvar.NewInt("TestExpvarTaken")
+	}
+	_, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithExpvar("TestExpvarTaken"))
+	notNil(err, t)
+}
+
+func TestExpvarDerived(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestExpvarDerived", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithExpvar("TestExpvarDerived"))
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// a clone doesn't take the name over, nor drop it when closed
+	clone, err := l.Clone(WithFilename("clone.log"))
+	isNil(err, t)
+	isNil(clone.Close(), t)
+	var s Stats
+	isNil(json.Unmarshal([]byte(expvar.Get("TestExpvarDerived").String()), &s), t)
+	equals(uint64(1), s.Writes, t)
+
+	// shards get names of their own
+	sh, err := NewSharded(nil, 2, WithLogPath(dir), WithFilename("shard.log"), WithExpvar("TestExpvarDerived"))
+	isNil(err, t)
+	defer sh.Close()
+	_, err = sh.Write([]byte("foo!"))
+	isNil(err, t)
+	isNil(json.Unmarshal([]byte(expvar.Get("TestExpvarDerived.0").String()), &s), t)
+	equals(uint64(1), s.Writes, t)
+	notNil(expvar.Get("TestExpvarDerived.1"), t)
+	isNil(json.Unmarshal([]byte(expvar.Get("TestExpvarDerived").String()), &s), t)
+	equals(uint64(1), s.Writes, t)
+}
@@ -0,0 +1,81 @@
+package rolling
+
+import "context"
+
+// followBuffer is how many writes a Follow channel holds for a consumer
+// falling behind before further ones are dropped.
+const followBuffer = 256
+
+// follower is a consumer of the writes streamed by Follow.
+type follower struct {
+	ch chan []byte
+	// done is closed along with ch, when the Logger is closed, for the
+	// goroutine waiting on the context to give up.
+	done chan struct{}
+}
+
+// Follow streams what is written to the Logger from now on, as tail -F
+// would the file, so that an admin endpoint or debug UI can show the log
+// live without watching the filesystem. Every successful write is sent on
+// the channel as a copy, whatever the file it went to, so rotations are
+// seamless. A consumer falling more than a few hundred writes behind misses
+// the newer ones rather than hold up the Logger. The channel is closed when
+// ctx is done or the Logger is closed.
+func (l *Logger) Follow(ctx context.Context) (<-chan []byte, error) {
+	f := &follower{ch: make(chan []byte, followBuffer), done: make(chan struct{})}
+	l.mu.Lock()
+	if l.file == nil {
+		l.mu.Unlock()
+		return nil, errClosed
+	}
+	if l.followers == nil {
+		l.followers = make(map[*follower]struct{})
+	}
+	l.followers[f] = struct{}{}
+	l.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.unfollow(f)
+		case <-f.done:
+		}
+	}()
+	return f.ch, nil
+}
+
+// unfollow stops streaming writes to f and closes its channel, unless the
+// Logger did already.
+func (l *Logger) unfollow(f *follower) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.followers[f]; ok {
+		delete(l.followers, f)
+		close(f.ch)
+	}
+}
+
+// publish sends a copy of p, which was just written, to every follower with
+// room for it, each its own so that one consumer can't change what another
+// sees. l.mu must be held.
+func (l *Logger) publish(p []byte) {
+	for f := range l.followers {
+		if len(f.ch) == cap(f.ch) {
+			continue
+		}
+		select {
+		case f.ch <- append([]byte(nil), p...):
+		default:
+		}
+	}
+}
+
+// closeFollowers closes the channel of every follower, for Close. l.mu must
+// be held.
+func (l *Logger) closeFollowers() {
+	for f := range l.followers {
+		close(f.ch)
+		close(f.done)
+	}
+	l.followers = nil
+}
@@ -0,0 +1,54 @@
+package rolling
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestFollow(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestFollow", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10))
+	isNil(err, t)
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := l.Follow(ctx)
+	isNil(err, t)
+	all, err := l.Follow(context.Background())
+	isNil(err, t)
+
+	// the writes keep coming across a rotation
+	b := []byte("boo!boo!")
+	for _, s := range []string{"boo!", "foo!", "bar!"} {
+		copy(b, s)
+		_, err = l.Write(b[:4])
+		isNil(err, t)
+	}
+	equals(uint64(1), l.Stats().Rotations, t)
+	for _, exp := range []string{"boo!", "foo!", "bar!"} {
+		got := <-ch
+		equals(exp, string(got), t)
+		// each consumer gets a copy of its own
+		copy(got, "moo!")
+		equals(exp, string(<-all), t)
+	}
+
+	cancel()
+	_, ok := <-ch
+	equals(false, ok, t)
+
+	_, err = l.Write([]byte("baz!"))
+	isNil(err, t)
+	equals("baz!", string(<-all), t)
+
+	isNil(l.Close(), t)
+	_, ok = <-all
+	equals(false, ok, t)
+	_, err = l.Follow(context.Background())
+	equals(errClosed, err, t)
+}
@@ -0,0 +1,91 @@
+package rolling
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// defaultGCSChunkSize is the size of the chunks of resumable GCS uploads,
+// the default of the Go client library.
+const defaultGCSChunkSize = 16 << 20
+
+// GCSClient is what the GCS archiver needs from a Google Cloud Storage
+// client: a writer uploading to an object, which is created when the writer
+// is closed without error and abandoned if ctx is cancelled first. Objects
+// larger than chunkSize should be sent as a resumable upload in chunks of
+// that size, each retried on its own. With cloud.google.com/go/storage:
+//
+//	type gcsUploader struct{ client *storage.Client }
+//
+//	func (u gcsUploader) NewWriter(ctx context.Context, bucket, object string, chunkSize int) io.WriteCloser {
+//		w := u.client.Bucket(bucket).Object(object).NewWriter(ctx)
+//		w.ChunkSize = chunkSize
+//		return w
+//	}
+type GCSClient interface {
+	NewWriter(ctx context.Context, bucket, object string, chunkSize int) io.WriteCloser
+}
+
+// GCSOption configures the archiver set up by WithGCSArchive.
+type GCSOption func(*gcsArchiver)
+
+// GCSChunkSize sets the size of the chunks of resumable uploads, 16MiB by
+// default. Bigger chunks upload faster but take as much memory, and more to
+// resend when one fails.
+func GCSChunkSize(size int) GCSOption {
+	return func(a *gcsArchiver) {
+		a.chunkSize = size
+	}
+}
+
+// GCSOnUpload has fn called with the local path and the object name of every
+// backup uploaded.
+func GCSOnUpload(fn func(localPath, object string)) GCSOption {
+	return func(a *gcsArchiver) {
+		a.onUpload = fn
+	}
+}
+
+// gcsArchiver uploads backups to bucket, under prefix.
+type gcsArchiver struct {
+	client    GCSClient
+	bucket    string
+	prefix    string
+	chunkSize int
+	onUpload  func(localPath, object string)
+}
+
+func (a *gcsArchiver) Archive(ctx context.Context, localPath string) error {
+	object := path.Join(a.prefix, filepath.Base(localPath))
+	err := retry(ctx, uploadAttempts, uploadBackoff, func() error {
+		return a.put(ctx, localPath, object)
+	})
+	if err == nil && a.onUpload != nil {
+		a.onUpload(localPath, object)
+	}
+	return err
+}
+
+// put uploads the file at localPath as object.
+func (a *gcsArchiver) put(ctx context.Context, localPath, object string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// cancelling is how an upload is abandoned rather than completed with
+	// what was written so far
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := a.client.NewWriter(ctx, a.bucket, object, a.chunkSize)
+	if _, err := io.Copy(w, f); err != nil {
+		cancel()
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
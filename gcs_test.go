@@ -0,0 +1,78 @@
+package rolling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// fakeGCS is a GCSClient keeping objects in memory. Its writers fail after
+// failAfter bytes, if set, and record the chunk size asked for.
+type fakeGCS struct {
+	objects   map[string][]byte
+	chunkSize int
+	failAfter int
+}
+
+func (s *fakeGCS) NewWriter(ctx context.Context, bucket, object string, chunkSize int) io.WriteCloser {
+	s.chunkSize = chunkSize
+	return &fakeGCSWriter{s: s, ctx: ctx, name: bucket + "/" + object}
+}
+
+type fakeGCSWriter struct {
+	s    *fakeGCS
+	ctx  context.Context
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *fakeGCSWriter) Write(p []byte) (int, error) {
+	if w.s.failAfter > 0 && w.buf.Len()+len(p) > w.s.failAfter {
+		return 0, errors.New("connection reset")
+	}
+	return w.buf.Write(p)
+}
+
+func (w *fakeGCSWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	w.s.objects[w.name] = w.buf.Bytes()
+	return nil
+}
+
+func TestGCSArchive(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestGCSArchive", t)
+	defer os.RemoveAll(dir)
+
+	gcs := &fakeGCS{objects: make(map[string][]byte), failAfter: 2}
+	var uploaded []string
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithSynchronousMill(),
+		WithGCSArchive(gcs, "bucket", "logs", GCSChunkSize(1<<20), GCSOnUpload(func(localPath, object string) {
+			uploaded = append(uploaded, object)
+		})))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	// an interrupted upload leaves no object behind
+	equals(0, len(gcs.objects), t)
+	equals(0, len(uploaded), t)
+	equals(1<<20, gcs.chunkSize, t)
+
+	gcs.failAfter = 0
+	isNil(l.millRunOnce(), t)
+	name := "logs/" + backupFile(dir)[len(dir)+1:]
+	equals([]string{name}, uploaded, t)
+	equals([]byte("boo!"), gcs.objects["bucket/"+name], t)
+}
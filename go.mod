@@ -2,4 +2,7 @@ module github.com/cnof/rolling
 
 go 1.17
 
-require github.com/robfig/cron v1.2.0
+require (
+	github.com/klauspost/compress v1.15.15
+	github.com/pierrec/lz4/v4 v4.1.18
+)
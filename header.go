@@ -0,0 +1,28 @@
+package rolling
+
+// writeHeader writes the header returned by the WithFileHeader function at
+// the top of the current file, unless the file already has content, as when
+// appending to an existing file on open. A newline is added to a header not
+// ending with one, so that it takes whole lines. l.mu must be held.
+func (l *Logger) writeHeader() error {
+	if l.fileHeader == nil {
+		return nil
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if l.openSize = info.Size(); l.openSize > 0 {
+		return nil
+	}
+	header := l.fileHeader()
+	if len(header) == 0 {
+		return nil
+	}
+	if header[len(header)-1] != '\n' {
+		header = append(header[:len(header):len(header)], '\n')
+	}
+	n, err := l.file.Write(header)
+	l.written += int64(n)
+	return err
+}
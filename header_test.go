@@ -0,0 +1,42 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFileHeader(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestFileHeader", t)
+	defer os.RemoveAll(dir)
+
+	files := 0
+	header := func() []byte {
+		files++
+		return []byte("# app v1.2.3")
+	}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(30), WithFileHeader(header))
+	isNil(err, t)
+
+	_, err = l.Write([]byte("boo!\n"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("# app v1.2.3\nboo!\n"), t)
+
+	// every new file gets one
+	newFakeTime()
+	_, err = l.Write([]byte("0123456789abcdefghij\n"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("# app v1.2.3\nboo!\n"), t)
+	existsWithContent(logFile(dir), []byte("# app v1.2.3\n0123456789abcdefghij\n"), t)
+	isNil(l.Close(), t)
+
+	// but a file that isn't new is appended to as is
+	l, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithFileHeader(header))
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("foo\n"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("# app v1.2.3\n0123456789abcdefghij\nfoo\n"), t)
+	equals(2, files, t)
+}
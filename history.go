@@ -0,0 +1,90 @@
+package rolling
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// HistoryReader returns a reader streaming what was written since the given
+// time, oldest first: the backups from the one holding since on, decrypted
+// and decompressed as needed, then the log file, buffered writes included.
+// A backup is assumed to hold what was written after the previous backup was
+// rotated out, up to its own timestamp, so the content may start somewhat
+// before since; lines aren't filtered. A zero since reads everything.
+//
+// The files are opened right away, so that neither rotation nor retention
+// makes the reader skip or repeat anything; writes made meanwhile to the log
+// file may or may not be read. The reader must be closed.
+func (l *Logger) HistoryReader(since time.Time) (io.ReadCloser, error) {
+	for {
+		rotations := atomic.LoadUint64(&l.stats.rotations)
+		h, err := l.openBackupsSince(since)
+		if err != nil {
+			return nil, err
+		}
+
+		l.mu.Lock()
+		if atomic.LoadUint64(&l.stats.rotations) != rotations {
+			// the file was rotated out to a backup the list is missing
+			l.mu.Unlock()
+			_ = h.Close()
+			continue
+		}
+		err = l.flush()
+		active, errOpen := os.Open(l.absPath)
+		l.mu.Unlock()
+
+		if err == nil && errOpen != nil && !os.IsNotExist(errOpen) {
+			err = errOpen
+		}
+		if errOpen == nil {
+			h.readers = append(h.readers, active)
+			h.closers = append(h.closers, active)
+		}
+		if err != nil {
+			_ = h.Close()
+			return nil, err
+		}
+		return readCloser{io.MultiReader(h.readers...), h.closers}, nil
+	}
+}
+
+// historyFiles are the files a HistoryReader reads, in order.
+type historyFiles struct {
+	readers []io.Reader
+	closers []io.Closer
+}
+
+func (h historyFiles) Close() error {
+	return readCloser{closers: h.closers}.Close()
+}
+
+// openBackupsSince opens the backups holding what was written since the
+// given time, oldest first, including with ArchiveDir those still in LogPath
+// waiting to be moved there.
+func (l *Logger) openBackupsSince(since time.Time) (historyFiles, error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	var h historyFiles
+	files, err := l.allLogFiles()
+	if err != nil {
+		return h, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		if f.timestamp.Before(since) {
+			continue
+		}
+		rc, err := l.OpenBackup(l.backupPath(f))
+		if err != nil {
+			_ = h.Close()
+			return historyFiles{}, err
+		}
+		h.readers = append(h.readers, rc)
+		h.closers = append(h.closers, rc)
+	}
+	return h, nil
+}
@@ -0,0 +1,83 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryReader(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestHistoryReader", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithCompress(),
+		WithCompressAfter(1), WithBufferSize(100), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	// three backups two days apart, the oldest two compressed
+	for _, s := range []string{"one\n", "two\n", "three\n"} {
+		_, err = l.Write([]byte(s))
+		isNil(err, t)
+		isNil(l.Rotate(""), t)
+		newFakeTime()
+	}
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals([]bool{false, true, true}, []bool{backups[0].Compressed, backups[1].Compressed, backups[2].Compressed}, t)
+	_, err = l.Write([]byte("four\n"))
+	isNil(err, t)
+
+	r, err := l.HistoryReader(time.Time{})
+	isNil(err, t)
+	b, err := ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("one\ntwo\nthree\nfour\n", string(b), t)
+
+	// the backup rotated out three days ago holds what was written since
+	r, err = l.HistoryReader(fakeTime().Add(-3 * 24 * time.Hour))
+	isNil(err, t)
+	b, err = ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("three\nfour\n", string(b), t)
+
+	r, err = l.HistoryReader(fakeTime())
+	isNil(err, t)
+	b, err = ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("four\n", string(b), t)
+}
+
+func TestHistoryReaderArchiveDir(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestHistoryReaderArchiveDir", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+	isNil(os.MkdirAll(archive, 0755), t)
+
+	// one backup archived, and a newer one the mill hasn't moved yet
+	isNil(ioutil.WriteFile(filepath.Join(archive, filepath.Base(backupFile(dir))), []byte("first\n"), 0644), t)
+	newFakeTime()
+	isNil(ioutil.WriteFile(backupFile(dir), []byte("second\n"), 0644), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithArchiveDir(archive))
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("third\n"))
+	isNil(err, t)
+
+	r, err := l.HistoryReader(time.Time{})
+	isNil(err, t)
+	b, err := ioutil.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("first\nsecond\nthird\n", string(b), t)
+}
@@ -0,0 +1,123 @@
+package rolling
+
+import (
+	"time"
+)
+
+// BackupInfo describes a rotated log file.
+type BackupInfo struct {
+	// Path is the backup's path, Timestamp the time encoded in its name, that
+	// is when it was rotated out.
+	Path      string    `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+	// Size is the size of the file on disk, compressed or not.
+	Size       int64 `json:"size"`
+	Compressed bool  `json:"compressed"`
+	// Label is what Rotate was given when making the backup, if anything.
+	Label string `json:"label,omitempty"`
+}
+
+// Inspector analyzes the log files of a directory written by a Logger, using
+// the same naming rules, without opening anything for writing or starting any
+// goroutines. It's meant for tooling looking at another process's logs.
+type Inspector struct {
+	l *Logger
+}
+
+// NewInspector returns an Inspector for the log file filename in dir and its
+// backups. Options that affect how backups are named, such as
+// WithCompressSuffix, should match those of the Logger writing to dir; the
+// others are ignored.
+func NewInspector(dir, filename string, options ...Option) *Inspector {
+	l := defaultLogWriter()
+	l.LogPath, l.Filename = dir, filename
+	for _, opt := range options {
+		opt(l)
+	}
+	return &Inspector{l: l}
+}
+
+// ListBackups returns the backups in the directory, newest first.
+func (in *Inspector) ListBackups() ([]BackupInfo, error) {
+	return in.l.Backups()
+}
+
+// InspectorStats summarizes a log directory.
+type InspectorStats struct {
+	DiskUsage
+	// Backups is the number of backups, Oldest and Newest their range of
+	// timestamps.
+	Backups int       `json:"backups"`
+	Oldest  time.Time `json:"oldest"`
+	Newest  time.Time `json:"newest"`
+}
+
+// Stats summarizes the log file and backups in the directory.
+func (in *Inspector) Stats() (InspectorStats, error) {
+	u, err := in.l.DiskUsage()
+	if err != nil {
+		return InspectorStats{}, err
+	}
+	backups, err := in.ListBackups()
+	if err != nil {
+		return InspectorStats{}, err
+	}
+	s := InspectorStats{DiskUsage: u, Backups: len(backups)}
+	if len(backups) > 0 {
+		s.Newest = backups[0].Timestamp
+		s.Oldest = backups[len(backups)-1].Timestamp
+	}
+	return s, nil
+}
+
+// Query returns the backups that may hold records written between from and
+// to, newest first. A backup is assumed to hold what was written after the
+// previous backup was rotated out, up to its own timestamp. A zero from or to
+// leaves that end of the range open.
+func (in *Inspector) Query(from, to time.Time) ([]BackupInfo, error) {
+	backups, err := in.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	var matched []BackupInfo
+	for i, b := range backups {
+		if !from.IsZero() && b.Timestamp.Before(from) {
+			break
+		}
+		if !to.IsZero() && i+1 < len(backups) && backups[i+1].Timestamp.After(to) {
+			continue
+		}
+		matched = append(matched, b)
+	}
+	return matched, nil
+}
+
+// Backups returns the Logger's backups, as recognized by retention, newest
+// first, including with ArchiveDir those still in LogPath waiting to be moved
+// there. It waits for a mill pass under way, so that a backup being
+// compressed or encrypted is listed once, as it ends up.
+func (l *Logger) Backups() ([]BackupInfo, error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	files, err := l.allLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]BackupInfo, 0, len(files))
+	for _, f := range files {
+		backups = append(backups, l.backupInfo(f))
+	}
+	return backups, nil
+}
+
+// backupInfo converts a logInfo found by oldLogFiles.
+func (l *Logger) backupInfo(f logInfo) BackupInfo {
+	return BackupInfo{
+		Path:       l.backupPath(f),
+		Timestamp:  f.timestamp,
+		Size:       f.Size(),
+		Compressed: f.compressed,
+		Label:      f.label,
+	}
+}
@@ -0,0 +1,116 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInspector(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestInspector", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var stamps []time.Time
+	for i := 0; i < 3; i++ {
+		newFakeTime()
+		name := backupFile(dir)
+		if i == 1 {
+			name += compressSuffix
+		}
+		isNil(ioutil.WriteFile(name, []byte("data"), 0644), t)
+		stamps = append(stamps, fakeTime().UTC().Truncate(time.Millisecond))
+	}
+	isNil(ioutil.WriteFile(logFile(dir), []byte("active"), 0644), t)
+
+	in := NewInspector(dir, logName())
+	backups, err := in.ListBackups()
+	isNil(err, t)
+	equals(3, len(backups), t)
+	equals(true, backups[0].Timestamp.Equal(stamps[2]), t)
+	equals(true, backups[1].Compressed, t)
+	equals(int64(4), backups[2].Size, t)
+	fileCount(dir, 4, t)
+
+	s, err := in.Stats()
+	isNil(err, t)
+	equals(3, s.Backups, t)
+	equals(int64(6), s.Active, t)
+	equals(true, s.Oldest.Equal(stamps[0]), t)
+
+	// the middle backup holds what was written between the first and second
+	// rotation
+	q, err := in.Query(stamps[0].Add(time.Hour), stamps[1].Add(-time.Hour))
+	isNil(err, t)
+	equals(1, len(q), t)
+	equals(backups[1].Path, q[0].Path, t)
+
+	q, err = in.Query(time.Time{}, time.Time{})
+	isNil(err, t)
+	equals(3, len(q), t)
+}
+
+func TestBackups(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestBackups", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithCompress(), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(""), t)
+	first := fakeTime().UTC().Truncate(time.Millisecond)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	isNil(l.Rotate("pre-deploy"), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals("pre-deploy", backups[0].Label, t)
+	equals(true, backups[1].Timestamp.Equal(first), t)
+	equals(backupFile(dir)[:len(backupFile(dir))-len(".log")]+"-pre-deploy.log"+compressSuffix, backups[0].Path, t)
+	for _, b := range backups {
+		equals(true, b.Compressed, t)
+		exists(b.Path, t)
+	}
+}
+
+func TestBackupsPendingArchival(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestBackupsPendingArchival", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+	isNil(os.MkdirAll(archive, 0755), t)
+
+	// one backup archived, and a newer one the mill hasn't moved yet
+	archived := filepath.Join(archive, filepath.Base(backupFile(dir)))
+	isNil(ioutil.WriteFile(archived, []byte("boo!"), 0644), t)
+	newFakeTime()
+	pending := backupFile(dir)
+	isNil(ioutil.WriteFile(pending, []byte("foo!"), 0644), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithArchiveDir(archive))
+	isNil(err, t)
+	defer l.Close()
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(pending, backups[0].Path, t)
+	equals(archived, backups[1].Path, t)
+}
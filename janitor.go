@@ -0,0 +1,235 @@
+package rolling
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// The background work of every Logger in the process is done by one shared
+// scheduler and one shared mill worker, so the number of goroutines and
+// timers stays fixed no matter how many Loggers are created.
+var (
+	defaultScheduler = &scheduler{wake: make(chan struct{}, 1)}
+	defaultMill      = &millWorker{queued: make(map[*Logger]bool)}
+)
+
+// Scheduler runs functions on a schedule. It is used for time rolling, where
+// spec is the Logger's TimePattern. Schedule must not call fn synchronously,
+// and fn must not be called again once stop has been called.
+type Scheduler interface {
+	Schedule(spec string, fn func()) (stop func(), err error)
+}
+
+var _ Scheduler = (*scheduler)(nil)
+
+// scheduler is the default Scheduler. It understands the cron expressions
+// parseCron does, and runs all schedules from a single goroutine which only
+// exists while any are registered.
+type scheduler struct {
+	mu      sync.Mutex
+	entries entryHeap
+	running bool
+	wake    chan struct{}
+}
+
+type entry struct {
+	schedule timeSchedule
+	next     time.Time
+	fn       func(at time.Time)
+	index    int
+}
+
+// Schedule runs fn on the cron schedule spec until the returned stop function
+// is called. fn runs on the scheduler's goroutine and must not block.
+func (s *scheduler) Schedule(spec string, fn func()) (stop func(), err error) {
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	return s.add(schedule, func(time.Time) { fn() }), nil
+}
+
+// add runs fn on schedule until the returned stop function is called,
+// passing it the activation time it runs for.
+func (s *scheduler) add(schedule timeSchedule, fn func(at time.Time)) (stop func()) {
+	e := &entry{schedule: schedule, next: schedule.Next(time.Now()), fn: fn}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	heap.Push(&s.entries, e)
+	if !s.running {
+		s.running = true
+		go s.run()
+	} else {
+		s.notify()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if e.index >= 0 {
+				heap.Remove(&s.entries, e.index)
+				s.notify()
+			}
+		})
+	}
+}
+
+// notify wakes up the scheduler goroutine so it picks up a change to the
+// entries. s.mu must be held.
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduler) run() {
+	for {
+		s.mu.Lock()
+		if len(s.entries) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		next := s.entries[0].next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+			continue
+		}
+
+		now := time.Now()
+		var due []func()
+		s.mu.Lock()
+		for len(s.entries) > 0 && !s.entries[0].next.After(now) {
+			e := s.entries[0]
+			fn, at := e.fn, e.next
+			due = append(due, func() { fn(at) })
+			if e.next = e.schedule.Next(now); e.next.IsZero() {
+				heap.Pop(&s.entries)
+			} else {
+				heap.Fix(&s.entries, 0)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, fn := range due {
+			fn()
+		}
+	}
+}
+
+// entryHeap orders entries by their next activation time.
+type entryHeap []*entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*h = old[:len(old)-1]
+	return e
+}
+
+// millWorker runs the post-rotation mill passes of all Loggers on a single
+// goroutine, which only exists while there is work queued. A Logger is queued
+// at most once no matter how often it rotates before its pass runs.
+type millWorker struct {
+	mu      sync.Mutex
+	pending []*Logger
+	queued  map[*Logger]bool
+	running bool
+	// current is the Logger whose pass is under way, if any, and idle is
+	// signaled when it is over.
+	current *Logger
+	idle    *sync.Cond
+}
+
+// enqueue schedules a mill pass for l.
+func (w *millWorker) enqueue(l *Logger) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.queued[l] {
+		return
+	}
+	w.queued[l] = true
+	w.pending = append(w.pending, l)
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+}
+
+func (w *millWorker) run() {
+	for {
+		w.mu.Lock()
+		if len(w.pending) == 0 {
+			w.running = false
+			w.mu.Unlock()
+			return
+		}
+		l := w.pending[0]
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+		delete(w.queued, l)
+		w.current = l
+		w.mu.Unlock()
+
+		l.reportError(l.millRunOnce())
+
+		w.mu.Lock()
+		w.current = nil
+		if w.idle != nil {
+			w.idle.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// cancel takes l out of the queue, and waits for its pass to be over if one
+// is under way, so that no work is left running for l once it's closed. It
+// reports whether a pass was queued, which the caller may then run itself.
+func (w *millWorker) cancel(l *Logger) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.current == l {
+		if w.idle == nil {
+			w.idle = sync.NewCond(&w.mu)
+		}
+		w.idle.Wait()
+	}
+	if !w.queued[l] {
+		return false
+	}
+	delete(w.queued, l)
+	for i, p := range w.pending {
+		if p == l {
+			w.pending = append(w.pending[:i], w.pending[i+1:]...)
+			break
+		}
+	}
+	return true
+}
@@ -0,0 +1,418 @@
+package rolling
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSharedGoroutine(t *testing.T) {
+	s := &scheduler{wake: make(chan struct{}, 1)}
+
+	fired := make(chan int, 10)
+	stop1, err := s.Schedule("* * * * * ?", func() { fired <- 1 })
+	isNil(err, t)
+	stop2, err := s.Schedule("@every 1h", func() { fired <- 2 })
+	isNil(err, t)
+	_, err = s.Schedule("not a cron spec", func() {})
+	notNil(err, t)
+
+	select {
+	case n := <-fired:
+		equals(1, n, t)
+	case <-time.After(3 * time.Second):
+		t.Fatal("schedule did not fire")
+	}
+
+	stop1()
+	stop1()
+	stop2()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		running, n := s.running, len(s.entries)
+		s.mu.Unlock()
+		if !running {
+			equals(0, n, t)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scheduler goroutine did not exit")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTimeRolling(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestTimeRolling", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	sched := &manualScheduler{}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithTimeRolling(),
+		WithTimePattern("@hourly"), WithScheduler(sched))
+	isNil(err, t)
+	equals("@hourly", sched.spec, t)
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+
+	newFakeTime()
+	sched.fire(l, t)
+
+	b2 := []byte("foo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	existsWithContent(logFile(dir), b2, t)
+	existsWithContent(backupFile(dir), b, t)
+
+	isNil(l.Close(), t)
+	equals(true, sched.stopped, t)
+}
+
+func TestInvalidTimePattern(t *testing.T) {
+	dir := makeTempDir("TestInvalidTimePattern", t)
+	defer os.RemoveAll(dir)
+
+	_, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithTimeRolling(), WithTimePattern("every day"))
+	notNil(err, t)
+	// nothing was opened
+	fileCount(dir, 0, t)
+
+	// the default pattern is used if none is given
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithTimeRolling())
+	isNil(err, t)
+	equals(rollingTimePattern, l.TimePattern, t)
+	isNil(l.Close(), t)
+}
+
+func TestRollingInterval(t *testing.T) {
+	currentTime = fakeTime
+	defer func(now time.Time) { fakeCurrentTime = now }(fakeCurrentTime)
+	dir := makeTempDir("TestRollingInterval", t)
+	defer os.RemoveAll(dir)
+
+	// a Thursday afternoon
+	fakeCurrentTime = time.Date(2021, 3, 4, 15, 30, 0, 0, time.Local)
+	for interval, next := range map[Interval]time.Time{
+		Hourly: time.Date(2021, 3, 4, 16, 0, 0, 0, time.Local),
+		Daily:  time.Date(2021, 3, 5, 0, 0, 0, 0, time.Local),
+		Weekly: time.Date(2021, 3, 7, 0, 0, 0, 0, time.Local),
+	} {
+		l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithRollingInterval(interval),
+			WithSynchronousMill())
+		isNil(err, t)
+		equals(TimeRolling, l.RollingPolicy, t)
+		equals(next, l.Stats().NextRotation, t)
+		isNil(l.Close(), t)
+	}
+}
+
+func TestRollingIntervalBoundary(t *testing.T) {
+	currentTime = fakeTime
+	defer func(now time.Time) { fakeCurrentTime = now }(fakeCurrentTime)
+	dir := makeTempDir("TestRollingIntervalBoundary", t)
+	defer os.RemoveAll(dir)
+
+	// the schedule fires at the top of the hour and rotates right away,
+	// without waiting for a write
+	fakeCurrentTime = time.Date(2021, 3, 4, 15, 30, 0, 0, time.Local)
+	sched := &manualScheduler{}
+	rotated := make(chan string, 1)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithRollingInterval(Hourly),
+		WithScheduler(sched), WithOnRotate(func(_, backup string) { rotated <- backup }))
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	fakeCurrentTime = time.Date(2021, 3, 4, 16, 0, 0, 0, time.Local)
+	sched.fn()
+	select {
+	case backup := <-rotated:
+		equals(backupFile(dir), backup, t)
+	case <-time.After(3 * time.Second):
+		t.Fatal("schedule did not rotate")
+	}
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+	existsWithContent(logFile(dir), []byte{}, t)
+	fileCount(dir, 2, t)
+}
+
+func TestRollingIntervalLateWrite(t *testing.T) {
+	currentTime = fakeTime
+	defer func(now time.Time) { fakeCurrentTime = now }(fakeCurrentTime)
+	dir := makeTempDir("TestRollingIntervalLateWrite", t)
+	defer os.RemoveAll(dir)
+
+	// in synchronous mode the rotation waits for the next write, but the
+	// backup is still named after the boundary rather than the write
+	fakeCurrentTime = time.Date(2021, 3, 4, 15, 30, 0, 0, time.Local)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithRollingInterval(Hourly),
+		WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	fakeCurrentTime = time.Date(2021, 3, 4, 16, 0, 0, 0, time.Local)
+	boundary := backupFile(dir)
+	fakeCurrentTime = time.Date(2021, 3, 4, 16, 20, 0, 0, time.Local)
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	existsWithContent(boundary, []byte("boo!"), t)
+	existsWithContent(logFile(dir), []byte("foo!"), t)
+	fileCount(dir, 2, t)
+}
+
+func TestDailyRotationAt(t *testing.T) {
+	dir := makeTempDir("TestDailyRotationAt", t)
+	defer os.RemoveAll(dir)
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithDailyRotationAt(0, 30, tokyo))
+	isNil(err, t)
+	defer l.Close()
+
+	// 15:00 UTC is 00:00 in Tokyo, so the next cut is half an hour away
+	schedule, err := l.cronSchedule()
+	isNil(err, t)
+	now := time.Date(2021, 3, 4, 15, 0, 0, 0, time.UTC)
+	equals(now.Add(30*time.Minute).Unix(), schedule.Next(now).Unix(), t)
+
+	_, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithTimeRolling(), WithTimeZone("Nowhere/Special"))
+	notNil(err, t)
+}
+
+func TestHybridRolling(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestHybridRolling", t)
+	defer os.RemoveAll(dir)
+
+	// time rolling alone ignores MaxSize
+	sched := &manualScheduler{}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithTimeRolling(),
+		WithScheduler(sched))
+	isNil(err, t)
+	_, err = l.Write([]byte("boo!boo!"))
+	isNil(err, t)
+	_, err = l.Write([]byte("boo!boo!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("boo!boo!boo!boo!"), t)
+	isNil(l.Close(), t)
+	isNil(os.Remove(logFile(dir)), t)
+
+	sched = &manualScheduler{}
+	l, err = NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithHybridRolling(),
+		WithScheduler(sched))
+	isNil(err, t)
+	defer l.Close()
+
+	// rotated by size
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+
+	// and by time
+	newFakeTime()
+	sched.fire(l, t)
+	_, err = l.Write([]byte("baz!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("foo!bar!"), t)
+	existsWithContent(logFile(dir), []byte("baz!"), t)
+	fileCount(dir, 3, t)
+}
+
+// manualScheduler is a Scheduler whose function is fired by the test.
+type manualScheduler struct {
+	spec    string
+	fn      func()
+	stopped bool
+}
+
+func (s *manualScheduler) Schedule(spec string, fn func()) (func(), error) {
+	s.spec, s.fn = spec, fn
+	return func() { s.stopped = true }, nil
+}
+
+// fire runs the scheduled function and waits for the rotation it makes l do
+// in the background.
+func (s *manualScheduler) fire(l *Logger, t testing.TB) {
+	rotations := l.Stats().Rotations
+	s.fn()
+	for deadline := time.Now().Add(3 * time.Second); l.Stats().Rotations == rotations; {
+		if time.Now().After(deadline) {
+			t.Fatal("schedule did not rotate")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSynchronousMill(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSynchronousMill", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithSynchronousMill(),
+		WithTimeRolling(), WithCompress())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	equals(true, l.stopSched == nil, t)
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+
+	// the daily schedule is due two days later, and the backup, named after
+	// the midnight it was due at, is compressed before Write returns
+	midnight := l.Stats().NextRotation
+	newFakeTime()
+	b2 := []byte("foo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	existsWithContent(logFile(dir), b2, t)
+	exists(filepath.Join(dir, "foobar-"+midnight.UTC().Format(backupTimeFormat)+".log"+compressSuffix), t)
+	fileCount(dir, 2, t)
+
+	b3 := []byte("bar!")
+	_, err = l.Write(b3)
+	isNil(err, t)
+	existsWithContent(logFile(dir), append(b2, b3...), t)
+	fileCount(dir, 2, t)
+}
+
+func TestMillRetry(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMillRetry", t)
+	defer os.RemoveAll(dir)
+
+	// the first attempt fails, as on a flaky network
+	var mu sync.Mutex
+	attempts := 0
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithArchiver(archiverFunc(func(ctx context.Context, localPath string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts++; attempts == 1 {
+				return errors.New("unreachable")
+			}
+			return nil
+		})))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	// the failed pass is retried after a backoff, without a rotation
+	notNil(<-l.Errors(), t)
+	select {
+	case err := <-l.Errors():
+		t.Fatalf("unexpected error %v", err)
+	case <-time.After(2 * defaultRotateBackoff):
+	}
+	equals(uint64(1), l.Stats().Uploads, t)
+	l.millMu.Lock()
+	equals(0, l.millFailures, t)
+	l.millMu.Unlock()
+}
+
+func TestMillPersistentFailure(t *testing.T) {
+	l := &Logger{Config: Config{SynchronousMill: true}}
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	fail := errors.New("busy")
+	equals(fail, l.millDone(fail), t)
+	err := l.millDone(fail)
+	equals("mill pass failed 2 times in a row: busy", err.Error(), t)
+	assert(errors.Is(err, fail), t, "the cause is lost")
+	isNil(l.millDone(nil), t)
+	equals(0, l.millFailures, t)
+}
+
+func TestParseCron(t *testing.T) {
+	// a Thursday afternoon
+	now := time.Date(2021, 3, 4, 15, 30, 7, 500, time.Local)
+	for spec, next := range map[string]time.Time{
+		rollingTimePattern:     time.Date(2021, 3, 5, 0, 0, 0, 0, time.Local),
+		"0 30 9 * * mon-fri":   time.Date(2021, 3, 5, 9, 30, 0, 0, time.Local),
+		"0 30 9 * * SAT,sun":   time.Date(2021, 3, 6, 9, 30, 0, 0, time.Local),
+		"*/15 * * * * *":       time.Date(2021, 3, 4, 15, 30, 15, 0, time.Local),
+		"5/20 * * * * *":       time.Date(2021, 3, 4, 15, 30, 25, 0, time.Local),
+		"0 10-40/10 15 * * *":  time.Date(2021, 3, 4, 15, 40, 0, 0, time.Local),
+		"0 0 12 1 * *":         time.Date(2021, 4, 1, 12, 0, 0, 0, time.Local),
+		"0 0 0 1 jan":          time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local),
+		"0 0 0 29 2 ?":         time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local),
+		"0 0 0 13 * fri":       time.Date(2021, 3, 5, 0, 0, 0, 0, time.Local),
+		"@hourly":              time.Date(2021, 3, 4, 16, 0, 0, 0, time.Local),
+		"@weekly":              time.Date(2021, 3, 7, 0, 0, 0, 0, time.Local),
+		"@monthly":             time.Date(2021, 4, 1, 0, 0, 0, 0, time.Local),
+		"@yearly":              time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local),
+		"@every 90m":           time.Date(2021, 3, 4, 17, 0, 7, 0, time.Local),
+		"0 0 0 31 2 *":         {},
+		"0 0 0 30 feb-apr mon": time.Date(2021, 3, 8, 0, 0, 0, 0, time.Local),
+	} {
+		schedule, err := parseCron(spec)
+		isNil(err, t)
+		equals(next, schedule.Next(now), t)
+	}
+
+	for _, spec := range []string{
+		"", "every day", "* * * *", "* * * * * * *", "60 * * * * *", "* * 24 * * *",
+		"* * * 0 * *", "*/0 * * * * *", "5-1 * * * * *", "1-2-3 * * * * *",
+		"* * * * foo *", "@fortnightly", "@every x",
+	} {
+		_, err := parseCron(spec)
+		assert(err != nil, t, "%q parsed", spec)
+	}
+}
+
+func TestParseCronTimeZone(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	// the clocks go forward from 2:00 to 3:00 on the last Sunday of March
+	schedule, err := parseCron("0 30 2 * * *")
+	isNil(err, t)
+	equals(time.Date(2021, 3, 29, 2, 30, 0, 0, paris).Unix(),
+		schedule.Next(time.Date(2021, 3, 28, 1, 0, 0, 0, paris)).Unix(), t)
+
+	schedule, err = parseCron("0 0 * * * *")
+	isNil(err, t)
+	india := time.FixedZone("IST", 5*60*60+30*60)
+	equals(time.Date(2021, 3, 4, 16, 0, 0, 0, india).Unix(),
+		schedule.Next(time.Date(2021, 3, 4, 15, 30, 0, 0, india)).Unix(), t)
+}
@@ -0,0 +1,125 @@
+//go:build linux
+// +build linux
+
+package rolling
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+var _ io.Writer = (*JournalWriter)(nil)
+
+// journalSocket is where journald listens for native protocol entries. It is
+// a variable so tests can point it elsewhere.
+var journalSocket = "/run/systemd/journal/socket"
+
+// journalPriorities maps levels to syslog priorities, as journald uses.
+var journalPriorities = map[Level]int{
+	DebugLevel: 7,
+	InfoLevel:  6,
+	WarnLevel:  4,
+	ErrorLevel: 3,
+}
+
+// JournalWriter writes entries to systemd-journald with its native protocol,
+// one per Write, with a priority mapped from their level. It can be the tee
+// or the fallback of a Logger, with WithTee and WithFallback, so that a
+// service running under systemd keeps its journal alongside its files.
+// Entries must fit in a datagram, a few hundred kilobytes with the default
+// socket buffers.
+type JournalWriter struct {
+	identifier string
+	level      func(p []byte) Level
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournalWriter returns a JournalWriter tagging entries with identifier,
+// as SYSLOG_IDENTIFIER, at the priority of the level level returns for them,
+// or at InfoLevel if level is nil. The connection to journald is only made
+// on the first write.
+func NewJournalWriter(identifier string, level func(p []byte) Level) *JournalWriter {
+	return &JournalWriter{identifier: identifier, level: level}
+}
+
+// Write writes p as the MESSAGE of an entry, without its trailing newline.
+func (j *JournalWriter) Write(p []byte) (int, error) {
+	lv := InfoLevel
+	if j.level != nil {
+		lv = j.level(p)
+	}
+	return j.WriteLevel(lv, p)
+}
+
+// WriteLevel writes p as the MESSAGE of an entry at level lv.
+func (j *JournalWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	priority, ok := journalPriorities[lv]
+	if !ok {
+		priority = journalPriorities[ErrorLevel]
+		if lv < DebugLevel {
+			priority = journalPriorities[DebugLevel]
+		}
+	}
+
+	var b bytes.Buffer
+	appendJournalField(&b, "PRIORITY", []byte(strconv.Itoa(priority)))
+	if j.identifier != "" {
+		appendJournalField(&b, "SYSLOG_IDENTIFIER", []byte(j.identifier))
+	}
+	appendJournalField(&b, "MESSAGE", bytes.TrimSuffix(p, []byte("\n")))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+		if err != nil {
+			return 0, err
+		}
+		j.conn = conn
+	}
+	if _, err := j.conn.Write(b.Bytes()); err != nil {
+		// reconnect next time, journald may have been restarted
+		_ = j.conn.Close()
+		j.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to journald, if any.
+func (j *JournalWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+// appendJournalField appends a field to an entry in the native protocol:
+// NAME=value on a line of its own, or, for values spanning several lines,
+// the name on its own line followed by the value's 64-bit little-endian
+// length, the value and a newline.
+func appendJournalField(b *bytes.Buffer, name string, value []byte) {
+	b.WriteString(name)
+	if bytes.IndexByte(value, '\n') < 0 {
+		b.WriteByte('=')
+		b.Write(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b.Write(size[:])
+	b.Write(value)
+	b.WriteByte('\n')
+}
@@ -0,0 +1,47 @@
+package rolling
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalWriter(t *testing.T) {
+	dir := makeTempDir("TestJournalWriter", t)
+	defer os.RemoveAll(dir)
+
+	sock := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	isNil(err, t)
+	defer conn.Close()
+	defer func(old string) { journalSocket = old }(journalSocket)
+	journalSocket = sock
+
+	j := NewJournalWriter("app", func(p []byte) Level {
+		if bytes.HasPrefix(p, []byte("ERROR")) {
+			return ErrorLevel
+		}
+		return InfoLevel
+	})
+	defer j.Close()
+	buf := make([]byte, 1024)
+
+	n, err := j.Write([]byte("ERROR boom\n"))
+	isNil(err, t)
+	equals(11, n, t)
+	n, err = conn.Read(buf)
+	isNil(err, t)
+	equals("PRIORITY=3\nSYSLOG_IDENTIFIER=app\nMESSAGE=ERROR boom\n", string(buf[:n]), t)
+
+	// multiline messages take the binary form
+	_, err = j.WriteLevel(WarnLevel, []byte("a\nb"))
+	isNil(err, t)
+	n, err = conn.Read(buf)
+	isNil(err, t)
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, 3)
+	equals("PRIORITY=4\nSYSLOG_IDENTIFIER=app\nMESSAGE\n"+string(size)+"a\nb\n", string(buf[:n]), t)
+}
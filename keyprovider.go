@@ -0,0 +1,140 @@
+package rolling
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultDataKeyLifetime is how long DataKeyProvider uses a data key for when
+// no lifetime is given.
+const defaultDataKeyLifetime = 24 * time.Hour
+
+// DataKeyService generates and decrypts data keys under a master key held by
+// a key management service, which never leaves it. Both AWS KMS and the
+// HashiCorp Vault transit engine fit, with a thin adapter. For AWS KMS:
+//
+//	type kmsService struct {
+//		client *kms.Client
+//		keyID  string
+//	}
+//
+//	func (s kmsService) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+//		out, err := s.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+//			KeyId:   &s.keyID,
+//			KeySpec: types.DataKeySpecAes256,
+//		})
+//		if err != nil {
+//			return nil, nil, err
+//		}
+//		return out.Plaintext, out.CiphertextBlob, nil
+//	}
+//
+//	func (s kmsService) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+//		out, err := s.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: ciphertext})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Plaintext, nil
+//	}
+//
+// For Vault, GenerateDataKey writes to transit/datakey/plaintext/<key> and
+// returns the decoded "plaintext" and the "ciphertext" string as bytes, while
+// Decrypt writes that string back to transit/decrypt/<key> and decodes the
+// "plaintext" it gets.
+type DataKeyService interface {
+	// GenerateDataKey returns a new random 32 byte key, in plain and
+	// encrypted under the master key. The encrypted form must be at most 191
+	// bytes long to fit in a backup's header once encoded.
+	GenerateDataKey(ctx context.Context) (plaintext, ciphertext []byte, err error)
+	// Decrypt decrypts a data key returned by GenerateDataKey.
+	Decrypt(ctx context.Context, ciphertext []byte) (plaintext []byte, err error)
+}
+
+// DataKeyProvider is a KeyProvider doing envelope encryption: backups are
+// encrypted with data keys from a DataKeyService, and the key ID recorded in
+// each backup is the data key encrypted under the service's master key. The
+// process never needs a secret in its configuration, and reading a backup
+// only requires access to the service. A new data key is generated
+// periodically, so rotation needs no intervention.
+type DataKeyProvider struct {
+	service  DataKeyService
+	lifetime time.Duration
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	current   EncryptionKey
+	generated time.Time
+	cache     map[string][]byte
+}
+
+// NewDataKeyProvider returns a DataKeyProvider which generates a new data key
+// from service every lifetime, daily by default, and gives up on calls to the
+// service after timeout if it isn't zero.
+func NewDataKeyProvider(service DataKeyService, lifetime, timeout time.Duration) *DataKeyProvider {
+	if lifetime <= 0 {
+		lifetime = defaultDataKeyLifetime
+	}
+	return &DataKeyProvider{
+		service:  service,
+		lifetime: lifetime,
+		timeout:  timeout,
+		cache:    make(map[string][]byte),
+	}
+}
+
+func (p *DataKeyProvider) context() (context.Context, context.CancelFunc) {
+	if p.timeout > 0 {
+		return context.WithTimeout(context.Background(), p.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// CurrentKey returns the data key new backups are encrypted with, generating
+// a new one if the current one has expired.
+func (p *DataKeyProvider) CurrentKey() (EncryptionKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current.ID != "" && time.Since(p.generated) < p.lifetime {
+		return p.current, nil
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+	plaintext, ciphertext, err := p.service.GenerateDataKey(ctx)
+	if err != nil {
+		return EncryptionKey{}, fmt.Errorf("can't generate data key: %v", err)
+	}
+	key := EncryptionKey{ID: base64.RawStdEncoding.EncodeToString(ciphertext), Secret: plaintext}
+	if err := key.validate(); err != nil {
+		return EncryptionKey{}, err
+	}
+	p.current, p.generated = key, time.Now()
+	p.cache[key.ID] = plaintext
+	return key, nil
+}
+
+// GetKey returns the data key with the given ID, asking the service to
+// decrypt it unless it has been seen before.
+func (p *DataKeyProvider) GetKey(id string) (EncryptionKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if secret, ok := p.cache[id]; ok {
+		return EncryptionKey{ID: id, Secret: secret}, nil
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(id)
+	if err != nil {
+		return EncryptionKey{}, fmt.Errorf("invalid data key ID: %v", err)
+	}
+	ctx, cancel := p.context()
+	defer cancel()
+	plaintext, err := p.service.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return EncryptionKey{}, fmt.Errorf("can't decrypt data key: %v", err)
+	}
+	p.cache[id] = plaintext
+	return EncryptionKey{ID: id, Secret: plaintext}, nil
+}
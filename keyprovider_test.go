@@ -0,0 +1,88 @@
+package rolling
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeKMS wraps data keys by XORing them with a master key.
+type fakeKMS struct {
+	master    []byte
+	generated int
+	decrypted int
+}
+
+func (k *fakeKMS) xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ k.master[i%len(k.master)]
+	}
+	return out
+}
+
+func (k *fakeKMS) GenerateDataKey(context.Context) ([]byte, []byte, error) {
+	k.generated++
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	return key, k.xor(key), nil
+}
+
+func (k *fakeKMS) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) != 32 {
+		return nil, errors.New("bad ciphertext")
+	}
+	k.decrypted++
+	return k.xor(ciphertext), nil
+}
+
+func TestDataKeyProvider(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDataKeyProvider", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	kms := &fakeKMS{master: []byte("master key")}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithSynchronousMill(), WithKeyProvider(NewDataKeyProvider(kms, time.Hour, time.Second)))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	b := []byte("boo!")
+	_, err = l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+	equals(1, kms.generated, t)
+
+	// a fresh provider, as in another process, only needs the service
+	backup := backupFile(dir) + encryptSuffix
+	l.keys = NewDataKeyProvider(kms, time.Hour, 0)
+	for i := 0; i < 2; i++ {
+		r, err := l.OpenBackup(backup)
+		isNil(err, t)
+		got, err := ioutil.ReadAll(r)
+		isNil(err, t)
+		isNil(r.Close(), t)
+		equals(true, bytes.Equal(b, got), t)
+	}
+	equals(1, kms.decrypted, t)
+}
@@ -0,0 +1,52 @@
+package rolling
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+// validLabel matches the labels Rotate accepts, which must keep backup
+// names plain file names.
+var validLabel = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Rotate closes the log file and starts a new one right away, whatever the
+// RollingPolicy. The backup is named with label after its timestamp, as in
+// "app-2006-01-02T15-04-05.000-pre-deploy.log", so that backups made around
+// an operational event are easy to find; it is retained and processed like
+// any other. An empty label names the backup as usual. Labels are made of
+// letters, digits, '.', '_' and '-', and can't be combined with
+// SequentialNames or BackupNamePattern.
+func (l *Logger) Rotate(label string) error {
+	if label != "" {
+		if !validLabel.MatchString(label) {
+			return fmt.Errorf("invalid backup label %q", label)
+		}
+		if l.SequentialNames || l.BackupNamePattern != "" {
+			return errors.New("backup labels can't be combined with SequentialNames or BackupNamePattern")
+		}
+	}
+	l.lockWrite()
+	defer l.unlockWrite()
+	if l.file == nil {
+		return errClosed
+	}
+	l.rotateLabel = label
+	defer func() { l.rotateLabel = "" }()
+	if err := l.rotate(); err != nil {
+		return err
+	}
+	l.pendingRotate = false
+	return nil
+}
+
+// withLabel returns the backup name with label inserted before the
+// extension, or name itself if label is empty.
+func (l *Logger) withLabel(name, label string) string {
+	if label == "" {
+		return name
+	}
+	ext := filepath.Ext(l.Filename)
+	return name[:len(name)-len(ext)] + "-" + label + ext
+}
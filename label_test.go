@@ -0,0 +1,56 @@
+package rolling
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateLabel(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRotateLabel", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100),
+		WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate("pre-deploy"), t)
+	labeled := strings.TrimSuffix(backupFile(dir), ".log") + "-pre-deploy.log"
+	existsWithContent(labeled, []byte("boo!"), t)
+	existsWithContent(logFile(dir), []byte{}, t)
+
+	backups, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	equals("pre-deploy", backups[0].Label, t)
+	equals(fakeTime().UTC().Truncate(time.Millisecond), backups[0].Timestamp, t)
+
+	// an empty label rotates as usual
+	newFakeTime()
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	isNil(l.Rotate(""), t)
+	existsWithContent(backupFile(dir), []byte("foo!"), t)
+
+	notNil(l.Rotate("../up"), t)
+	notNil(l.Rotate("pre deploy"), t)
+}
+
+func TestRotateLabelSequential(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRotateLabelSequential", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithSequentialNames())
+	isNil(err, t)
+	defer l.Close()
+	notNil(l.Rotate("pre-deploy"), t)
+	isNil(l.Rotate(""), t)
+}
@@ -0,0 +1,147 @@
+package rolling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var _ io.WriteCloser = (*LevelWriter)(nil)
+
+// Level is the severity LevelWriter routes writes by.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+func (lv Level) String() string {
+	switch lv {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(lv))
+}
+
+// ParseLevel returns the Level named name, which may also be one of the
+// levels of logrus or zerolog beyond those of Level: trace is taken as
+// debug, and fatal and panic as error.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "trace", "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error", "fatal", "panic":
+		return ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("unknown level %q", name)
+}
+
+// LevelWriter writes to one Logger per level, such as info.log, warn.log and
+// error.log, each rolled and retained on its own while sharing the mill
+// worker. A write goes whole to the file of the highest level configured at
+// or below its own, so with files for info, warn and error, debug writes are
+// dropped.
+type LevelWriter struct {
+	levels  []Level // configured, lowest first
+	loggers map[Level]*Logger
+	detect  func(p []byte) Level
+}
+
+// NewLevelWriter creates a Logger for every level in levels, configured by
+// options as NewWriter would, then by the options given for the level, with
+// the level's name as Filename, keeping the extension of the Filename in
+// options: info.log, warn.log and so on. The options for a level can set
+// their own Filename, retention or anything else. detect tells the level of
+// what is passed to Write; if nil, Write uses InfoLevel.
+func NewLevelWriter(detect func(p []byte) Level, levels map[Level][]Option, options ...Option) (*LevelWriter, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("at least one level is needed")
+	}
+	base := defaultLogWriter()
+	base.apply(options)
+	ext := filepath.Ext(base.Filename)
+
+	w := &LevelWriter{loggers: make(map[Level]*Logger, len(levels)), detect: detect}
+	for lv := range levels {
+		w.levels = append(w.levels, lv)
+	}
+	sort.Slice(w.levels, func(i, j int) bool { return w.levels[i] < w.levels[j] })
+	for _, lv := range w.levels {
+		opts := append(options[:len(options):len(options)], WithFilename(lv.String()+ext), withExpvarSuffix(lv.String()))
+		opts = append(opts, levels[lv]...)
+		l, err := NewWriter(opts...)
+		if err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		w.loggers[lv] = l
+	}
+	return w, nil
+}
+
+// Write writes p at the level detect says it is at.
+func (w *LevelWriter) Write(p []byte) (int, error) {
+	lv := InfoLevel
+	if w.detect != nil {
+		lv = w.detect(p)
+	}
+	return w.WriteLevel(lv, p)
+}
+
+// WriteLevel writes p at level lv.
+func (w *LevelWriter) WriteLevel(lv Level, p []byte) (int, error) {
+	l := w.Logger(lv)
+	if l == nil {
+		return len(p), nil
+	}
+	return l.Write(p)
+}
+
+// Logger returns the Logger writes at level lv go to, or nil if they are
+// dropped.
+func (w *LevelWriter) Logger(lv Level) *Logger {
+	for i := len(w.levels) - 1; i >= 0; i-- {
+		if w.levels[i] <= lv {
+			return w.loggers[w.levels[i]]
+		}
+	}
+	return nil
+}
+
+// Sync syncs the Loggers of all levels, see Logger.Sync.
+func (w *LevelWriter) Sync() error {
+	var err error
+	for _, l := range w.loggers {
+		if errSync := l.Sync(); err == nil {
+			err = errSync
+		}
+	}
+	return err
+}
+
+// Close closes the Loggers of all levels.
+func (w *LevelWriter) Close() error {
+	var err error
+	for _, l := range w.loggers {
+		if errClose := l.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
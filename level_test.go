@@ -0,0 +1,63 @@
+package rolling
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLevelWriter(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestLevelWriter", t)
+	defer os.RemoveAll(dir)
+
+	detect := func(p []byte) Level {
+		if bytes.HasPrefix(p, []byte("ERROR")) {
+			return ErrorLevel
+		}
+		return InfoLevel
+	}
+	w, err := NewLevelWriter(detect, map[Level][]Option{
+		InfoLevel:  nil,
+		WarnLevel:  nil,
+		ErrorLevel: {WithMaxRemain(100)},
+	}, WithLogPath(dir), WithFilename("app.log"), WithMaxSize(10000))
+	isNil(err, t)
+	defer w.Close()
+
+	_, err = w.Write([]byte("ERROR boom\n"))
+	isNil(err, t)
+	_, err = w.Write([]byte("INFO hello\n"))
+	isNil(err, t)
+	_, err = w.WriteLevel(WarnLevel, []byte("careful\n"))
+	isNil(err, t)
+	_, err = w.WriteLevel(DebugLevel, []byte("dropped\n"))
+	isNil(err, t)
+	_, err = w.WriteLevel(Level(10), []byte("fatal\n"))
+	isNil(err, t)
+
+	existsWithContent(filepath.Join(dir, "error.log"), []byte("ERROR boom\nfatal\n"), t)
+	existsWithContent(filepath.Join(dir, "info.log"), []byte("INFO hello\n"), t)
+	existsWithContent(filepath.Join(dir, "warn.log"), []byte("careful\n"), t)
+	fileCount(dir, 3, t)
+	equals(100, w.Logger(ErrorLevel).MaxRemain, t)
+	equals(30, w.Logger(InfoLevel).MaxRemain, t)
+	equals((*Logger)(nil), w.Logger(DebugLevel), t)
+
+	_, err = NewLevelWriter(nil, nil)
+	notNil(err, t)
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{
+		"trace": DebugLevel, "DEBUG": DebugLevel, "info": InfoLevel, "warning": WarnLevel,
+		"warn": WarnLevel, "error": ErrorLevel, "fatal": ErrorLevel, "panic": ErrorLevel,
+	} {
+		lv, err := ParseLevel(name)
+		isNil(err, t)
+		equals(want, lv, t)
+	}
+	_, err := ParseLevel("loud")
+	notNil(err, t)
+}
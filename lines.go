@@ -0,0 +1,85 @@
+package rolling
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// countLines returns the number of newlines in the file at path, zero if it
+// doesn't exist.
+func countLines(path string) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var lines int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+// linesFull reports whether the file holds MaxLines lines, and is due for
+// rotation. l.mu must be held.
+func (l *Logger) linesFull() bool {
+	return l.MaxLines > 0 && l.lines >= int64(l.MaxLines)
+}
+
+// linesCut returns where p is to be cut for the lines before to fill the
+// file up to MaxLines and the rest to go to the next one, or -1 if p fits.
+// l.mu must be held.
+func (l *Logger) linesCut(p []byte) int {
+	if l.MaxLines <= 0 {
+		return -1
+	}
+	left := int64(l.MaxLines) - l.lines
+	if left <= 0 {
+		// the file is rotated before p is written
+		left = int64(l.MaxLines)
+	}
+	for i, c := range p {
+		if c != '\n' {
+			continue
+		}
+		if left--; left == 0 {
+			if i+1 == len(p) {
+				return -1
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// writeLines writes p, which holds more lines than the file has room for,
+// in pieces of at most MaxLines lines, rotating in between. l.mu must be
+// held.
+func (l *Logger) writeLines(p []byte, tr *WriteTrace) (n int, seq uint64, err error) {
+	for n < len(p) {
+		chunk := p[n:]
+		if i := l.linesCut(chunk); i >= 0 {
+			chunk = chunk[:i]
+		}
+		m, s, err := l.writeLocked(chunk, tr)
+		n += m
+		if s > 0 {
+			seq = s
+		}
+		if err != nil {
+			return n, seq, err
+		}
+	}
+	return n, seq, nil
+}
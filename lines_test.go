@@ -0,0 +1,46 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestMaxLines(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMaxLines", t)
+	defer os.RemoveAll(dir)
+
+	// a line already in the file counts
+	isNil(ioutil.WriteFile(logFile(dir), []byte("a\n"), 0644), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1000),
+		WithMaxLines(3))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("b\n"))
+	isNil(err, t)
+	n, err := l.Write([]byte("c\nd\ne\nf\ng"))
+	isNil(err, t)
+	equals(9, n, t)
+	existsWithContent(logFile(dir), []byte("g"), t)
+
+	_, err = l.Write([]byte("\nh\n"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("g\nh\n"), t)
+
+	files, err := ioutil.ReadDir(dir)
+	isNil(err, t)
+	var contents []string
+	for _, f := range files {
+		c, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		isNil(err, t)
+		contents = append(contents, string(c))
+	}
+	sort.Strings(contents)
+	equals([]string{"a\nb\nc\n", "d\ne\nf\n", "g\nh\n"}, contents, t)
+}
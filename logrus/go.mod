@@ -0,0 +1,15 @@
+module github.com/cnof/rolling/logrus
+
+go 1.17
+
+require (
+	github.com/cnof/rolling v0.0.0-20261016004947-c07b60171102
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require (
+	github.com/klauspost/compress v1.15.15 // indirect
+	github.com/pierrec/lz4/v4 v4.1.18 // indirect
+	github.com/robfig/cron v1.2.0 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
@@ -0,0 +1,60 @@
+// Package logrus provides a logrus.Hook writing entries to a rolling.Logger,
+// or to a rolling.LevelWriter to have a file per level. It is a module of its
+// own, so that using rolling doesn't bring in logrus.
+package logrus
+
+import (
+	"io"
+
+	"github.com/cnof/rolling"
+	"github.com/sirupsen/logrus"
+)
+
+var _ logrus.Hook = (*Hook)(nil)
+
+// Hook is a logrus.Hook writing every entry, formatted by the logger's
+// Formatter, and syncing on panic and fatal entries so they survive the
+// process going down right after. The logger's own output is left alone, so
+// it is usually installed with:
+//
+//	logger.SetOutput(io.Discard)
+//	logger.AddHook(NewHook(w))
+type Hook struct {
+	w io.Writer
+}
+
+// NewHook returns a Hook writing to w, normally a *rolling.Logger or a
+// *rolling.LevelWriter. Any other writer with a Sync method is synced on
+// panic and fatal entries too.
+func NewHook(w io.Writer) *Hook {
+	return &Hook{w: w}
+}
+
+// Levels returns all the logrus levels.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes the formatted entry e at its level.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	lv, err := rolling.ParseLevel(e.Level.String())
+	if err != nil {
+		return err
+	}
+	line, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	if lw, ok := h.w.(*rolling.LevelWriter); ok {
+		_, err = lw.WriteLevel(lv, line)
+	} else {
+		_, err = h.w.Write(line)
+	}
+	if err != nil || e.Level > logrus.FatalLevel {
+		return err
+	}
+	if s, ok := h.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
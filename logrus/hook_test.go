@@ -0,0 +1,64 @@
+package logrus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnof/rolling"
+	"github.com/sirupsen/logrus"
+)
+
+func TestHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestHook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := rolling.NewLevelWriter(nil, map[rolling.Level][]rolling.Option{
+		rolling.InfoLevel:  nil,
+		rolling.ErrorLevel: nil,
+	}, rolling.WithLogPath(dir), rolling.WithFilename("app.log"), rolling.WithBufferSize(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	logger.AddHook(NewHook(w))
+
+	logger.Info("hello")
+	logger.Warn("careful")
+	logger.Trace("dropped")
+	content := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if got := content("info.log"); got != "" {
+		t.Fatalf("buffered entries written early: %q", got)
+	}
+
+	// buffered until a panic entry has everything synced
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("no panic")
+			}
+		}()
+		logger.Panic("bye")
+	}()
+	if got, want := content("info.log"), "level=info msg=hello\nlevel=warning msg=careful\n"; got != want {
+		t.Fatalf("info.log: got %q, want %q", got, want)
+	}
+	if got, want := content("error.log"), "level=panic msg=bye\n"; got != want {
+		t.Fatalf("error.log: got %q, want %q", got, want)
+	}
+}
@@ -1,5 +1,13 @@
 package rolling
 
+import (
+	"context"
+	"io"
+	"os"
+	"text/template"
+	"time"
+)
+
 // Option defined config option
 type Option func(*Logger)
 
@@ -21,38 +29,641 @@ func WithMaxAge(maxAge int) Option {
 	}
 }
 
+// WithMaxAgeDuration removes backups older than d, see
+// Config.MaxAgeDuration.
+func WithMaxAgeDuration(d time.Duration) Option {
+	return func(logger *Logger) {
+		logger.MaxAgeDuration = d
+	}
+}
+
 func WithMaxRemain(maxRemain int) Option {
 	return func(logger *Logger) {
 		logger.MaxRemain = maxRemain
 	}
 }
 
+// WithMaxTotalSize caps the space taken by the log file and its backups
+// together, see Config.MaxTotalSize.
+func WithMaxTotalSize(bytes int64) Option {
+	return func(logger *Logger) {
+		logger.MaxTotalSize = bytes
+	}
+}
+
 func WithMaxSize(maxSize int) Option {
 	return func(logger *Logger) {
 		logger.MaxSize = maxSize
 	}
 }
 
+// WithFileMode creates log files with mode, see Config.FileMode.
+func WithFileMode(mode os.FileMode) Option {
+	return func(logger *Logger) {
+		logger.FileMode = mode
+	}
+}
+
+// WithDirMode creates missing directories with mode, see Config.DirMode.
+func WithDirMode(mode os.FileMode) Option {
+	return func(logger *Logger) {
+		logger.DirMode = mode
+	}
+}
+
+// WithFileFlags opens the log file with flag, as given to os.OpenFile,
+// instead of DefaultFileFlag, for instance to add os.O_SYNC or drop
+// os.O_APPEND for this Logger alone. The flags must allow writing, and
+// os.O_APPEND is still added with AppendOnly.
+func WithFileFlags(flag int) Option {
+	return func(logger *Logger) {
+		logger.fileFlags = flag
+	}
+}
+
+// WithWriteErrorPolicy sets what writes do when the disk is full, see
+// WriteErrorPolicy. Errors are returned by default. A fallback set with
+// WithFallback gets the writes first, and the policy only applies to those
+// it couldn't take.
+func WithWriteErrorPolicy(policy WriteErrorPolicy) Option {
+	return func(logger *Logger) {
+		logger.writeErrorPolicy = policy
+	}
+}
+
+// WithOwner gives the log directory, the log file and the backups to the
+// user uid and group gid, as with os.Chown, where -1 leaves either
+// unchanged. This lets a service running as root keep its logs readable by a
+// log shipper running as a dedicated user. The process must be allowed to
+// change owners, and it isn't supported on Windows.
+func WithOwner(uid, gid int) Option {
+	return func(logger *Logger) {
+		logger.owner = &fileOwner{uid: uid, gid: gid}
+	}
+}
+
+// WithArchiveDir keeps backups in dir rather than next to the log file, see
+// Config.ArchiveDir.
+func WithArchiveDir(dir string) Option {
+	return func(logger *Logger) {
+		logger.ArchiveDir = dir
+	}
+}
+
+// WithArchiver ships every backup with a, see Archiver. It replaces any
+// archiver set up by WithS3Archive and the like.
+func WithArchiver(a Archiver) Option {
+	return func(logger *Logger) {
+		logger.archiver = a
+	}
+}
+
+// WithOnArchive has fn called with the outcome of every attempt to archive a
+// backup, nil on success. fn is called from the mill and must not block.
+func WithOnArchive(fn func(localPath string, err error)) Option {
+	return func(logger *Logger) {
+		logger.onArchive = fn
+	}
+}
+
+// WithS3Archive uploads every backup, once compressed and encrypted as
+// configured, to bucket under prefix using client. Failed uploads are retried
+// with a backoff, and uploads are recorded in the manifest so they survive
+// restarts. See also WithKeepUploaded.
+func WithS3Archive(client S3Client, bucket, prefix string) Option {
+	return func(logger *Logger) {
+		logger.archiver = &s3Archiver{client: client, bucket: bucket, prefix: prefix}
+	}
+}
+
+// WithGCSArchive uploads every backup to the Google Cloud Storage bucket under
+// prefix using client, as resumable uploads for large backups, like
+// WithS3Archive does to S3.
+func WithGCSArchive(client GCSClient, bucket, prefix string, options ...GCSOption) Option {
+	return func(logger *Logger) {
+		a := &gcsArchiver{client: client, bucket: bucket, prefix: prefix, chunkSize: defaultGCSChunkSize}
+		for _, option := range options {
+			option(a)
+		}
+		logger.archiver = a
+	}
+}
+
+// WithAzureArchive uploads every backup to the Azure Blob Storage container
+// under prefix using client, like WithS3Archive does to S3.
+func WithAzureArchive(client AzureBlobClient, container, prefix string) Option {
+	return func(logger *Logger) {
+		logger.archiver = &azureArchiver{client: client, container: container, prefix: prefix}
+	}
+}
+
+// WithSFTPArchive uploads every backup over SFTP, for hosts without object
+// storage, using a session opened by dial for each backup, see SFTPClient.
+// Backups go into dir, a text/template which may use the backup's time as
+// {{.Year}}, {{.Month}} and {{.Day}}, or as {{.Time}} for other layouts, such
+// as "/srv/logs/{{.Year}}/{{.Month}}/{{.Day}}". Failed uploads are retried
+// with a backoff, like with WithS3Archive.
+func WithSFTPArchive(dial func(ctx context.Context) (SFTPClient, error), dir string) Option {
+	return func(logger *Logger) {
+		a := &sftpArchiver{l: logger, dial: dial}
+		a.dir, a.err = template.New("dir").Option("missingkey=error").Parse(dir)
+		logger.archiver = a
+	}
+}
+
+// WithKeepUploaded keeps only the newest n uploaded backups locally, see
+// Config.KeepUploaded.
+func WithKeepUploaded(n int) Option {
+	return func(logger *Logger) {
+		logger.KeepUploaded = n
+	}
+}
+
+// WithDeleteAfterUpload keeps backups until they are uploaded, see
+// Config.DeleteAfterUpload.
+func WithDeleteAfterUpload() Option {
+	return func(logger *Logger) {
+		logger.DeleteAfterUpload = true
+	}
+}
+
+// WithChecksums writes a SHA-256 checksum sidecar for every backup, see
+// Config.Checksums.
+func WithChecksums() Option {
+	return func(logger *Logger) {
+		logger.Checksums = true
+	}
+}
+
+// WithAtomicRecords never splits a Write, or a line, between two files, see
+// Config.AtomicRecords.
+func WithAtomicRecords() Option {
+	return func(logger *Logger) {
+		logger.AtomicRecords = true
+	}
+}
+
+func WithSizeSinceOpen() Option {
+	return func(logger *Logger) {
+		logger.SizeSinceOpen = true
+	}
+}
+
+// WithMaxLines rotates the file after n lines, see Config.MaxLines.
+func WithMaxLines(n int) Option {
+	return func(logger *Logger) {
+		logger.MaxLines = n
+	}
+}
+
+// WithSplitOversized splits a log file found to be more than factor times
+// MaxSize when opened into backups, as described on Config.SplitOversized.
+func WithSplitOversized(factor int) Option {
+	return func(logger *Logger) {
+		logger.SplitOversized = factor
+	}
+}
+
 func WithTimeRolling() Option {
 	return func(logger *Logger) {
 		logger.RollingPolicy = TimeRolling
 	}
 }
 
+// WithHybridRolling rolls the file by time, and by size in between, see
+// Config.RollingPolicy.
+func WithHybridRolling() Option {
+	return func(logger *Logger) {
+		logger.RollingPolicy = HybridRolling
+	}
+}
+
+// WithTimePattern sets the cron schedule of time rolling, see
+// Config.TimePattern.
 func WithTimePattern(timePattern string) Option {
 	return func(logger *Logger) {
 		logger.TimePattern = timePattern
 	}
 }
 
+// WithRollingInterval rolls the file by time at every interval boundary,
+// without having to write the cron expression for it, naming the backup
+// after the boundary. It keeps hybrid rolling if set.
+func WithRollingInterval(interval Interval) Option {
+	return func(logger *Logger) {
+		if logger.RollingPolicy != HybridRolling {
+			logger.RollingPolicy = TimeRolling
+		}
+		logger.TimePattern = string(interval)
+	}
+}
+
+// WithTimeZone evaluates the time rolling schedule in the named time zone,
+// see Config.TimeZone.
+func WithTimeZone(name string) Option {
+	return func(logger *Logger) {
+		logger.TimeZone = name
+		logger.location = nil
+	}
+}
+
+// WithDailyRotationAt rolls the file by time every day at hour:min in loc,
+// or in the local time zone if loc is nil. It keeps hybrid rolling if set.
+func WithDailyRotationAt(hour, min int, loc *time.Location) Option {
+	return func(logger *Logger) {
+		if logger.RollingPolicy != HybridRolling {
+			logger.RollingPolicy = TimeRolling
+		}
+		logger.TimePattern = dailyPattern(hour, min)
+		logger.location = loc
+		if loc != nil {
+			logger.TimeZone = loc.String()
+		}
+	}
+}
+
+// WithCompressAfter leaves the newest n backups uncompressed, see
+// Config.CompressAfter.
+func WithCompressAfter(n int) Option {
+	return func(logger *Logger) {
+		logger.CompressAfter = n
+	}
+}
+
 func WithCompress() Option {
 	return func(logger *Logger) {
 		logger.Compress = true
 	}
 }
 
+// WithCompression compresses backups in the given format, "gzip", "zstd" or
+// "lz4".
+func WithCompression(format string) Option {
+	return func(logger *Logger) {
+		logger.Compress = true
+		logger.Compression = format
+	}
+}
+
+// WithCompressionLevel sets the gzip level backups are compressed at, see
+// Config.CompressionLevel.
+func WithCompressionLevel(level int) Option {
+	return func(logger *Logger) {
+		logger.CompressionLevel = level
+	}
+}
+
+func WithMaxCompressionWorkers(n int) Option {
+	return func(logger *Logger) {
+		logger.MaxCompressionWorkers = n
+	}
+}
+
+func WithLowPriority() Option {
+	return func(logger *Logger) {
+		logger.LowPriority = true
+	}
+}
+
+func WithCompressSuffix(suffix string) Option {
+	return func(logger *Logger) {
+		logger.CompressSuffix = suffix
+	}
+}
+
+func WithCompressReplaceExt() Option {
+	return func(logger *Logger) {
+		logger.CompressReplaceExt = true
+	}
+}
+
+func WithSyncEveryWrite() Option {
+	return func(logger *Logger) {
+		logger.SyncEveryWrite = true
+	}
+}
+
+func WithGroupCommit(window time.Duration) Option {
+	return func(logger *Logger) {
+		logger.SyncEveryWrite = true
+		logger.GroupCommitWindow = window
+	}
+}
+
+// WithSyncInterval fsyncs the file at most d after a write, see
+// Config.SyncInterval.
+func WithSyncInterval(d time.Duration) Option {
+	return func(logger *Logger) {
+		logger.SyncInterval = d
+	}
+}
+
+// WithBufferSize buffers up to n bytes of writes in memory, see
+// Config.BufferSize.
+func WithBufferSize(n int) Option {
+	return func(logger *Logger) {
+		logger.BufferSize = n
+	}
+}
+
+// WithFlushInterval flushes the write buffer at most d after data was first
+// buffered, see Config.FlushInterval.
+func WithFlushInterval(d time.Duration) Option {
+	return func(logger *Logger) {
+		logger.FlushInterval = d
+	}
+}
+
+// WithWriteTrace calls fn with a trace of every nth Write, or of every Write if
+// n is less than 2. fn is called synchronously from Write and should be fast.
+func WithWriteTrace(fn func(WriteTrace), n int) Option {
+	return func(logger *Logger) {
+		logger.traceFn = fn
+		logger.traceEvery = 1
+		if n > 1 {
+			logger.traceEvery = uint64(n)
+		}
+	}
+}
+
+func WithAsync() Option {
+	return func(logger *Logger) {
+		logger.Async = true
+	}
+}
+
+func WithAsyncQueueSize(size int) Option {
+	return func(logger *Logger) {
+		logger.Async = true
+		logger.AsyncQueueSize = size
+	}
+}
+
+// WithBurstBuffer lets the async queue grow by up to size extra writes during
+// a burst, shrinking back to its normal size once they are written.
+// onHighWatermark, if not nil, is called with the queue length each time the
+// queue goes over its normal size.
+func WithBurstBuffer(size int, onHighWatermark func(queued int)) Option {
+	return func(logger *Logger) {
+		logger.Async = true
+		logger.AsyncBurstSize = size
+		logger.onHighWatermark = onHighWatermark
+	}
+}
+
+func WithScheduler(scheduler Scheduler) Option {
+	return func(logger *Logger) {
+		logger.scheduler = scheduler
+	}
+}
+
+// WithRotateOnHUP reopens or rotates the log file on SIGHUP, see Reopen.
+func WithRotateOnHUP() Option {
+	return func(logger *Logger) {
+		logger.RotateOnHUP = true
+	}
+}
+
+// WithReopenOnMove reopens the log file when it's moved or deleted, see
+// Config.ReopenOnMove.
+func WithReopenOnMove() Option {
+	return func(logger *Logger) {
+		logger.ReopenOnMove = true
+	}
+}
+
+func WithSynchronousMill() Option {
+	return func(logger *Logger) {
+		logger.SynchronousMill = true
+	}
+}
+
+func WithAdaptiveRotation(horizon time.Duration) Option {
+	return func(logger *Logger) {
+		logger.AdaptiveRotation = true
+		logger.AdaptiveHorizon = horizon
+	}
+}
+
+func WithLockMetrics() Option {
+	return func(logger *Logger) {
+		logger.LockMetrics = true
+	}
+}
+
+// WithEncryption encrypts backups, after compressing them if enabled, with
+// the last of keys. The other keys are older versions still needed to read
+// backups encrypted before the newest was added.
+func WithEncryption(keys ...EncryptionKey) Option {
+	return func(logger *Logger) {
+		logger.encryptionKeys = keys
+	}
+}
+
+// WithKeyProvider encrypts backups, after compressing them if enabled, with
+// keys from provider.
+func WithKeyProvider(provider KeyProvider) Option {
+	return func(logger *Logger) {
+		logger.keys = provider
+	}
+}
+
+// WithAgeEncryption encrypts backups, after compressing them if enabled, in
+// the age format to the recipients of enc, see AgeEncrypter. Encrypted
+// backups take the .age suffix and can be decrypted with the age command.
+// It can't be combined with WithEncryption or WithKeyProvider.
+func WithAgeEncryption(enc AgeEncrypter) Option {
+	return func(logger *Logger) {
+		logger.age = enc
+	}
+}
+
+// WithErrorHandler has fn called with errors which have no caller to be
+// returned to, and would otherwise go unnoticed: failed writes in async mode,
+// failed mill passes and background rotations, and corrupt backups found by
+// Verify. fn must not block. See also Errors.
+func WithErrorHandler(fn func(error)) Option {
+	return func(logger *Logger) {
+		logger.onError = fn
+	}
+}
+
+// WithExpvar publishes the Logger's Stats as the expvar name, see
+// Config.Expvar.
+func WithExpvar(name string) Option {
+	return func(logger *Logger) {
+		logger.Expvar = name
+	}
+}
+
+// WithOnRotate has fn called after every rotation with the path of the log
+// file and the path its content was moved to. With compression or
+// encryption enabled, the backup is later replaced by the processed one. fn is
+// called with the write lock held, so it must not block or write to the
+// Logger.
+func WithOnRotate(fn func(oldPath, newPath string)) Option {
+	return func(logger *Logger) {
+		logger.onRotate = fn
+	}
+}
+
+// WithFileHeader has the result of fn written at the top of every new log
+// file, on open and after every rotation, such as a line giving the version
+// of the program, the host and the time, so that every file describes
+// itself. A newline is added if missing. Files which already have content
+// when opened are appended to without a header. fn is called with the write
+// lock held and must not write to the Logger.
+func WithFileHeader(fn func() []byte) Option {
+	return func(logger *Logger) {
+		logger.fileHeader = fn
+	}
+}
+
+// WithTee has every write copied to w as well, such as os.Stdout for
+// container logs, whatever becomes of it in the file, except for writes
+// refused as bigger than MaxSize, which the writer gets an error for. Writes
+// to w are made under the write lock, in order, from the writer goroutine in
+// async mode; their errors are reported to the error handler rather than to
+// the writer.
+func WithTee(w io.Writer) Option {
+	return func(logger *Logger) {
+		logger.tee = w
+	}
+}
+
+// WithFallback has writes the file can't take, because it can't be written
+// to or rotated, or because of MinFreeSpace, forwarded to w instead, such as
+// the local syslog with WithSyslogFallback, so that logs degrade rather than
+// disappear. Such writes succeed if w takes them, the file's error going to
+// the error handler, and are counted in Stats.
+func WithFallback(w io.Writer) Option {
+	return func(logger *Logger) {
+		logger.fallback = w
+	}
+}
+
+// WithOnRemove has fn called for every backup removed by retention, with the
+// reason it was removed. fn is called from the mill and must not block.
+func WithOnRemove(fn func(path string, reason PruneReason)) Option {
+	return func(logger *Logger) {
+		logger.onRemove = fn
+	}
+}
+
+// WithAppendOnly protects the log file and its backups against tampering, as
+// described on Config.AppendOnly.
+func WithAppendOnly() Option {
+	return func(logger *Logger) {
+		logger.AppendOnly = true
+	}
+}
+
+// WithBackupNamePattern names backups following pattern, see
+// Config.BackupNamePattern.
+func WithBackupNamePattern(pattern string) Option {
+	return func(logger *Logger) {
+		logger.BackupNamePattern = pattern
+	}
+}
+
+// WithSequentialNames names backups app.log.1, app.log.2 and so on, see
+// Config.SequentialNames.
+func WithSequentialNames() Option {
+	return func(logger *Logger) {
+		logger.SequentialNames = true
+	}
+}
+
 func WithLocalTime() Option {
 	return func(logger *Logger) {
 		logger.LocalTime = true
 	}
 }
+
+// WithCopyTruncate copies then truncates the log file when it can't be
+// renamed for a rotation, see Config.CopyTruncate.
+func WithCopyTruncate() Option {
+	return func(logger *Logger) {
+		logger.CopyTruncate = true
+	}
+}
+
+func WithRotateBackoff(backoff, maxBackoff time.Duration) Option {
+	return func(logger *Logger) {
+		logger.RotateBackoff = backoff
+		logger.MaxRotateBackoff = maxBackoff
+	}
+}
+
+func WithMinFreeSpace(bytes int64) Option {
+	return func(logger *Logger) {
+		logger.MinFreeSpace = bytes
+	}
+}
+
+// WithLowDiskWatermark cleans up backups ahead of retention while less than
+// bytes are free on the log volume, see Config.LowDiskWatermark.
+func WithLowDiskWatermark(bytes int64) Option {
+	return func(logger *Logger) {
+		logger.LowDiskWatermark = bytes
+	}
+}
+
+// WithRateLimit caps writes at bytesPerSec bytes per second, with bursts of
+// up to burst bytes, see Config.RateLimit. Writes over the limit wait unless
+// WithRateLimitDrop is given too.
+func WithRateLimit(bytesPerSec, burst int64) Option {
+	return func(logger *Logger) {
+		logger.RateLimit = bytesPerSec
+		logger.RateBurst = burst
+	}
+}
+
+// WithRateLimitDrop drops writes over the rate limit instead of waiting.
+func WithRateLimitDrop() Option {
+	return func(logger *Logger) {
+		logger.RateLimitDrop = true
+	}
+}
+
+// WithDedup collapses runs of identical writes into a single one and a
+// "last message repeated N times" line, written at least every interval and
+// every maxRepeats repeats if not zero, see Config.DedupInterval.
+func WithDedup(interval time.Duration, maxRepeats int) Option {
+	return func(logger *Logger) {
+		logger.DedupInterval = interval
+		logger.DedupMaxRepeats = maxRepeats
+	}
+}
+
+// WithWriteTimeout fails writes which take longer than d with
+// ErrWriteTimeout, see Config.WriteTimeout.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(logger *Logger) {
+		logger.WriteTimeout = d
+	}
+}
+
+// WithPreallocate reserves the disk space for MaxSize bytes whenever a log
+// file is opened, see Config.Preallocate.
+func WithPreallocate() Option {
+	return func(logger *Logger) {
+		logger.Preallocate = true
+	}
+}
+
+// WithSynchronousIO opens the log file with O_DSYNC, see
+// Config.SynchronousIO.
+func WithSynchronousIO() Option {
+	return func(logger *Logger) {
+		logger.SynchronousIO = true
+	}
+}
+
+// WithOversizedWrites sets what becomes of writes bigger than MaxSize,
+// OversizedSplit or OversizedOwnFile, see Config.OversizedWrites.
+func WithOversizedWrites(mode string) Option {
+	return func(logger *Logger) {
+		logger.OversizedWrites = mode
+	}
+}
@@ -0,0 +1,160 @@
+package rolling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// manifest records what the Logger needs to remember about its backups
+// beyond what their names say. It is stored as JSON next to the log file, in
+// a hidden file that isn't mistaken for a backup.
+type manifest struct {
+	// Held lists the backups under hold by timestamp, as formatted in
+	// backup names, followed by their sequence number if they have one.
+	Held []string `json:"held,omitempty"`
+	// Uploaded lists the backups the archiver has uploaded, keyed the
+	// same way.
+	Uploaded []string `json:"uploaded,omitempty"`
+}
+
+// manifestPath returns the path of the Logger's manifest.
+func (l *Logger) manifestPath() string {
+	return filepath.Join(l.backupDir(), "."+l.Filename+".manifest")
+}
+
+// loadManifest reads the manifest, which is empty if it doesn't exist yet.
+// l.millMu must be held.
+func (l *Logger) loadManifest() (manifest, error) {
+	var m manifest
+	b, err := ioutil.ReadFile(l.manifestPath())
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("invalid manifest %s: %v", l.manifestPath(), err)
+	}
+	return m, nil
+}
+
+// saveManifest replaces the manifest with m. l.millMu must be held.
+func (l *Logger) saveManifest(m manifest) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	path := l.manifestPath()
+	tmp := path + tmpSuffix
+	if err := ioutil.WriteFile(tmp, b, l.fileMode()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// held returns the set of timestamps of held backups, as keyed by holdKey.
+func (m manifest) held() map[string]bool {
+	held := make(map[string]bool, len(m.Held))
+	for _, ts := range m.Held {
+		held[ts] = true
+	}
+	return held
+}
+
+// holdKey identifies a backup in the manifest. The timestamp, and sequence
+// number if any, are used rather than the name since compression and
+// encryption change the latter.
+func holdKey(f logInfo) string {
+	if f.seq > 0 {
+		return fmt.Sprintf("%s.%d", f.timestamp.Format(backupTimeFormat), f.seq)
+	}
+	return f.timestamp.Format(backupTimeFormat)
+}
+
+// backupOf returns the backup described by info, which must be one of l's,
+// in the backup directory or still waiting in LogPath to be moved there.
+func (l *Logger) backupOf(info BackupInfo) (logInfo, error) {
+	dir, name := filepath.Split(info.Path)
+	dir = filepath.Clean(dir)
+	if dir != filepath.Clean(l.backupDir()) && dir != filepath.Clean(l.LogPath) {
+		return logInfo{}, fmt.Errorf("%s is not in %s", info.Path, l.backupDir())
+	}
+	prefix, ext := l.prefixAndExt()
+	f, ok := l.parseBackupName(name, prefix, ext)
+	if !ok {
+		return logInfo{}, fmt.Errorf("%s is not a backup of %s", name, l.Filename)
+	}
+	f.dir = dir
+	return f, nil
+}
+
+// Hold exempts a backup from deletion by MaxAge and MaxRemain until it is
+// released, for instance to enforce a legal hold without disabling retention
+// altogether. Held backups don't count towards MaxRemain, and they are still
+// compressed and encrypted as usual. Holds are recorded in a manifest next to
+// the log file, so they survive restarts.
+func (l *Logger) Hold(info BackupInfo) error {
+	return l.setHold(info, true)
+}
+
+// Release lifts a hold placed with Hold, letting retention delete the backup
+// again from the next mill pass on. Releasing a backup that isn't held does
+// nothing.
+func (l *Logger) Release(info BackupInfo) error {
+	return l.setHold(info, false)
+}
+
+// Held reports whether a backup is under hold.
+func (l *Logger) Held(info BackupInfo) (bool, error) {
+	f, err := l.backupOf(info)
+	if err != nil {
+		return false, err
+	}
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	m, err := l.loadManifest()
+	if err != nil {
+		return false, err
+	}
+	return m.held()[holdKey(f)], nil
+}
+
+func (l *Logger) setHold(info BackupInfo, hold bool) error {
+	if l.SequentialNames {
+		return errors.New("backups can't be held with sequential names, which change on every rotation")
+	}
+	f, err := l.backupOf(info)
+	if err != nil {
+		return err
+	}
+
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	m, err := l.loadManifest()
+	if err != nil {
+		return err
+	}
+	held := m.held()
+	key := holdKey(f)
+	if held[key] == hold {
+		return nil
+	}
+	if hold {
+		held[key] = true
+	} else {
+		delete(held, key)
+	}
+
+	m.Held = m.Held[:0]
+	for ts := range held {
+		m.Held = append(m.Held, ts)
+	}
+	sort.Strings(m.Held)
+	return l.saveManifest(m)
+}
@@ -0,0 +1,101 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHold(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHold", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		newFakeTime()
+		name := backupFile(dir)
+		isNil(ioutil.WriteFile(name, []byte("data"), 0644), t)
+		names = append(names, name)
+	}
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxRemain(1), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	backups, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	oldest := backups[2]
+	equals(names[0], oldest.Path, t)
+	isNil(l.Hold(oldest), t)
+	held, err := l.Held(oldest)
+	isNil(err, t)
+	equals(true, held, t)
+
+	// the held backup doesn't count towards MaxRemain
+	isNil(l.millRunOnce(), t)
+	existsWithContent(names[0], []byte("data"), t)
+	notExist(names[1], t)
+	existsWithContent(names[2], []byte("data"), t)
+
+	// a backup belongs to the Logger of the file it was rotated from
+	l2, err := l.Clone(WithFilename("other.log"))
+	isNil(err, t)
+	isNil(l2.Close(), t)
+	_, err = l2.Held(oldest)
+	notNil(err, t)
+
+	isNil(l.Release(oldest), t)
+	isNil(l.millRunOnce(), t)
+	notExist(names[0], t)
+	existsWithContent(names[2], []byte("data"), t)
+
+	_, err = l.Held(BackupInfo{Path: logFile(dir)})
+	notNil(err, t)
+}
+
+func TestHoldPendingArchival(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHoldPendingArchival", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	// two backups the mill hasn't moved to the archive directory yet
+	newFakeTime()
+	older := backupFile(dir)
+	isNil(ioutil.WriteFile(older, []byte("data\n"), 0644), t)
+	newFakeTime()
+	newer := backupFile(dir)
+	isNil(ioutil.WriteFile(newer, []byte("data\nsecret\n"), 0644), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithArchiveDir(archive), WithMaxRemain(1),
+		WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(older, backups[1].Path, t)
+	isNil(l.Hold(backups[1]), t)
+	removed, err := l.PurgeRecords(backups[:1], func(line []byte) bool { return string(line) == "secret\n" })
+	isNil(err, t)
+	equals(1, removed, t)
+	existsWithContent(newer, []byte("data\n"), t)
+
+	// the hold carries over to the archive directory
+	isNil(l.millRunOnce(), t)
+	existsWithContent(filepath.Join(archive, filepath.Base(older)), []byte("data\n"), t)
+	existsWithContent(filepath.Join(archive, filepath.Base(newer)), []byte("data\n"), t)
+}
@@ -0,0 +1,199 @@
+package rolling
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Placeholders standing for the fields of a backup name template, to work
+// out from its output how to parse names back.
+const (
+	namePrefixMark = "\x00prefix\x00"
+	nameDateMark   = "\x00date\x00"
+	nameSeqMark    = "\x00seq\x00"
+	nameExtMark    = "\x00ext\x00"
+)
+
+var nameMarks = regexp.MustCompile("\x00(prefix|date|seq|ext)\x00")
+
+// backupNamer formats and parses backup names following a
+// BackupNamePattern.
+type backupNamer struct {
+	pattern string
+	// parts is the template's output with placeholders for the fields,
+	// split around them: literal text and marks alternate.
+	parts  []string
+	hasSeq bool
+
+	// mu guards res, the regexps matching names by prefix and extension.
+	mu  sync.Mutex
+	res map[[2]string]*regexp.Regexp
+}
+
+func newBackupNamer(pattern string) (*backupNamer, error) {
+	tmpl, err := template.New("backup").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup name pattern: %v", err)
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, map[string]interface{}{
+		"Prefix": namePrefixMark,
+		"Date":   nameDateMark,
+		"Seq":    nameSeqMark,
+		"Ext":    nameExtMark,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup name pattern: %v", err)
+	}
+	out := b.String()
+	n := &backupNamer{pattern: pattern, res: make(map[[2]string]*regexp.Regexp)}
+	dates, seqs := strings.Count(out, nameDateMark), strings.Count(out, nameSeqMark)
+	if dates != 1 || seqs > 1 {
+		return nil, errors.New("backup name pattern must use {{.Date}} once and {{.Seq}} at most once")
+	}
+	if strings.ContainsAny(nameMarks.ReplaceAllString(out, ""), `/\`) {
+		return nil, errors.New("backup name pattern must not contain path separators")
+	}
+	n.hasSeq = seqs == 1
+	last := 0
+	for _, loc := range nameMarks.FindAllStringIndex(out, -1) {
+		n.parts = append(n.parts, out[last:loc[0]], out[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	n.parts = append(n.parts, out[last:])
+	return n, nil
+}
+
+// format returns the name of the backup with the given timestamp and
+// sequence number, for a log file with the given prefix and extension.
+func (n *backupNamer) format(prefix, ext string, t time.Time, seq int) string {
+	var b strings.Builder
+	for _, p := range n.parts {
+		switch p {
+		case namePrefixMark:
+			b.WriteString(prefix)
+		case nameDateMark:
+			b.WriteString(t.Format(backupTimeFormat))
+		case nameSeqMark:
+			b.WriteString(strconv.Itoa(seq))
+		case nameExtMark:
+			b.WriteString(ext)
+		default:
+			b.WriteString(p)
+		}
+	}
+	return b.String()
+}
+
+// parse extracts the timestamp and sequence number from name if it is
+// formatted for the given prefix and extension.
+func (n *backupNamer) parse(name, prefix, ext string) (time.Time, int, bool) {
+	re := n.regexp(prefix, ext)
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(backupTimeFormat, m[re.SubexpIndex("date")])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	var seq int
+	if n.hasSeq {
+		seq, _ = strconv.Atoi(m[re.SubexpIndex("seq")])
+	}
+	return t, seq, true
+}
+
+// regexp returns the regexp matching names with the given prefix and
+// extension.
+func (n *backupNamer) regexp(prefix, ext string) *regexp.Regexp {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if re, ok := n.res[[2]string{prefix, ext}]; ok {
+		return re
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, p := range n.parts {
+		switch p {
+		case namePrefixMark:
+			b.WriteString(regexp.QuoteMeta(prefix))
+		case nameDateMark:
+			b.WriteString(`(?P<date>.+?)`)
+		case nameSeqMark:
+			b.WriteString(`(?P<seq>\d+)`)
+		case nameExtMark:
+			b.WriteString(regexp.QuoteMeta(ext))
+		default:
+			b.WriteString(regexp.QuoteMeta(p))
+		}
+	}
+	b.WriteString("$")
+	re := regexp.MustCompile(b.String())
+	n.res[[2]string{prefix, ext}] = re
+	return re
+}
+
+// namer returns the backupNamer for BackupNamePattern, or nil if it isn't
+// set and backups are named the default way.
+func (l *Logger) namer() (*backupNamer, error) {
+	if l.BackupNamePattern == "" {
+		return nil, nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.backupNamer == nil || l.backupNamer.pattern != l.BackupNamePattern {
+		n, err := newBackupNamer(l.BackupNamePattern)
+		if err != nil {
+			return nil, err
+		}
+		l.backupNamer = n
+	}
+	return l.backupNamer, nil
+}
+
+// plainBackupName returns the name of the uncompressed, unencrypted backup
+// with timestamp t and sequence number seq.
+func (l *Logger) plainBackupName(t time.Time, seq int) string {
+	if l.SequentialNames {
+		return l.Filename + "." + strconv.Itoa(seq)
+	}
+	ext := filepath.Ext(l.Filename)
+	prefix := l.Filename[:len(l.Filename)-len(ext)]
+	if n, err := l.namer(); err == nil && n != nil {
+		return n.format(prefix, ext, t, seq)
+	}
+	return prefix + "-" + t.Format(backupTimeFormat) + ext
+}
+
+// freeSeq returns the lowest sequence number from 1 up which, with
+// timestamp t, names no existing backup in dir, in any of its forms. It is
+// always 0 if the name pattern doesn't use a sequence number.
+func (l *Logger) freeSeq(dir string, t time.Time) int {
+	n, err := l.namer()
+	if err != nil || n == nil || !n.hasSeq {
+		return 0
+	}
+	for seq := 1; ; seq++ {
+		name := filepath.Join(dir, l.plainBackupName(t, seq))
+		taken := false
+		for _, variant := range []string{name, l.compressedName(name)} {
+			for _, v := range []string{variant, variant + encryptSuffix, variant + ageSuffix} {
+				if _, err := os.Stat(v); err == nil {
+					taken = true
+				}
+			}
+		}
+		if !taken {
+			return seq
+		}
+	}
+}
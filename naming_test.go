@@ -0,0 +1,78 @@
+package rolling
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupNamePattern(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestBackupNamePattern", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename("app.log"), WithMaxSize(10), WithMaxRemain(2),
+		WithBackupNamePattern("{{.Prefix}}.{{.Date}}.{{.Seq}}{{.Ext}}"), WithCompress(), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	name := func(seq string) string {
+		return filepath.Join(dir, "app."+fakeTime().UTC().Format(backupTimeFormat)+"."+seq+".log")
+	}
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("0000000!"))
+	isNil(err, t)
+	first := name("1") + compressSuffix
+	exists(first, t)
+
+	// a second rotation at the same time gets the next sequence number
+	_, err = l.Write([]byte("1111111!"))
+	isNil(err, t)
+	exists(name("2")+compressSuffix, t)
+
+	backups, err := NewInspector(dir, "app.log",
+		WithBackupNamePattern("{{.Prefix}}.{{.Date}}.{{.Seq}}{{.Ext}}")).ListBackups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(name("2")+compressSuffix, backups[0].Path, t)
+	equals(true, backups[0].Compressed, t)
+
+	// retention follows the names
+	newFakeTime()
+	_, err = l.Write([]byte("2222222!"))
+	isNil(err, t)
+	notExist(first, t)
+	fileCount(dir, 3, t)
+
+	for _, bad := range []string{"{{.Prefix}}{{.Ext}}", "{{.Date}}{{.Date}}", "{{.Nope}}", "{{.Date}}/x", "{{"} {
+		_, err := NewWriter(WithLogPath(dir), WithFilename("bad.log"), WithBackupNamePattern(bad))
+		notNil(err, t)
+	}
+}
+
+func TestBackupNamerParse(t *testing.T) {
+	n, err := newBackupNamer("{{.Ext}}_{{.Prefix}}_{{.Date}}")
+	isNil(err, t)
+	ts := time.Date(2021, 2, 3, 4, 5, 6, 7000000, time.UTC)
+	name := n.format("app", ".log", ts, 0)
+	equals(".log_app_2021-02-03T04-05-06.007", name, t)
+	got, _, ok := n.parse(name, "app", ".log")
+	equals(true, ok, t)
+	equals(true, got.Equal(ts), t)
+	_, _, ok = n.parse(name, "other", ".log")
+	equals(false, ok, t)
+}
@@ -0,0 +1,32 @@
+package rolling
+
+// The modes of OversizedWrites.
+const (
+	OversizedSplit   = "split"
+	OversizedOwnFile = "own_file"
+)
+
+// writeSplit writes p, which is bigger than MaxSize, in pieces, the first
+// filling what room is left in the file and the others a file each, rotating
+// in between, for OversizedSplit. l.mu must be held.
+func (l *Logger) writeSplit(p []byte, tr *WriteTrace) (n int, seq uint64, err error) {
+	for n < len(p) {
+		room := l.rotateSize()
+		if size, err := l.size(); err == nil && size < room {
+			room -= size
+		}
+		chunk := p[n:]
+		if int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		m, s, err := l.writeLocked(chunk, tr)
+		n += m
+		if s > 0 {
+			seq = s
+		}
+		if err != nil {
+			return n, seq, err
+		}
+	}
+	return n, seq, nil
+}
@@ -0,0 +1,75 @@
+package rolling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestOversizedSplit(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestOversizedSplit", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithOversizedWrites(OversizedSplit))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	b := []byte("0123456789abcdefghij")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+
+	// the write fills the file, then another, and starts a third
+	existsWithContent(logFile(dir), []byte("ghij"), t)
+	files, err := ioutil.ReadDir(dir)
+	isNil(err, t)
+	var contents []string
+	for _, f := range files {
+		c, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		isNil(err, t)
+		contents = append(contents, string(c))
+	}
+	sort.Strings(contents)
+	equals([]string{"6789abcdef", "boo!012345", "ghij"}, contents, t)
+}
+
+func TestOversizedOwnFile(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestOversizedOwnFile", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithOversizedWrites(OversizedOwnFile))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	b := bytes.Repeat([]byte("x"), 20)
+	_, err = l.Write(b)
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+	existsWithContent(logFile(dir), b, t)
+
+	// and the next write goes to a new file
+	newFakeTime()
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), b, t)
+	existsWithContent(logFile(dir), []byte("foo!"), t)
+}
+
+func TestOversizedInvalid(t *testing.T) {
+	_, err := NewWriter(WithLogPath(os.TempDir()), WithFilename(logName()), WithOversizedWrites("chop"))
+	notNil(err, t)
+}
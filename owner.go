@@ -0,0 +1,64 @@
+package rolling
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// osChown is os.Chown, a variable so tests can record calls without the
+// privileges to actually change owners.
+var osChown = os.Chown
+
+// fileOwner is the owner given with WithOwner.
+type fileOwner struct {
+	uid, gid int
+}
+
+// chown gives the file at path to the owner set with WithOwner, if any.
+func (l *Logger) chown(path string) error {
+	if l.owner == nil {
+		return nil
+	}
+	return osChown(path, l.owner.uid, l.owner.gid)
+}
+
+// chownDirs gives the log and archive directories to the owner set with
+// WithOwner, if any.
+func (l *Logger) chownDirs() error {
+	if err := l.chown(l.LogPath); err != nil {
+		return err
+	}
+	if l.ArchiveDir != "" {
+		return l.chown(l.ArchiveDir)
+	}
+	return nil
+}
+
+// chownBackups gives the backups, their checksum sidecars and the manifest
+// to the owner set with WithOwner, if any. Backups renamed from the log file
+// already have it, but compression, encryption and splitting write new
+// files, owned by the process. l.millMu must be held.
+func (l *Logger) chownBackups() error {
+	if l.owner == nil {
+		return nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	paths := []string{l.manifestPath()}
+	for _, f := range files {
+		path := filepath.Join(l.backupDir(), f.Name())
+		paths = append(paths, path)
+		if l.Checksums {
+			paths = append(paths, path+checksumSuffix)
+		}
+	}
+	for _, path := range paths {
+		if errChown := l.chown(path); errChown != nil && !os.IsNotExist(errChown) && err == nil {
+			err = fmt.Errorf("can't change owner of %s: %v", path, errChown)
+		}
+	}
+	return err
+}
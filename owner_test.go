@@ -0,0 +1,59 @@
+package rolling
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOwner(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestOwner", t)
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	owned := make(map[string]bool)
+	defer func() { osChown = os.Chown }()
+	osChown = func(name string, uid, gid int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if uid != 1000 || gid != 2000 {
+			t.Errorf("chown %s to %d:%d", name, uid, gid)
+		}
+		if _, err := os.Stat(name); err != nil {
+			return err
+		}
+		owned[name] = true
+		return nil
+	}
+	isOwned := func(name string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return owned[name]
+	}
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress(),
+		WithOwner(1000, 2000))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	assert(isOwned(dir), t, "log directory not chowned")
+	assert(isOwned(logFile(dir)), t, "log file not chowned")
+
+	// the new file and the compressed backup, which the process wrote
+	mu.Lock()
+	owned = make(map[string]bool)
+	mu.Unlock()
+	newFakeTime()
+	_, err = l.Write([]byte("foobarbaz!"))
+	isNil(err, t)
+	assert(isOwned(logFile(dir)), t, "new log file not chowned")
+	for i := 0; i < 100 && !isOwned(backupFile(dir)+compressSuffix); i++ {
+		<-time.After(10 * time.Millisecond)
+	}
+	assert(isOwned(backupFile(dir)+compressSuffix), t, "compressed backup not chowned")
+}
@@ -0,0 +1,23 @@
+//go:build linux
+// +build linux
+
+package rolling
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE from linux/falloc.h.
+const fallocKeepSize = 0x1
+
+// preallocate reserves size bytes of disk space for f from off on, without
+// changing its size, so that appending to it doesn't scatter its blocks nor
+// find the volume full. It does nothing on filesystems which can't.
+func preallocate(f *os.File, off, size int64) error {
+	err := syscall.Fallocate(int(f.Fd()), fallocKeepSize, off, size)
+	if err == nil || unsupported(err) {
+		return nil
+	}
+	return &os.PathError{Op: "fallocate", Path: f.Name(), Err: err}
+}
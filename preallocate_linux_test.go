@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package rolling
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestPreallocate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestPreallocate", t)
+	defer os.RemoveAll(dir)
+
+	probe, err := os.Create(logFile(dir) + ".probe")
+	isNil(err, t)
+	errProbe := syscall.Fallocate(int(probe.Fd()), fallocKeepSize, 0, 1)
+	isNil(probe.Close(), t)
+	isNil(os.Remove(probe.Name()), t)
+	if errProbe != nil {
+		t.Skipf("fallocate not supported here: %v", errProbe)
+	}
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1<<20), WithPreallocate())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	var st syscall.Stat_t
+	isNil(syscall.Stat(logFile(dir), &st), t)
+	// the space is reserved, while the size is what was written
+	assert(st.Blocks*512 >= 1<<20, t, "only %d bytes allocated", st.Blocks*512)
+	equals(int64(4), st.Size, t)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package rolling
+
+import "os"
+
+// preallocate does nothing on platforms without fallocate.
+func preallocate(f *os.File, off, size int64) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+package rolling
+
+import "runtime"
+
+// background runs fn, the CPU or I/O heavy part of some background work,
+// at reduced priority if LowPriority is set. The work gets an OS thread of
+// its own for the purpose, which is thrown away afterwards rather than
+// handed back to the Go scheduler with its priority lowered.
+func (l *Logger) background(fn func() error) error {
+	if !l.LowPriority || l.SynchronousMill {
+		return fn()
+	}
+	errc := make(chan error, 1)
+	go func() {
+		// never unlocked, so that the thread exits with the goroutine
+		runtime.LockOSThread()
+		_ = lowerThreadPriority()
+		errc <- fn()
+	}()
+	return <-errc
+}
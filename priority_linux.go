@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package rolling
+
+import "syscall"
+
+const (
+	// backgroundNice is the niceness given to threads doing background work.
+	backgroundNice = 10
+
+	ioprioWhoProcess = 1
+	ioprioClassBE    = 2
+	ioprioClassShift = 13
+	// backgroundIOPrio is the lowest priority of the best-effort I/O class,
+	// which unlike the idle class can't be starved indefinitely.
+	backgroundIOPrio = ioprioClassBE<<ioprioClassShift | 7
+)
+
+// lowerThreadPriority lowers the CPU and I/O scheduling priority of the
+// calling thread, which on Linux is a per-thread attribute.
+func lowerThreadPriority() error {
+	tid := syscall.Gettid()
+	err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, backgroundNice)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), backgroundIOPrio); errno != 0 && err == nil {
+		err = errno
+	}
+	return err
+}
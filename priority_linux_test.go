@@ -0,0 +1,24 @@
+package rolling
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestBackgroundLowPriority(t *testing.T) {
+	l := defaultLogWriter()
+	l.LowPriority = true
+
+	var prio int
+	err := l.background(func() (err error) {
+		// the raw syscall returns 20 - nice
+		prio, err = syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+		return err
+	})
+	isNil(err, t)
+	equals(20-backgroundNice, prio, t)
+
+	self, err := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+	isNil(err, t)
+	assert(self > prio, t, "priority of the calling thread changed to %d", self)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package rolling
+
+// lowerThreadPriority does nothing: elsewhere scheduling priority can only be
+// changed for the whole process, which would slow down the application too.
+func lowerThreadPriority() error {
+	return nil
+}
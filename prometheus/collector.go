@@ -0,0 +1,87 @@
+// Package prometheus exports the Stats of a rolling.Logger as Prometheus
+// metrics. It is a module of its own, so that using rolling doesn't bring in
+// the Prometheus client.
+package prometheus
+
+import (
+	"path/filepath"
+
+	"github.com/cnof/rolling"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// collector exports a Logger's Stats as Prometheus metrics.
+type collector struct {
+	l *rolling.Logger
+
+	writes       *prom.Desc
+	bytes        *prom.Desc
+	writeErrors  *prom.Desc
+	rotations    *prom.Desc
+	lastRotation *prom.Desc
+	size         *prom.Desc
+	backups      *prom.Desc
+	compression  *prom.Desc
+	pruned       *prom.Desc
+}
+
+// Collector returns a prometheus.Collector exporting l's Stats, along with
+// the number of backups on disk. Every metric has a file label holding the
+// path of the log file, so the collectors of several Loggers can be
+// registered together.
+func Collector(l *rolling.Logger) prom.Collector {
+	labels := prom.Labels{"file": filepath.Join(l.LogPath, l.Filename)}
+	desc := func(name, help string, variable ...string) *prom.Desc {
+		return prom.NewDesc("rolling_"+name, help, variable, labels)
+	}
+	return &collector{
+		l:            l,
+		writes:       desc("writes_total", "Writes made to the log file."),
+		bytes:        desc("written_bytes_total", "Bytes written to the log file."),
+		writeErrors:  desc("write_errors_total", "Writes that failed."),
+		rotations:    desc("rotations_total", "Successful rotations of the log file."),
+		lastRotation: desc("last_rotation_timestamp_seconds", "When the log file was last rotated."),
+		size:         desc("file_size_bytes", "Size of the active log file."),
+		backups:      desc("backups", "Backups on disk."),
+		compression:  desc("compression_duration_seconds", "Time spent compressing backups."),
+		pruned:       desc("pruned_backups_total", "Backups removed by retention.", "reason"),
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.writes
+	ch <- c.bytes
+	ch <- c.writeErrors
+	ch <- c.rotations
+	ch <- c.lastRotation
+	ch <- c.size
+	ch <- c.backups
+	ch <- c.compression
+	ch <- c.pruned
+}
+
+func (c *collector) Collect(ch chan<- prom.Metric) {
+	s := c.l.Stats()
+	counter := func(d *prom.Desc, v uint64, labels ...string) {
+		ch <- prom.MustNewConstMetric(d, prom.CounterValue, float64(v), labels...)
+	}
+	gauge := func(d *prom.Desc, v float64) {
+		ch <- prom.MustNewConstMetric(d, prom.GaugeValue, v)
+	}
+
+	counter(c.writes, s.Writes)
+	counter(c.bytes, s.BytesWritten)
+	counter(c.writeErrors, s.WriteErrors)
+	counter(c.rotations, s.Rotations)
+	if !s.LastRotation.IsZero() {
+		gauge(c.lastRotation, float64(s.LastRotation.UnixNano())/1e9)
+	}
+	gauge(c.size, float64(s.Size))
+	if backups, err := c.l.Backups(); err == nil {
+		gauge(c.backups, float64(len(backups)))
+	}
+	ch <- prom.MustNewConstSummary(c.compression, s.Compressions, s.CompressionTime.Seconds(), nil)
+	for reason, n := range s.Pruned {
+		counter(c.pruned, n, reason)
+	}
+}
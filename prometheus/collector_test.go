@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cnof/rolling"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestCollector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := rolling.NewWriter(rolling.WithLogPath(dir), rolling.WithFilename("foobar.log"),
+		rolling.WithMaxSize(1), rolling.WithMaxRemain(1), rolling.WithSynchronousMill())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for _, b := range []string{"boo!", "foo!bar!", "baz!qux!"} {
+		if _, err := l.Write([]byte(b)); err != nil {
+			t.Fatal(err)
+		}
+		if b != "baz!qux!" {
+			if err := l.Rotate(""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// bigger than MaxSize
+	if _, err := l.Write(make([]byte, 1024*1024+1)); err == nil {
+		t.Fatal("oversized write succeeded")
+	}
+
+	reg := prom.NewPedanticRegistry()
+	if err := reg.Register(Collector(l)); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			name := f.GetName()
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == "reason" {
+					name += "/" + lp.GetValue()
+				}
+			}
+			switch {
+			case m.Counter != nil:
+				values[name] = m.Counter.GetValue()
+			case m.Gauge != nil:
+				values[name] = m.Gauge.GetValue()
+			}
+		}
+	}
+	for name, exp := range map[string]float64{
+		"rolling_writes_total":                    3,
+		"rolling_written_bytes_total":             20,
+		"rolling_write_errors_total":              1,
+		"rolling_rotations_total":                 2,
+		"rolling_file_size_bytes":                 8,
+		"rolling_backups":                         1,
+		"rolling_pruned_backups_total/max_remain": 1,
+		"rolling_pruned_backups_total/max_age":    0,
+	} {
+		if values[name] != exp {
+			t.Errorf("%s: expected %v, got %v", name, exp, values[name])
+		}
+	}
+}
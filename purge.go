@@ -0,0 +1,162 @@
+package rolling
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PurgeRecords rewrites the given backups without the lines for which match
+// returns true, such as those mentioning a user whose data must be erased,
+// and returns how many lines were removed. Compressed and encrypted backups
+// are decompressed and decrypted to be filtered, then processed again the
+// same way, with the current encryption key or age recipients.
+//
+// Each backup is rewritten to a temporary file which then replaces it, so
+// a backup is never left half purged, and keeps its name, and so its hold if
+// any. Backups with nothing to remove are left untouched. The active file
+// isn't a backup and can't be purged; rotate it first.
+func (l *Logger) PurgeRecords(backups []BackupInfo, match func(line []byte) bool) (int, error) {
+	if l.AppendOnly {
+		return 0, errAppendOnly
+	}
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	var removed int
+	for _, b := range backups {
+		n, err := l.purge(b, match)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// PurgeBackups removes the backups rotated out more than olderThan ago, all
+// of them if olderThan isn't positive, right away rather than on the next
+// mill pass, as when disk space must be freed now, and returns how many
+// files were removed. With ArchiveDir, that includes backups still in
+// LogPath waiting to be moved there. Held backups, and those waiting for
+// upload with DeleteAfterUpload, are kept as they are from retention.
+// Removals are reported to WithOnRemove as PrunePurged.
+func (l *Logger) PurgeBackups(olderThan time.Duration) (int, error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	files, err := l.allLogFiles()
+	if err != nil {
+		return 0, err
+	}
+	if files, _, err = l.splitHeld(files); err != nil {
+		return 0, err
+	}
+	cutoff := currentTime().Add(-olderThan)
+	var removed int
+	for _, f := range files {
+		if olderThan > 0 && !f.timestamp.Before(cutoff) {
+			continue
+		}
+		if err := l.prune(f, PrunePurged); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// purge rewrites a single backup for PurgeRecords. l.millMu must be held.
+func (l *Logger) purge(b BackupInfo, match func(line []byte) bool) (removed int, err error) {
+	f, err := l.backupOf(b)
+	if err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(b.Path)
+	if err != nil {
+		return 0, err
+	}
+	r, err := l.OpenBackup(b.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	// the filtered backup is built up under its uncompressed name in a
+	// directory of its own, so that compression records the right name
+	tmpDir, err := ioutil.TempDir(f.dir, ".purge")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmp := filepath.Join(tmpDir, l.withLabel(l.plainBackupName(f.timestamp, f.seq), f.label))
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return 0, err
+	}
+	if removed, err = filterLines(out, r, match); err != nil {
+		_ = out.Close()
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		return 0, err
+	}
+
+	if f.compressed {
+		dst := l.compressedName(tmp)
+		if err := compressLogFile(tmp, dst, f.codec, l.CompressionLevel); err != nil {
+			return 0, err
+		}
+		tmp = dst
+	}
+	if f.encrypted {
+		if tmp, err = l.encryptBackup(tmp, f.age); err != nil {
+			return 0, err
+		}
+	}
+	if err := os.Rename(tmp, b.Path); err != nil {
+		return 0, err
+	}
+	if l.Checksums {
+		if err := writeChecksum(b.Path); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// filterLines copies the lines of r to w, except those match returns true
+// for, and returns how many it left out. A last line without a newline is
+// a line too.
+func filterLines(w io.Writer, r io.Reader, match func(line []byte) bool) (int, error) {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	var removed int
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if match(line) {
+				removed++
+			} else if _, errWrite := bw.Write(line); errWrite != nil {
+				return removed, errWrite
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, bw.Flush()
+}
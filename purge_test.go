@@ -0,0 +1,137 @@
+package rolling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPurgeRecords(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestPurgeRecords", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	isNil(ioutil.WriteFile(backupFile(dir), []byte("user=1 a\nuser=2 b\nuser=1 c"), 0644), t)
+	newFakeTime()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(20), WithCompress(),
+		WithEncryption(testKey("a")), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	_, err = l.Write([]byte("user=1 d\nuser=3 e\n"))
+	isNil(err, t)
+	_, err = l.Write([]byte("user=4 f\n"))
+	isNil(err, t)
+	processed := backupFile(dir) + compressSuffix + encryptSuffix
+	exists(processed, t)
+
+	backups, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	user1 := func(line []byte) bool { return bytes.HasPrefix(line, []byte("user=1 ")) }
+	n, err := l.PurgeRecords(backups, user1)
+	isNil(err, t)
+	equals(3, n, t)
+
+	for i, want := range []string{"user=3 e\n", "user=2 b\n"} {
+		r, err := l.OpenBackup(backups[i].Path)
+		isNil(err, t)
+		b, err := ioutil.ReadAll(r)
+		isNil(err, t)
+		isNil(r.Close(), t)
+		equals(want, string(b), t)
+	}
+	equals(processed, backups[0].Path, t)
+	fileCount(dir, 3, t)
+
+	n, err = l.PurgeRecords(backups, user1)
+	isNil(err, t)
+	equals(0, n, t)
+
+	_, err = l.PurgeRecords([]BackupInfo{{Path: logFile(dir)}}, user1)
+	notNil(err, t)
+}
+
+func TestPurgeBackups(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestPurgeBackups", t)
+	defer os.RemoveAll(dir)
+
+	var removed []PruneReason
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithSynchronousMill(),
+		WithOnRemove(func(path string, reason PruneReason) { removed = append(removed, reason) }))
+	isNil(err, t)
+	defer l.Close()
+
+	// four backups two days apart
+	var backups []string
+	for i := 0; i < 4; i++ {
+		_, err = l.Write([]byte("boo!"))
+		isNil(err, t)
+		isNil(l.Rotate(""), t)
+		backups = append(backups, backupFile(dir))
+		newFakeTime()
+	}
+	infos, err := l.Backups()
+	isNil(err, t)
+	isNil(l.Hold(infos[3]), t)
+
+	// the oldest is held, the newest too recent
+	n, err := l.PurgeBackups(3 * 24 * time.Hour)
+	isNil(err, t)
+	equals(2, n, t)
+	exists(backups[0], t)
+	notExist(backups[1], t)
+	notExist(backups[2], t)
+	exists(backups[3], t)
+	equals([]PruneReason{PrunePurged, PrunePurged}, removed, t)
+	equals(uint64(2), l.Stats().Pruned["purged"], t)
+
+	n, err = l.PurgeBackups(0)
+	isNil(err, t)
+	equals(1, n, t)
+	exists(backups[0], t)
+	notExist(backups[3], t)
+}
+
+func TestPurgeBackupsPendingArchival(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestPurgeBackupsPendingArchival", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+	isNil(os.MkdirAll(archive, 0755), t)
+
+	// one backup archived, and a newer one the mill hasn't moved yet
+	archived := filepath.Join(archive, filepath.Base(backupFile(dir)))
+	isNil(ioutil.WriteFile(archived, []byte("boo!"), 0644), t)
+	newFakeTime()
+	pending := backupFile(dir)
+	isNil(ioutil.WriteFile(pending, []byte("foo!"), 0644), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithArchiveDir(archive))
+	isNil(err, t)
+	defer l.Close()
+
+	n, err := l.PurgeBackups(0)
+	isNil(err, t)
+	equals(2, n, t)
+	notExist(archived, t)
+	notExist(pending, t)
+	exists(logFile(dir), t)
+}
@@ -0,0 +1,69 @@
+package rolling
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitWait waits for the rate limit to let a write through. It is a
+// variable so tests can skip the wait.
+var rateLimitWait = time.Sleep
+
+// rateLimiter is a token bucket holding up to burst bytes, refilled at rate
+// bytes per second. Its zero value starts full on first use.
+type rateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n bytes' worth of tokens, and returns how long to wait
+// before writing them, or false if they should be dropped instead. With
+// drop, writes are dropped when there are fewer tokens than they need, or
+// than burst for bigger ones. Otherwise the bucket goes into debt, and the
+// write waits for it to be paid off, so that the callers queued up are let
+// through in turn at the given rate.
+func (r *rateLimiter) reserve(n int, rate, burst int64, drop bool) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.last.IsZero() {
+		r.tokens = float64(burst)
+	} else if r.tokens += now.Sub(r.last).Seconds() * float64(rate); r.tokens > float64(burst) {
+		r.tokens = float64(burst)
+	}
+	r.last = now
+
+	need := float64(n)
+	if drop && r.tokens < math.Min(need, float64(burst)) {
+		return 0, false
+	}
+	r.tokens -= need
+	if r.tokens >= 0 {
+		return 0, true
+	}
+	return time.Duration(-r.tokens / float64(rate) * float64(time.Second)), true
+}
+
+// rateLimit applies RateLimit to a write of p, waiting for its turn or
+// reporting that it was dropped. It is called without l.mu held, so that a
+// waiting writer doesn't keep Close and the like waiting too.
+func (l *Logger) rateLimit(p []byte) (dropped bool) {
+	burst := l.RateBurst
+	if burst <= 0 {
+		burst = l.RateLimit
+	}
+	wait, ok := l.limiter.reserve(len(p), l.RateLimit, burst, l.RateLimitDrop)
+	if !ok {
+		atomic.AddUint64(&l.stats.droppedWrites, 1)
+		atomic.AddUint64(&l.stats.droppedBytes, uint64(len(p)))
+		return true
+	}
+	if wait > 0 {
+		rateLimitWait(wait)
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRateLimitDrop(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRateLimitDrop", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100),
+		WithRateLimit(1, 8), WithRateLimitDrop())
+	isNil(err, t)
+	defer l.Close()
+
+	for _, s := range []string{"boo!", "foo!", "bar!"} {
+		n, err := l.Write([]byte(s))
+		isNil(err, t)
+		equals(4, n, t)
+	}
+	existsWithContent(logFile(dir), []byte("boo!foo!"), t)
+	equals(uint64(1), l.Stats().DroppedWrites, t)
+	equals(uint64(4), l.Stats().DroppedBytes, t)
+}
+
+func TestRateLimitWait(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRateLimitWait", t)
+	defer os.RemoveAll(dir)
+
+	var waits []time.Duration
+	defer func() { rateLimitWait = time.Sleep }()
+	rateLimitWait = func(d time.Duration) { waits = append(waits, d) }
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100),
+		WithRateLimit(1, 8))
+	isNil(err, t)
+	defer l.Close()
+
+	// the burst goes through, then every write waits longer for its turn
+	for _, s := range []string{"boo!", "foo!", "bar!", "baz!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+	existsWithContent(logFile(dir), []byte("boo!foo!bar!baz!"), t)
+	equals(2, len(waits), t)
+	assert(waits[0] > 3*time.Second && waits[0] <= 4*time.Second, t, "first wait %v", waits[0])
+	assert(waits[1] > 7*time.Second && waits[1] <= 8*time.Second, t, "second wait %v", waits[1])
+	equals(uint64(0), l.Stats().DroppedWrites, t)
+}
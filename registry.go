@@ -0,0 +1,104 @@
+package rolling
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Manager creates and keeps one Logger per name, such as a module or a
+// tenant, writing to <name>.log in a common directory with a common
+// configuration. All Loggers in the process share one mill worker and one
+// scheduler whatever creates them; Manager adds a single place to look them
+// up and to close them all.
+type Manager struct {
+	options []Option
+	ext     string
+
+	mu      sync.Mutex
+	loggers map[string]*Logger
+	closed  bool
+}
+
+// NewManager returns a Manager creating Loggers configured by options as
+// NewWriter would, with the name they are asked for as Filename, keeping the
+// extension of the Filename in options. Nothing is opened until asked for.
+func NewManager(options ...Option) *Manager {
+	base := defaultLogWriter()
+	base.apply(options)
+	return &Manager{
+		options: options,
+		ext:     filepath.Ext(base.Filename),
+		loggers: make(map[string]*Logger),
+	}
+}
+
+// Get returns the Logger for name, creating it on first use with the
+// Manager's options followed by options, which are ignored afterwards.
+// Names are used as file names, so they can't be empty, "." or "..", or
+// contain path separators. With an Expvar name in the Manager's options, the
+// Logger publishes its Stats under that name followed by "." and name.
+func (m *Manager) Get(name string, options ...Option) (*Logger, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid logger name %q", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, errClosed
+	}
+	if l, ok := m.loggers[name]; ok {
+		return l, nil
+	}
+	opts := append(m.options[:len(m.options):len(m.options)], WithFilename(name+m.ext), withExpvarSuffix(name))
+	l, err := NewWriter(append(opts, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	m.loggers[name] = l
+	return l, nil
+}
+
+// Names returns the names of the open Loggers, sorted.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.loggers))
+	for name := range m.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close closes the Logger for name, if open. A later Get opens it again.
+func (m *Manager) Close(name string) error {
+	m.mu.Lock()
+	l, ok := m.loggers[name]
+	delete(m.loggers, name)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return l.Close()
+}
+
+// CloseAll closes all open Loggers. Get fails from then on.
+func (m *Manager) CloseAll() error {
+	m.mu.Lock()
+	m.closed = true
+	loggers := m.loggers
+	m.loggers = make(map[string]*Logger)
+	m.mu.Unlock()
+
+	var err error
+	for _, l := range loggers {
+		if errClose := l.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
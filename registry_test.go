@@ -0,0 +1,72 @@
+package rolling
+
+import (
+	"encoding/json"
+	"expvar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestManager", t)
+	defer os.RemoveAll(dir)
+
+	m := NewManager(WithLogPath(dir), WithFilename("app.log"), WithMaxSize(10000))
+	db, err := m.Get("db")
+	isNil(err, t)
+	http, err := m.Get("http", WithMaxRemain(5))
+	isNil(err, t)
+	again, err := m.Get("db")
+	isNil(err, t)
+	equals(true, db == again, t)
+	equals(5, http.MaxRemain, t)
+	equals([]string{"db", "http"}, m.Names(), t)
+
+	_, err = db.Write([]byte("query\n"))
+	isNil(err, t)
+	_, err = http.Write([]byte("request\n"))
+	isNil(err, t)
+	existsWithContent(filepath.Join(dir, "db.log"), []byte("query\n"), t)
+	existsWithContent(filepath.Join(dir, "http.log"), []byte("request\n"), t)
+
+	_, err = m.Get("../escape")
+	notNil(err, t)
+
+	isNil(m.Close("db"), t)
+	equals([]string{"http"}, m.Names(), t)
+	isNil(m.CloseAll(), t)
+	_, err = http.Write([]byte("late\n"))
+	notNil(err, t)
+	_, err = m.Get("db")
+	notNil(err, t)
+}
+
+func TestManagerExpvar(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestManagerExpvar", t)
+	defer os.RemoveAll(dir)
+
+	// each managed Logger publishes under a name of its own
+	m := NewManager(WithLogPath(dir), WithFilename("app.log"), WithExpvar("TestManagerExpvar"))
+	defer m.CloseAll()
+	for i, name := range []string{"a", "b"} {
+		l, err := m.Get(name)
+		isNil(err, t)
+		for j := 0; j <= i; j++ {
+			_, err = l.Write([]byte("boo!"))
+			isNil(err, t)
+		}
+	}
+	var s Stats
+	v := expvar.Get("TestManagerExpvar.a")
+	notNil(v, t)
+	isNil(json.Unmarshal([]byte(v.String()), &s), t)
+	equals(uint64(1), s.Writes, t)
+	v = expvar.Get("TestManagerExpvar.b")
+	notNil(v, t)
+	isNil(json.Unmarshal([]byte(v.String()), &s), t)
+	equals(uint64(2), s.Writes, t)
+	equals(true, expvar.Get("TestManagerExpvar") == nil, t)
+}
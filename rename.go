@@ -0,0 +1,77 @@
+package rolling
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// renameAttempts is how many times the log file is renamed for a rotation
+// before giving up, and renameBackoff how long to wait before the first
+// retry, doubling every time.
+const (
+	renameAttempts = 5
+	renameBackoff  = 10 * time.Millisecond
+)
+
+// osRename exists, so it can be mocked out by tests.
+var osRename = os.Rename
+
+// renameActive moves the log file at name to backup for a rotation. On
+// Windows, where a file can't be renamed while another process, such as an
+// antivirus scanner, has it open, the rename is retried for a while; if it
+// still fails, the file is copied to backup then truncated with
+// CopyTruncate.
+func (l *Logger) renameActive(name, backup string) error {
+	backoff := renameBackoff
+	err := l.rename(name, backup)
+	for i := 1; err != nil && i < renameAttempts && renameRetryable(err); i++ {
+		time.Sleep(backoff)
+		backoff *= 2
+		err = l.rename(name, backup)
+	}
+	if err != nil && l.CopyTruncate && !l.AppendOnly {
+		return copyTruncate(name, backup)
+	}
+	return err
+}
+
+// copyTruncate copies the file at src to dst, which only takes its name once
+// complete and synced, then truncates src. Anything written to src in
+// between by another process would be lost, but the Logger writes under its
+// write lock, and doesn't have the file open meanwhile.
+func copyTruncate(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + tmpSuffix
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	}
+	if err == nil {
+		err = osRename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = in.Close()
+	return os.Truncate(src, 0)
+}
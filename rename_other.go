@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package rolling
+
+// renameRetryable reports whether a failed rename may succeed if retried
+// shortly. Elsewhere than on Windows, open files don't stand in the way.
+func renameRetryable(err error) bool {
+	return false
+}
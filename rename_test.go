@@ -0,0 +1,46 @@
+package rolling
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCopyTruncate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCopyTruncate", t)
+	defer os.RemoveAll(dir)
+
+	// the active file is held open by someone else
+	defer func() { osRename = os.Rename }()
+	osRename = func(oldpath, newpath string) error {
+		if oldpath == logFile(dir) {
+			return errors.New("file in use")
+		}
+		return os.Rename(oldpath, newpath)
+	}
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCopyTruncate())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+	existsWithContent(logFile(dir), []byte("foo!bar!"), t)
+	notExist(backupFile(dir)+tmpSuffix, t)
+	equals(uint64(1), l.Stats().Rotations, t)
+
+	// without it, the rotation fails
+	osRename = func(oldpath, newpath string) error { return errors.New("file in use") }
+	l.CopyTruncate = false
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("foo!bar!baz!qux!"), t)
+	equals(uint64(1), l.Stats().Rotations, t)
+}
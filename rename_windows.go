@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package rolling
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows error codes for a file another process has open without sharing
+// deletion, as antivirus scanners and tailers often do.
+const (
+	errorAccessDenied     syscall.Errno = 5
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+// renameRetryable reports whether a failed rename may succeed if retried
+// shortly, once whoever holds the file lets go of it.
+func renameRetryable(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	return errno == errorAccessDenied || errno == errorSharingViolation || errno == errorLockViolation
+}
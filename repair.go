@@ -0,0 +1,92 @@
+package rolling
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepairBackup salvages what can be read of the compressed backup src, which
+// may be truncated or corrupt towards its end, as after a crash in the middle
+// of compression or a partial copy. It writes that to dst as a new, valid
+// file compressed in the same format as src, whether gzip, zstd or lz4, and
+// returns the number of uncompressed bytes salvaged; whatever followed the
+// damage is lost. An intact src is simply copied. If dst can't be written,
+// the error is returned and src is left as it was.
+func RepairBackup(src, dst string) (int64, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	br := bufio.NewReader(f)
+	c, err := detectCodec(br)
+	if err != nil {
+		return 0, fmt.Errorf("nothing to salvage from %s: %v", src, err)
+	}
+	r, err := c.newReader(br)
+	if err != nil {
+		return 0, fmt.Errorf("nothing to salvage from %s: %v", src, err)
+	}
+	defer r.Close()
+	// gzip records the original name and time
+	name, modTime := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src)), fi.ModTime()
+	if gz, ok := r.(*gzip.Reader); ok {
+		if gz.Name != "" && gz.Name == filepath.Base(gz.Name) {
+			name = gz.Name
+		}
+		if !gz.ModTime.IsZero() {
+			modTime = gz.ModTime
+		}
+	}
+
+	// decompress to a file named as the original was, so that the new
+	// header records the same name
+	tmpDir, err := ioutil.TempDir(filepath.Dir(dst), ".repair")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmp := filepath.Join(tmpDir, name)
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return 0, err
+	}
+	n, err := salvage(out, r)
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Chtimes(tmp, modTime, modTime); err != nil {
+		return 0, err
+	}
+	if err := compressLogFile(tmp, dst, c, 0); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// salvage copies r to w up to the first error. Read errors are what's being
+// repaired, so the data read before one is kept and only a write error is
+// returned.
+func salvage(w io.Writer, r io.Reader) (int64, error) {
+	src := &readErrRecorder{r: r}
+	n, err := io.Copy(w, src)
+	if err != nil && err == src.err {
+		err = nil
+	}
+	return n, err
+}
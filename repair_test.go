@@ -0,0 +1,86 @@
+package rolling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRepairBackup(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestRepairBackup", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	name := backupFile(dir)
+	data := bytes.Repeat([]byte("0123456789 some log line\n"), 10000)
+	isNil(ioutil.WriteFile(name, data, 0644), t)
+	isNil(compressLogFile(name, name+compressSuffix, gzipCodec, 0), t)
+	b, err := ioutil.ReadFile(name + compressSuffix)
+	isNil(err, t)
+	truncated := name + ".truncated" + compressSuffix
+	isNil(ioutil.WriteFile(truncated, b[:len(b)/2], 0644), t)
+
+	repaired := filepath.Join(dir, "repaired"+compressSuffix)
+	n, err := RepairBackup(truncated, repaired)
+	isNil(err, t)
+	equals(true, n > 0 && n < int64(len(data)), t)
+
+	size, err := originalSize(repaired)
+	isNil(err, t)
+	equals(n, size, t)
+	f, err := os.Open(repaired)
+	isNil(err, t)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	isNil(err, t)
+	equals(filepath.Base(name), gz.Name, t)
+	got, err := ioutil.ReadAll(gz)
+	isNil(err, t)
+	equals(true, bytes.Equal(data[:n], got), t)
+
+	n, err = RepairBackup(name+compressSuffix, repaired)
+	isNil(err, t)
+	equals(int64(len(data)), n, t)
+
+	_, err = RepairBackup(logFile(dir), repaired)
+	notNil(err, t)
+}
+
+type limitWriter struct {
+	w bytes.Buffer
+	n int
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.w.Len()+len(p) > lw.n {
+		return 0, errors.New("no space left")
+	}
+	return lw.w.Write(p)
+}
+
+func TestSalvage(t *testing.T) {
+	data := bytes.Repeat([]byte("some log line\n"), 100)
+
+	// a read error ends the copy but is no failure
+	var buf bytes.Buffer
+	r := io.MultiReader(bytes.NewReader(data), iotest.ErrReader(io.ErrUnexpectedEOF))
+	n, err := salvage(&buf, r)
+	isNil(err, t)
+	equals(int64(len(data)), n, t)
+	equals(true, bytes.Equal(data, buf.Bytes()), t)
+
+	// a write error is
+	_, err = salvage(&limitWriter{n: 10}, bytes.NewReader(data))
+	notNil(err, t)
+}
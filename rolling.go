@@ -1,9 +1,10 @@
 package rolling
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
-	"github.com/robfig/cron"
 	"io"
 	"io/ioutil"
 	"os"
@@ -12,29 +13,90 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
-// RollingPolicies give out 3 policy for rolling.
+// RollingPolicies give out 4 policy for rolling.
 const (
 	WithoutRolling = iota
 	TimeRolling
 	VolumeRolling
+	HybridRolling
+)
+
+// Interval is a preset schedule for time rolling, for WithRollingInterval.
+// Boundaries are in local time.
+type Interval string
+
+const (
+	Hourly Interval = "@hourly" // at the top of every hour
+	Daily  Interval = "@daily"  // at midnight
+	Weekly Interval = "@weekly" // at midnight between Saturday and Sunday
 )
 
 const (
 	rollingTimePattern = "0 0 0 * * ?"
 	backupTimeFormat   = "2006-01-02T15-04-05.000"
 	compressSuffix     = ".gz"
+	tmpSuffix          = ".tmp"
 	defaultMaxSize     = 100
+	defaultDirMode     = os.FileMode(0744)
+
+	defaultRotateBackoff    = time.Second
+	defaultMaxRotateBackoff = 5 * time.Minute
+
+	freeSpaceInterval = time.Second
+	fileCheckInterval = time.Second
 )
 
-var _ io.WriteCloser = (*Logger)(nil)
+var (
+	_ io.WriteCloser  = (*Logger)(nil)
+	_ io.StringWriter = (*Logger)(nil)
+)
+
+// PruneReason tells which retention setting had a backup removed.
+type PruneReason int
+
+const (
+	PruneMaxRemain PruneReason = iota
+	PruneMaxAge
+	PruneMaxTotalSize
+	PruneUploaded
+	PruneLowDisk
+	PrunePurged
+
+	numPruneReasons
+)
+
+func (r PruneReason) String() string {
+	switch r {
+	case PruneMaxRemain:
+		return "max_remain"
+	case PruneMaxAge:
+		return "max_age"
+	case PruneMaxTotalSize:
+		return "max_total_size"
+	case PruneUploaded:
+		return "uploaded"
+	case PruneLowDisk:
+		return "low_disk"
+	case PrunePurged:
+		return "purged"
+	}
+	return fmt.Sprintf("PruneReason(%d)", int(r))
+}
+
+var errAppendOnly = errors.New("backups can't be rewritten in append-only mode")
 
 var (
 	// currentTime exists, so it can be mocked out by tests.
 	currentTime = time.Now
 
+	// diskFree exists, so it can be mocked out by tests.
+	diskFree = freeSpace
+
 	// DefaultFileMode set the default open mode rw-r--r-- by default
 	DefaultFileMode = os.FileMode(0644)
 	// DefaultFileFlag set the default file flag
@@ -47,153 +109,751 @@ var (
 )
 
 type Logger struct {
-	LogPath  string `json:"logPath" yaml:"logPath"`
-	Filename string `json:"filename" yaml:"filename"`
-
-	// MaxAge is the maximum number of days to retain old log files based on the
-	// timestamp encoded in their filename.  Note that a day is defined as 24
-	// hours and may not exactly correspond to calendar days due to daylight
-	// savings, leap seconds, etc. The default is not to remove old log files
-	// based on age.
-	MaxAge int `json:"maxAge" yaml:"maxAge"`
-	// MaxRemain will auto clear the rolling file list, set 0 will disable auto clean
-	MaxRemain int `json:"max_remain"`
-
-	// RollingPolicy give out the rolling policy
-	// We got 3 policies(actually, 2):
-	//
-	//	1. WithoutRolling: no rolling will happen
-	//	2. TimeRolling: rolling by time
-	//	3. VolumeRolling: rolling by file size
-	RollingPolicy int    `json:"rolling_policy"`
-	TimePattern   string `json:"time_pattern"`
-	MaxSize       int    `json:"max_size"`
-
-	// Compress will compress log file with gzip
-	Compress bool `json:"compress"`
-
-	// LocalTime determines if the time used for formatting the timestamps in
-	// backup files is the computer's local time.  The default is to use UTC
-	// time.
-	LocalTime bool `json:"localtime" yaml:"localtime"`
+	Config
 
 	file      *os.File
 	mu        sync.Mutex
 	lock      sync.Mutex
 	absPath   string
-	fire      chan string
 	startAt   time.Time
-	cr        *cron.Cron
-	millCh    chan bool
-	startMill sync.Once
+	options   []Option
+	scheduler Scheduler
+	location  *time.Location
+
+	// backupNamer caches the parsed BackupNamePattern, guarded by lock.
+	backupNamer *backupNamer
+
+	traceFn    func(WriteTrace)
+	traceEvery uint64
+
+	adaptive adaptState
+
+	encryptionKeys []EncryptionKey
+	keys           KeyProvider
+	age            AgeEncrypter
+
+	async           *asyncQueue
+	onHighWatermark func(queued int)
+	onError         func(error)
+	onRotate        func(oldPath, newPath string)
+	onRemove        func(path string, reason PruneReason)
+	fileHeader      func() []byte
+	tee             io.Writer
+	owner           *fileOwner
+	fileFlags       int
+	fallback        io.Writer
+	errs            chan error
+	stopSched       func()
+
+	writeErrorPolicy WriteErrorPolicy
+
+	// schedule and nextRotateAt drive time rolling from Write in synchronous
+	// mode, where there is no scheduler goroutine to do it.
+	schedule     timeSchedule
+	nextRotateAt time.Time
+
+	pendingRotate bool
+	// rotateAt is the schedule boundary the pending rotation is for, which
+	// the backup is named after.
+	rotateAt       time.Time
+	rotateFailures int
+	retryRotateAt  time.Time
+	written        int64
+	// openSize is the size the file had when it was opened, before written.
+	openSize int64
+	// midLine is set while the last write didn't end with a newline.
+	midLine bool
+	// lines is the number of lines in the file, for MaxLines.
+	lines int64
+	// rotateLabel is the label of the backup Rotate is making.
+	rotateLabel string
+	// followers are the consumers of Follow.
+	followers map[*follower]struct{}
+
+	millMu        sync.Mutex
+	free          int64
+	freeCheckedAt time.Time
+	fileCheckedAt time.Time
+
+	lowDiskCheckedAt time.Time
+	limiter          rateLimiter
+	// stalled counts the writes given up on by WriteTimeout but still under
+	// way.
+	stalled int32
+	// shut is set once Shutdown is called, to turn writes away, and
+	// shutdownDone closed once it's over, with shutdownErr.
+	shut         int32
+	shutdownOnce sync.Once
+	shutdownDone chan struct{}
+	shutdownErr  error
+
+	// lastWrite is the last write made, which the repeats after it since
+	// repeatsSince are counted against, for DedupInterval.
+	lastWrite    []byte
+	repeats      int
+	repeatsSince time.Time
+
+	// archiver ships backups, reporting every attempt to onArchive.
+	archiver  Archiver
+	onArchive func(localPath string, err error)
+
+	// millFailures counts consecutive failed mill passes, retried by
+	// millRetry; both are guarded by millMu.
+	millFailures int
+	millRetry    *time.Timer
+
+	// stats is allocated separately to keep its 64-bit counters aligned for
+	// sync/atomic on 32-bit platforms.
+	stats *counters
+	// state is what Stats reports about the active file, published by
+	// unlockWrite under stateMu so that Stats never waits for l.mu.
+	stateMu sync.Mutex
+	state   fileState
+
+	writeSeq uint64
+	group    groupCommit
+	// unsynced is set, atomically, while the current file has writes Sync
+	// hasn't covered yet, so that Sync has nothing to do otherwise.
+	unsynced uint32
+
+	// buf holds writes not yet passed on to file when BufferSize is set,
+	// the oldest of them made at bufferedAt.
+	buf        []byte
+	bufferedAt time.Time
+	flushTimer *time.Timer
+
+	// syncPending is set while syncTimer is armed to fsync writes made
+	// since the last sync, for SyncInterval; synchronous mode has no timer
+	// and tracks the oldest unsynced write in dirtySince instead.
+	syncPending bool
+	syncTimer   *time.Timer
+	dirtySince  time.Time
 }
 
 func defaultLogWriter() *Logger {
-	return &Logger{
-		LogPath:       os.TempDir(),
-		Filename:      "all.log",
-		MaxAge:        30,
-		MaxRemain:     30,
-		RollingPolicy: VolumeRolling,
-		MaxSize:       15,
-		Compress:      false,
-		LocalTime:     false,
-		fire:          make(chan string),
-		startAt:       time.Now(),
-		cr:            cron.New(),
+	l := &Logger{
+		Config:  DefaultConfig(),
+		startAt: time.Now(),
+		stats:   new(counters),
+		errs:    make(chan error, errorsBuffer),
 	}
+	l.group.cond = sync.NewCond(&l.group.mu)
+	return l
 }
 
 func NewWriter(options ...Option) (*Logger, error) {
 	logger := defaultLogWriter()
-	for _, opt := range options {
-		opt(logger)
+	logger.apply(options)
+	if err := logger.open(); err != nil {
+		return nil, err
 	}
+	return logger, nil
+}
 
-	// make dir for path if not exist
-	if err := os.MkdirAll(logger.LogPath, 0744); err != nil {
+// New creates a Logger from cfg, applied as is: unlike NewWriter, only an
+// empty LogPath or Filename fall back to their defaults, so start from
+// DefaultConfig to get the same behaviour. Options are applied after cfg.
+func New(cfg Config, options ...Option) (*Logger, error) {
+	logger := defaultLogWriter()
+	def := logger.Config
+	logger.Config = cfg
+	if logger.LogPath == "" {
+		logger.LogPath = def.LogPath
+	}
+	if logger.Filename == "" {
+		logger.Filename = def.Filename
+	}
+	logger.apply(options)
+	if err := logger.open(); err != nil {
 		return nil, err
 	}
+	return logger, nil
+}
 
-	fp := path.Join(logger.LogPath, logger.Filename)
-	file, err := os.OpenFile(fp, DefaultFileFlag, DefaultFileMode)
-	if err != nil {
+// Clone creates a new Logger with the same configuration as l, modified by
+// options, which must at least change the file it writes to. It's a
+// convenient way to give several components their own files with a uniform
+// rolling and retention policy. Expvar isn't carried over, so that the clone
+// doesn't take l's name over; options can give it a name of its own.
+func (l *Logger) Clone(options ...Option) (*Logger, error) {
+	clone := defaultLogWriter()
+	// the parent's options carry the settings that aren't part of Config,
+	// such as callbacks, while its Config may have been changed since
+	clone.apply(l.options)
+	clone.Config = l.Config
+	clone.Expvar = ""
+	clone.apply(options)
+	if filepath.Join(clone.LogPath, clone.Filename) == filepath.Join(l.LogPath, l.Filename) {
+		return nil, errors.New("clone must write to a different file")
+	}
+	if err := clone.open(); err != nil {
 		return nil, err
 	}
+	return clone, nil
+}
 
-	logger.file = file
-	logger.absPath = fp
+// apply applies options to l, remembering them for Clone.
+func (l *Logger) apply(options []Option) {
+	for _, opt := range options {
+		opt(l)
+	}
+	l.options = append(l.options, options...)
+}
+
+// open opens the log file and starts rolling according to the configuration.
+func (l *Logger) open() error {
+	if l.encryptionKeys != nil {
+		keys, err := newKeyring(l.encryptionKeys)
+		if err != nil {
+			return err
+		}
+		l.keys = keys
+	}
 
-	switch logger.RollingPolicy {
+	if l.keys != nil && l.age != nil {
+		return errors.New("backups can't be encrypted both with keys and with age")
+	}
+
+	if _, err := l.namer(); err != nil {
+		return err
+	}
+	if l.CompressionLevel < gzip.HuffmanOnly || l.CompressionLevel > gzip.BestCompression {
+		return fmt.Errorf("invalid compression level %d", l.CompressionLevel)
+	}
+	switch l.OversizedWrites {
+	case "", OversizedSplit, OversizedOwnFile:
 	default:
-		fallthrough
-	case WithoutRolling:
-		return logger, nil
-	case TimeRolling:
-		if logger.TimePattern == "" {
-			logger.TimePattern = rollingTimePattern
+		return fmt.Errorf("invalid oversized writes mode %q", l.OversizedWrites)
+	}
+	if _, err := codecByName(l.Compression); err != nil {
+		return err
+	}
+	if l.SequentialNames && (l.BackupNamePattern != "" || l.SplitOversized > 0 || l.ArchiveDir != "" ||
+		l.archiver != nil || l.Checksums) {
+		return errors.New("sequential names can't be combined with BackupNamePattern, SplitOversized, ArchiveDir, uploads or checksums")
+	}
+	if a, ok := l.archiver.(*sftpArchiver); ok && a.err != nil {
+		return fmt.Errorf("invalid SFTP directory: %v", a.err)
+	}
+	if l.location == nil && l.TimeZone != "" {
+		loc, err := time.LoadLocation(l.TimeZone)
+		if err != nil {
+			return err
+		}
+		l.location = loc
+	}
+	if l.timeRolling() {
+		if l.TimePattern == "" {
+			l.TimePattern = rollingTimePattern
 		}
-		if err := logger.cr.AddFunc(logger.TimePattern, func() {
-			logger.fire <- logger.backupName(logger.LogPath, logger.Filename, logger.LocalTime)
-		}); err != nil {
-			return nil, err
+		// a custom Scheduler may understand other specs
+		if l.scheduler == nil {
+			if _, err := l.cronSchedule(); err != nil {
+				return err
+			}
 		}
-		logger.cr.Start()
 	}
 
-	return logger, nil
+	// make dir for path if not exist
+	if err := os.MkdirAll(l.LogPath, l.dirMode()); err != nil {
+		return err
+	}
+	if l.ArchiveDir != "" {
+		if err := os.MkdirAll(l.ArchiveDir, l.dirMode()); err != nil {
+			return err
+		}
+	}
+	if err := l.chownDirs(); err != nil {
+		return err
+	}
+
+	// the path is resolved once, so that a later change of working directory
+	// doesn't move the file
+	fp, err := filepath.Abs(path.Join(l.LogPath, l.Filename))
+	if err != nil {
+		return err
+	}
+	split, err := l.setAsideOversized(fp)
+	if err != nil {
+		return err
+	}
+	if l.MaxLines > 0 {
+		if l.lines, err = countLines(fp); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(fp, l.fileFlag(), l.fileMode())
+	if err != nil {
+		return err
+	}
+	if err := l.chown(fp); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if l.AppendOnly {
+		if err := setAppendOnly(fp, true); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if l.Expvar != "" {
+		if err := publishExpvar(l.Expvar, l); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+
+	l.file = file
+	l.absPath = fp
+	l.startAt = currentTime()
+	l.preallocateFile()
+	if err := l.writeHeader(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if split {
+		l.mill()
+	}
+	if l.RotateOnHUP {
+		defaultHUP.register(l)
+	}
+
+	if l.Async {
+		l.async = newAsyncQueue(l.AsyncQueueSize, l.AsyncBurstSize)
+		go l.runAsync()
+	}
+
+	if l.timeRolling() {
+		if err := l.startSchedule(); err != nil {
+			return err
+		}
+	}
+	l.publishFileState()
+	return nil
 }
 
 func (l *Logger) Write(p []byte) (n int, err error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	if atomic.LoadInt32(&l.shut) != 0 {
+		return 0, errClosed
+	}
+	if l.async != nil {
+		return l.enqueue(p)
+	}
+	if l.WriteTimeout > 0 {
+		return l.writeWithTimeout(p)
+	}
+	return l.writeNow(p)
+}
+
+// writeNow writes p synchronously, as Write does without async writes.
+func (l *Logger) writeNow(p []byte) (n int, err error) {
+	tr := l.startTrace(len(p))
+	start := tr.now()
+	defer func() { l.finishTrace(tr, start, err) }()
+
+	n, seq, err := l.write(p, tr)
+	if err != nil || !l.SyncEveryWrite || seq == 0 {
+		return n, err
+	}
+	start = tr.now()
+	err = l.commit(seq)
+	tr.observe(traceSync, start)
+	return n, err
+}
+
+// WriteString is like Write, without the copy converting s to a []byte
+// would make.
+func (l *Logger) WriteString(s string) (n int, err error) {
+	return l.Write(stringBytes(s))
+}
+
+// stringBytes returns the bytes of s without copying them. They must not be
+// modified, which Write, like any io.Writer, never does, nor kept: the write
+// buffer and the async queue make copies of their own.
+func stringBytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		int
+	}{s, len(s)}))
+}
+
+// write writes p to the current file, rotating first if needed, and returns
+// the sequence number of the write, or 0 if nothing was written.
+func (l *Logger) write(p []byte, tr *WriteTrace) (n int, seq uint64, err error) {
+	if l.RateLimit > 0 && l.rateLimit(p) {
+		return len(p), 0, nil
+	}
+
+	start := tr.now()
+	l.lockWrite()
+	defer l.unlockWrite()
+	tr.observe(traceWait, start)
+	if l.DedupInterval > 0 && l.dedupe(p) {
+		return len(p), 0, nil
+	}
+	return l.writeLocked(p, tr)
+}
+
+// writeLocked writes p under the write lock, which write has taken.
+func (l *Logger) writeLocked(p []byte, tr *WriteTrace) (n int, seq uint64, err error) {
+	defer func() {
+		if err != nil {
+			atomic.AddUint64(&l.stats.writeErrors, 1)
+		}
+	}()
 
 	writeLen := int64(len(p))
-	if writeLen > l.max() {
-		return 0, fmt.Errorf(
+	if writeLen > l.max() && l.OversizedWrites == OversizedSplit && l.sizeRolling() {
+		return l.writeSplit(p, tr)
+	}
+	if l.linesCut(p) >= 0 {
+		return l.writeLines(p, tr)
+	}
+
+	if writeLen > l.max() && !l.AtomicRecords && l.OversizedWrites != OversizedOwnFile {
+		return 0, 0, fmt.Errorf(
 			"write length %d exceeds maximum file size %d", writeLen, l.max(),
 		)
 	}
 
-	if l.RollingPolicy == TimeRolling {
-		select {
-		case <-l.fire:
-			if err := l.rotate(); err != nil {
-				return 0, err
-			}
-		default:
-			// 防止每天产生的日志文件过大
-			if info, err := l.file.Stat(); err == nil && info.Size()+writeLen > l.max() {
-				if err := l.rotate(); err != nil {
-					return 0, err
-				}
+	if l.tee != nil {
+		if _, err := l.tee.Write(p); err != nil {
+			l.reportError(fmt.Errorf("can't write to tee: %v", err))
+		}
+	}
+
+	if l.reserveBreached(writeLen) {
+		atomic.AddUint64(&l.stats.droppedWrites, 1)
+		atomic.AddUint64(&l.stats.droppedBytes, uint64(writeLen))
+		l.fallBack(p, nil)
+		return len(p), 0, nil
+	}
+
+	if l.AdaptiveRotation {
+		l.adapt()
+	}
+	if l.ReopenOnMove {
+		l.checkMoved()
+	}
+	if l.LowDiskWatermark > 0 {
+		l.checkLowDisk()
+	}
+
+	if l.timeRolling() {
+		if l.schedule != nil {
+			if now := currentTime(); !l.nextRotateAt.IsZero() && !now.Before(l.nextRotateAt) {
+				l.pendingRotate = true
+				l.rotateAt = l.nextRotateAt
+				l.nextRotateAt = l.schedule.Next(now)
 			}
 		}
-	} else if l.RollingPolicy == VolumeRolling {
-		if info, err := l.file.Stat(); err == nil && info.Size()+writeLen > l.max() {
-			if err := l.rotate(); err != nil {
-				return 0, err
+	}
+
+	if l.pendingRotate || l.exceeds(writeLen) || l.linesFull() {
+		if l.AtomicRecords && l.midLine {
+			// the line under way is finished in the current file, and the
+			// rotation done before the next one
+			l.pendingRotate = true
+		} else {
+			start := tr.now()
+			err := l.tryRotate()
+			tr.observe(traceRotate, start)
+			if err != nil {
+				if l.fallBack(p, err) {
+					return len(p), 0, nil
+				}
+				return 0, 0, err
 			}
 		}
 	}
 
-	n, err = l.file.Write(p)
-	return
+	start := tr.now()
+	n, err = l.writeFile(p)
+	if err != nil && l.writeErrorPolicy == WriteErrorBlock && isDiskFull(err) {
+		var m int
+		m, err = l.retryDiskFull(p[n:])
+		n += m
+	}
+	tr.observe(traceWrite, start)
+	if n > 0 {
+		l.midLine = p[n-1] != '\n'
+		if len(l.followers) > 0 {
+			l.publish(p[:n])
+		}
+	}
+	l.written += int64(n)
+	l.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	l.writeSeq++
+	atomic.StoreUint32(&l.unsynced, 1)
+	atomic.AddUint64(&l.stats.writes, 1)
+	atomic.AddUint64(&l.stats.bytesWritten, uint64(n))
+	l.scheduleSync()
+	if err != nil && l.fallBack(p[n:], err) {
+		n, err = len(p), nil
+	}
+	if err != nil && l.writeErrorPolicy == WriteErrorDrop && isDiskFull(err) {
+		l.dropDiskFull(p[n:])
+		n, err = len(p), nil
+	}
+	return n, l.writeSeq, err
 }
 
+// Close closes the file, once the async queue and the write buffer are
+// written out, and stops the Logger's background work: the rotation
+// schedule, flush and sync timers, and upload retries. A mill pass queued
+// for the Logger is run before Close returns, and one under way waited for,
+// so that none is left running afterwards.
 func (l *Logger) Close() error {
+	return l.closeLogger(false)
+}
+
+// closeLogger is Close, running a last mill pass whether or not one is queued
+// if lastPass is set, as for Shutdown. The pass comes before retries are
+// stopped, so that one it fails doesn't arm them again.
+func (l *Logger) closeLogger(lastPass bool) error {
+	if l.async != nil {
+		l.closeAsync()
+	}
+
+	if l.RotateOnHUP {
+		defaultHUP.unregister(l)
+	}
+	if l.Expvar != "" {
+		unpublishExpvar(l.Expvar, l)
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.close()
+	if l.stopSched != nil {
+		l.stopSched()
+	}
+	if l.flushTimer != nil {
+		l.flushTimer.Stop()
+	}
+	if l.syncTimer != nil {
+		l.syncTimer.Stop()
+	}
+	if l.file != nil {
+		l.flushRepeats()
+	}
+	err := l.close()
+	l.publishFileState()
+	l.closeFollowers()
+	// a pass still queued runs now rather than after Close returns
+	if lastPass || l.SynchronousMill || defaultMill.cancel(l) {
+		if errMill := l.millRunOnce(); err == nil {
+			err = errMill
+		}
+	}
+	l.millMu.Lock()
+	if l.millRetry != nil {
+		l.millRetry.Stop()
+	}
+	l.millMu.Unlock()
+	// nor one the retry queued in the meantime
+	defaultMill.cancel(l)
+	return err
+}
+
+// CurrentFile returns the absolute path of the file the Logger writes to, as
+// resolved when it was opened, for an application to report or hand to a
+// tail-based shipper. It stays the same across rotations, which move the
+// content written so far to a backup.
+func (l *Logger) CurrentFile() string {
+	return l.absPath
+}
+
+// fallBack forwards p, which couldn't be written to the file because of err,
+// or was dropped if err is nil, to the fallback writer if there is one, and
+// reports whether that worked. err is then reported to the error handler
+// rather than to the writer.
+func (l *Logger) fallBack(p []byte, err error) bool {
+	if l.fallback == nil {
+		return false
+	}
+	if _, errFallback := l.fallback.Write(p); errFallback != nil {
+		l.reportError(fmt.Errorf("can't write to fallback: %v", errFallback))
+		return false
+	}
+	atomic.AddUint64(&l.stats.fallbackWrites, 1)
+	if err != nil {
+		atomic.AddUint64(&l.stats.writeErrors, 1)
+		l.reportError(err)
+	}
+	return true
+}
+
+// reserveBreached reports whether writing writeLen more bytes would leave less
+// than MinFreeSpace bytes free on the log volume, running retention once to
+// try and make room first. The free space figure is refreshed at most every
+// freeSpaceInterval and is otherwise estimated from what has been written.
+// l.mu must be held.
+func (l *Logger) reserveBreached(writeLen int64) bool {
+	if l.MinFreeSpace <= 0 {
+		return false
+	}
+	now := currentTime()
+	if l.free-writeLen < l.MinFreeSpace || now.Sub(l.freeCheckedAt) >= freeSpaceInterval {
+		free, err := diskFree(l.LogPath)
+		if err != nil {
+			return false
+		}
+		l.free, l.freeCheckedAt = free, now
+	}
+	if l.free-writeLen < l.MinFreeSpace {
+		l.reportError(l.pruneOnce())
+		free, err := diskFree(l.LogPath)
+		if err != nil {
+			return false
+		}
+		l.free, l.freeCheckedAt = free, now
+		if l.free-writeLen < l.MinFreeSpace {
+			return true
+		}
+	}
+	l.free -= writeLen
+	return false
+}
+
+// checkLowDisk has the mill run if free space on the log volume has fallen
+// below LowDiskWatermark. It looks at most every freeSpaceInterval. l.mu must
+// be held.
+func (l *Logger) checkLowDisk() {
+	now := currentTime()
+	if now.Sub(l.lowDiskCheckedAt) < freeSpaceInterval {
+		return
+	}
+	l.lowDiskCheckedAt = now
+	if free, err := diskFree(l.LogPath); err == nil && free < l.LowDiskWatermark {
+		l.mill()
+	}
+}
+
+// preallocateFile reserves disk space for the file up to MaxSize, for
+// Preallocate. Failures are reported rather than returned, since the file is
+// usable all the same. l.mu must be held.
+func (l *Logger) preallocateFile() {
+	if !l.Preallocate || !l.sizeRolling() {
+		return
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		l.reportError(err)
+		return
+	}
+	if rest := l.max() - info.Size(); rest > 0 {
+		l.reportError(preallocate(l.file, info.Size(), rest))
+	}
+}
+
+// timeRolling reports whether the file is rotated on a schedule.
+func (l *Logger) timeRolling() bool {
+	return l.RollingPolicy == TimeRolling || l.RollingPolicy == HybridRolling
+}
+
+// sizeRolling reports whether the file is rotated when it reaches MaxSize.
+func (l *Logger) sizeRolling() bool {
+	return l.RollingPolicy == VolumeRolling || l.RollingPolicy == HybridRolling
+}
+
+// exceeds reports whether writing writeLen more bytes would take the current
+// file over MaxSize, with a rolling policy that cares.
+func (l *Logger) exceeds(writeLen int64) bool {
+	if !l.sizeRolling() {
+		return false
+	}
+	size, err := l.size()
+	if err != nil {
+		return false
+	}
+	if (l.AtomicRecords || l.OversizedWrites == OversizedOwnFile) && size == 0 {
+		// rotating wouldn't make room for an oversized record
+		return false
+	}
+	return size+writeLen > l.rotateSize()
+}
+
+// size returns the size of the file as rotation goes by: what was written
+// since it was opened with SizeSinceOpen, or its size, buffered writes
+// included. l.mu must be held.
+func (l *Logger) size() (int64, error) {
+	if l.SizeSinceOpen {
+		return l.written, nil
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size() + int64(len(l.buf)), nil
+}
+
+// tryRotate rotates the file unless an earlier failed rotation is still
+// cooling down. As long as the current file remains usable, a failed rotation
+// is not reported to the writer: the write goes to the current file and the
+// rotation is retried once the backoff has elapsed.
+func (l *Logger) tryRotate() error {
+	if currentTime().Before(l.retryRotateAt) {
+		return nil
+	}
+
+	err := l.rotate()
+	if err == nil {
+		l.pendingRotate = false
+		l.rotateFailures = 0
+		l.retryRotateAt = time.Time{}
+		return nil
+	}
+
+	l.rotateFailures++
+	l.retryRotateAt = currentTime().Add(l.rotateBackoff())
+	if l.file != nil {
+		l.reportError(err)
+		return nil
+	}
+	return err
+}
+
+// rotateBackoff returns how long to wait before retrying after the current
+// run of consecutive rotation failures, doubling from RotateBackoff up to
+// MaxRotateBackoff.
+func (l *Logger) rotateBackoff() time.Duration {
+	backoff, limit := l.RotateBackoff, l.MaxRotateBackoff
+	if backoff <= 0 {
+		backoff = defaultRotateBackoff
+	}
+	if limit <= 0 {
+		limit = defaultMaxRotateBackoff
+	}
+	for i := 1; i < l.rotateFailures && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if backoff > limit {
+		backoff = limit
+	}
+	return backoff
 }
 
 func (l *Logger) rotate() error {
 	if err := l.close(); err != nil {
 		return err
 	}
-	if err := l.openNew(); err != nil {
+	backup, err := l.openNew()
+	if err != nil {
+		// keep writing to the existing file until the rotation can be retried
+		if f, ferr := os.OpenFile(l.absPath, l.fileFlag(), l.fileMode()); ferr == nil {
+			l.file = f
+		}
 		return err
 	}
+	l.rotateAt = time.Time{}
+	atomic.AddUint64(&l.stats.rotations, 1)
+	atomic.StoreInt64(&l.stats.lastRotation, currentTime().UnixNano())
+	if backup != "" && l.onRotate != nil {
+		l.onRotate(l.absPath, backup)
+	}
 	l.mill()
 	return nil
 }
@@ -203,89 +863,303 @@ func (l *Logger) close() error {
 	if l.file == nil {
 		return nil
 	}
-	err := l.file.Close()
+	err := l.flush()
+	if l.SyncEveryWrite || l.SyncInterval > 0 {
+		l.syncBeforeClose()
+	}
+	if errClose := l.file.Close(); err == nil {
+		err = errClose
+	}
 	l.file = nil
 	return err
 }
 
 // openNew opens a new log file for writing, moving any old log file out of the
-// way.  This method assume the file has already been closed.
-func (l *Logger) openNew() error {
-	err := os.MkdirAll(l.LogPath, 0744)
+// way, and returns the path the old file was moved to, if there was one.
+// This method assume the file has already been closed.
+func (l *Logger) openNew() (backup string, err error) {
+	err = os.MkdirAll(l.LogPath, l.dirMode())
 	if err != nil {
-		return fmt.Errorf("can't make directories for new logfile: %s", err)
+		return "", fmt.Errorf("can't make directories for new logfile: %s", err)
 	}
 	name := l.absPath
-	mode := os.FileMode(0644)
+	mode := l.fileMode()
 	info, err := os.Stat(name)
 	if err == nil {
-		mode = info.Mode()
+		if l.FileMode == 0 {
+			mode = info.Mode()
+		}
 
-		newName := l.backupName(l.LogPath, l.Filename, l.LocalTime)
-		if err := os.Rename(name, newName); err != nil {
-			return fmt.Errorf("can't rename log file: %s", err)
+		if l.SequentialNames {
+			backup, err = l.rotateSequential(name)
+		} else {
+			backup = l.backupName(l.LogPath, l.LocalTime)
+			err = l.renameActive(name, backup)
+		}
+		if err != nil {
+			return "", fmt.Errorf("can't rename log file: %s", err)
 		}
 	}
 
 	// we use truncate here because this should only get called when we've moved
 	// the file ourselves. if someone else creates the file in the meantime,
 	// just wipe out the contents.
-	f, err := os.OpenFile(name, DefaultFileFlag, mode)
+	f, err := os.OpenFile(name, l.fileFlag(), mode)
 	if err != nil {
-		return fmt.Errorf("can't open new logfile: %s", err)
+		return "", fmt.Errorf("can't open new logfile: %s", err)
+	}
+	// like the header below, a failed chown doesn't undo the rotation
+	l.reportError(l.chown(name))
+	if l.AppendOnly {
+		if err := setAppendOnly(name, true); err != nil {
+			_ = f.Close()
+			return "", err
+		}
 	}
 	l.file = f
+	l.written = 0
+	l.midLine = false
+	l.lines = 0
+	l.startAt = currentTime()
+	l.preallocateFile()
+	// the rotation is done, and the file usable, whether or not the header
+	// made it
+	l.reportError(l.writeHeader())
 
-	return nil
+	return backup, nil
+}
+
+// fileMode returns the mode new log files are created with: FileMode if
+// set, or else DefaultFileMode.
+func (l *Logger) fileMode() os.FileMode {
+	if l.FileMode != 0 {
+		return l.FileMode
+	}
+	return DefaultFileMode
 }
 
+// dirMode returns the mode missing directories are created with: DirMode if
+// set, or else defaultDirMode.
+func (l *Logger) dirMode() os.FileMode {
+	if l.DirMode != 0 {
+		return l.DirMode
+	}
+	return defaultDirMode
+}
+
+// fileFlag returns the flags to open the log file with: those set with
+// WithFileFlags, or else DefaultFileFlag, with O_DSYNC for SynchronousIO.
+func (l *Logger) fileFlag() int {
+	flag := DefaultFileFlag
+	if l.fileFlags != 0 {
+		flag = l.fileFlags
+	}
+	if l.SynchronousIO {
+		flag |= oDsync
+	}
+	if l.AppendOnly {
+		return flag&^os.O_TRUNC | os.O_APPEND
+	}
+	return flag
+}
+
+// rename renames one of the Logger's files, lifting the append-only
+// attribute for the purpose in append-only mode.
+func (l *Logger) rename(oldpath, newpath string) error {
+	if !l.AppendOnly {
+		return osRename(oldpath, newpath)
+	}
+	if err := setAppendOnly(oldpath, false); err != nil {
+		return err
+	}
+	if err := osRename(oldpath, newpath); err != nil {
+		_ = setAppendOnly(oldpath, true)
+		return err
+	}
+	return setAppendOnly(newpath, true)
+}
+
+// remove removes one of the Logger's files, lifting the append-only
+// attribute first in append-only mode.
+func (l *Logger) remove(path string) error {
+	if l.AppendOnly {
+		if err := setAppendOnly(path, false); err != nil {
+			return err
+		}
+	}
+	return os.Remove(path)
+}
+
+// mill queues a pass of post-rotation compression and removal of old log
+// files on the shared mill worker, or runs it straight away in synchronous
+// mode.
 func (l *Logger) mill() {
-	l.startMill.Do(func() {
-		l.millCh = make(chan bool, 1)
-		go l.millRun()
-	})
-	select {
-	case l.millCh <- true:
-	default:
+	if l.SynchronousMill {
+		l.reportError(l.millRunOnce())
+		return
 	}
+	defaultMill.enqueue(l)
 }
 
-// millRun runs in a goroutine to manage post-rotation compression and removal
-// of old log files.
-func (l *Logger) millRun() {
-	for range l.millCh {
-		_ = l.millRunOnce()
+// millDone records the outcome of a mill pass. A failed pass is retried, the
+// work it left undone with it, with a backoff, unless in synchronous mode
+// where the next rotation or Close does it. Failures which persist are told
+// apart by the returned error, which counts them. l.millMu must be held.
+func (l *Logger) millDone(err error) error {
+	if err == nil {
+		l.millFailures = 0
+		return nil
 	}
+	l.millFailures++
+	if !l.SynchronousMill {
+		backoff := defaultRotateBackoff
+		for i := 1; i < l.millFailures && backoff < defaultMaxRotateBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > defaultMaxRotateBackoff {
+			backoff = defaultMaxRotateBackoff
+		}
+		if l.millRetry == nil {
+			l.millRetry = time.AfterFunc(backoff, l.mill)
+		} else {
+			l.millRetry.Reset(backoff)
+		}
+	}
+	if l.millFailures > 1 {
+		return fmt.Errorf("mill pass failed %d times in a row: %w", l.millFailures, err)
+	}
+	return err
 }
 
 // millRunOnce performs compression and removal of stale log files.
 // Log files are compressed if enabled via configuration and old log
 // files are removed, keeping at most l.MaxBackups files, as long as
 // none of them are older than MaxAge.
-func (l *Logger) millRunOnce() error {
-	if l.MaxRemain == 0 && l.MaxAge == 0 && !l.Compress {
+func (l *Logger) millRunOnce() (err error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	defer func() { err = l.millDone(err) }()
+
+	if err := l.archiveBackups(); err != nil {
+		return err
+	}
+	if err := l.splitSetAside(); err != nil {
+		return err
+	}
+
+	if l.MaxRemain == 0 && l.maxAge() == 0 && l.MaxTotalSize == 0 && !l.Compress && !l.encrypts() &&
+		l.ArchiveDir == "" && l.archiver == nil && !l.Checksums && l.owner == nil && l.LowDiskWatermark == 0 {
+		return nil
+	}
+
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+
+	// the newest backups are left alone for CompressAfter
+	recent := make(map[string]bool)
+	for _, f := range files {
+		if len(recent) >= l.CompressAfter && !recent[holdKey(f)] {
+			break
+		}
+		recent[holdKey(f)] = true
+	}
+
+	// held backups, and those waiting for upload with DeleteAfterUpload,
+	// are left out of retention, but still processed
+	var held []logInfo
+	if l.retains() {
+		if files, held, err = l.splitHeld(files); err != nil {
+			return err
+		}
+	}
+
+	files, remove, reasons, lowDisk := l.retention(files, held)
+	// below the low disk watermark, the others are processed without
+	// waiting for CompressAfter
+	if lowDisk {
+		recent = nil
+	}
+
+	var process []logInfo
+	for _, f := range append(files, held...) {
+		if l.needsProcessing(f) && !recent[holdKey(f)] {
+			process = append(process, f)
+		}
+	}
+
+	for i, f := range remove {
+		if errRemove := l.prune(f, reasons[i]); err == nil {
+			err = errRemove
+		}
+	}
+	if errProcess := l.processAll(process); err == nil {
+		err = errProcess
+	}
+	if errSum := l.writeChecksums(); err == nil {
+		err = errSum
+	}
+	if errChown := l.chownBackups(); err == nil {
+		err = errChown
+	}
+	if errShip := l.shipBackups(); err == nil {
+		err = errShip
+	}
+
+	return err
+}
+
+// pruneOnce runs retention alone, without the rest of a mill pass, for
+// reserveBreached to make room on the log volume. l.mu must be held.
+func (l *Logger) pruneOnce() (err error) {
+	if !l.retains() {
 		return nil
 	}
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
 
 	files, err := l.oldLogFiles()
 	if err != nil {
 		return err
 	}
+	files, held, err := l.splitHeld(files)
+	if err != nil {
+		return err
+	}
+	_, remove, reasons, _ := l.retention(files, held)
+	for i, f := range remove {
+		if errRemove := l.prune(f, reasons[i]); err == nil {
+			err = errRemove
+		}
+	}
+	return err
+}
 
-	var remove []logInfo
+// retains reports whether any retention setting is in force.
+func (l *Logger) retains() bool {
+	return l.MaxRemain > 0 || l.maxAge() > 0 || l.MaxTotalSize > 0 || l.LowDiskWatermark > 0
+}
 
+// retention picks the backups among files, newest first, which the retention
+// settings remove, and why, returning the others too. held backups are never
+// picked but count towards MaxTotalSize. lowDisk reports whether free space
+// was below LowDiskWatermark. l.millMu must be held.
+func (l *Logger) retention(files, held []logInfo) (remaining, remove []logInfo, reasons []PruneReason, lowDisk bool) {
 	if l.MaxRemain > 0 && l.MaxRemain < len(files) {
-		preserved := make(map[string]bool)
+		// a backup and its compressed copy share a timestamp and count once
+		type backupID struct {
+			timestamp int64
+			seq       int
+		}
+		preserved := make(map[backupID]bool)
 		var remaining []logInfo
 		for _, f := range files {
-			fn := f.Name()
-			if strings.HasSuffix(fn, compressSuffix) {
-				fn = fn[:len(fn)-len(compressSuffix)]
-			}
-			preserved[fn] = true
+			preserved[backupID{f.timestamp.UnixNano(), f.seq}] = true
 
 			if len(preserved) > l.MaxRemain {
 				remove = append(remove, f)
+				reasons = append(reasons, PruneMaxRemain)
 			} else {
 				remaining = append(remaining, f)
 			}
@@ -293,14 +1167,14 @@ func (l *Logger) millRunOnce() error {
 		files = remaining
 	}
 
-	if l.MaxAge > 0 {
-		diff := time.Duration(int64(24*time.Hour) * int64(l.MaxAge))
-		cutoff := currentTime().Add(-1 * diff)
+	if maxAge := l.maxAge(); maxAge > 0 {
+		cutoff := currentTime().Add(-1 * maxAge)
 
 		var remaining []logInfo
 		for _, f := range files {
 			if f.timestamp.Before(cutoff) {
 				remove = append(remove, f)
+				reasons = append(reasons, PruneMaxAge)
 			} else {
 				remaining = append(remaining, f)
 			}
@@ -308,20 +1182,133 @@ func (l *Logger) millRunOnce() error {
 		files = remaining
 	}
 
-	for _, f := range remove {
-		errRemove := os.Remove(filepath.Join(l.LogPath, f.Name()))
-		if err == nil && errRemove != nil {
-			err = errRemove
+	if l.MaxTotalSize > 0 {
+		// held backups can't be removed but still take up the space
+		var total int64
+		if info, err := os.Stat(l.absPath); err == nil {
+			total = info.Size()
+		}
+		for _, f := range append(files, held...) {
+			total += f.Size()
+		}
+		for len(files) > 0 && total > l.MaxTotalSize {
+			oldest := files[len(files)-1]
+			remove = append(remove, oldest)
+			reasons = append(reasons, PruneMaxTotalSize)
+			total -= oldest.Size()
+			files = files[:len(files)-1]
 		}
 	}
 
-	return err
+	// below the low disk watermark, the oldest backups go whatever the
+	// retention settings
+	if l.LowDiskWatermark > 0 {
+		if free, errFree := diskFree(l.LogPath); errFree == nil && free < l.LowDiskWatermark {
+			for len(files) > 0 && free < l.LowDiskWatermark {
+				oldest := files[len(files)-1]
+				remove = append(remove, oldest)
+				reasons = append(reasons, PruneLowDisk)
+				free += oldest.Size()
+				files = files[:len(files)-1]
+			}
+			lowDisk = true
+		}
+	}
+	return files, remove, reasons, lowDisk
+}
+
+// splitHeld separates the backups retention may remove from the held ones,
+// and those waiting for upload with DeleteAfterUpload. l.millMu must be held.
+func (l *Logger) splitHeld(files []logInfo) (remaining, held []logInfo, err error) {
+	m, err := l.loadManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+	isHeld := m.held()
+	if l.DeleteAfterUpload && l.archiver != nil {
+		uploaded := m.uploaded()
+		for _, f := range files {
+			if !uploaded[holdKey(f)] {
+				isHeld[holdKey(f)] = true
+			}
+		}
+	}
+	if len(isHeld) == 0 {
+		return files, nil, nil
+	}
+	for _, f := range files {
+		if isHeld[holdKey(f)] {
+			held = append(held, f)
+		} else {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining, held, nil
 }
 
-// oldLogFiles returns the list of backup log files stored in the same
-// directory as the current log file, sorted by ModTime
+// prune removes the backup f for the given reason.
+func (l *Logger) prune(f logInfo, reason PruneReason) error {
+	fn := l.backupPath(f)
+	if err := l.remove(fn); err != nil {
+		return err
+	}
+	if l.Checksums {
+		if err := removeChecksum(fn); err != nil {
+			return err
+		}
+	}
+	atomic.AddUint64(&l.stats.pruned[reason], 1)
+	if l.onRemove != nil {
+		l.onRemove(fn, reason)
+	}
+	return nil
+}
+
+// maxAge returns how long backups are kept, MaxAgeDuration or else MaxAge
+// days, or 0 if they're kept whatever their age.
+func (l *Logger) maxAge() time.Duration {
+	if l.MaxAgeDuration > 0 {
+		return l.MaxAgeDuration
+	}
+	return time.Duration(int64(24*time.Hour) * int64(l.MaxAge))
+}
+
+// oldLogFiles returns the list of backup log files stored in the backup
+// directory, sorted by ModTime
 func (l *Logger) oldLogFiles() ([]logInfo, error) {
-	files, err := ioutil.ReadDir(l.LogPath)
+	return l.logFilesIn(l.backupDir())
+}
+
+// allLogFiles returns the backups in the backup directory and, with
+// ArchiveDir, those rotated out to LogPath that the mill hasn't moved there
+// yet, newest first. l.millMu must be held.
+func (l *Logger) allLogFiles() ([]logInfo, error) {
+	files, err := l.oldLogFiles()
+	if err != nil || l.ArchiveDir == "" || filepath.Clean(l.ArchiveDir) == filepath.Clean(l.LogPath) {
+		return files, err
+	}
+	pending, err := l.logFilesIn(l.LogPath)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, pending...)
+	sort.Sort(byFormatTime(files))
+	return files, nil
+}
+
+// backupPath returns the path of the backup f, in the directory it was
+// listed in, or else the backup directory.
+func (l *Logger) backupPath(f logInfo) string {
+	if f.dir != "" {
+		return filepath.Join(f.dir, f.Name())
+	}
+	return filepath.Join(l.backupDir(), f.Name())
+}
+
+// logFilesIn returns the list of backup log files stored in dir, sorted by
+// ModTime.
+func (l *Logger) logFilesIn(dir string) ([]logInfo, error) {
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("can't read log file directory: %s", err)
 	}
@@ -333,16 +1320,20 @@ func (l *Logger) oldLogFiles() ([]logInfo, error) {
 		if f.IsDir() {
 			continue
 		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
-		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext+compressSuffix); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
+		if info, ok := l.parseBackupName(f.Name(), prefix, ext); ok {
+			info.FileInfo = f
+			info.dir = dir
+			if l.SequentialNames {
+				info.timestamp = f.ModTime()
+			}
+			logFiles = append(logFiles, info)
 		}
 	}
-	sort.Sort(byFormatTime(logFiles))
+	if l.SequentialNames {
+		sortSequential(logFiles)
+	} else {
+		sort.Sort(byFormatTime(logFiles))
+	}
 
 	return logFiles, nil
 }
@@ -356,17 +1347,117 @@ func (l *Logger) prefixAndExt() (prefix, ext string) {
 	return prefix, ext
 }
 
-// timeFromName extracts the formatted time from the filename by stripping off
-// the prefix and extension. This prevents someone's filename from confusing time.parse.
-func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
+// parseBackupName parses the name of a backup of the log file with the given
+// prefix and extension, which may have been compressed and/or encrypted.
+func (l *Logger) parseBackupName(name, prefix, ext string) (logInfo, bool) {
+	if l.SequentialNames {
+		return l.parseSequentialName(name)
+	}
+	n, err := l.namer()
+	if err != nil {
+		return logInfo{}, false
+	}
+	// plain backups first, then compressed ones
+	variants := append([]compressedVariant{{ext, nil}}, l.compressedExts(ext)...)
+	for _, v := range variants {
+		for _, suffix := range []string{"", encryptSuffix, ageSuffix} {
+			e := v.ext + suffix
+			info := logInfo{compressed: v.codec != nil, codec: v.codec, encrypted: suffix != "",
+				age: suffix == ageSuffix}
+			if n != nil {
+				// the pattern has its own idea of where the extension goes
+				var ok bool
+				if info.timestamp, info.seq, ok = n.parse(name, prefix[:len(prefix)-1], e); ok {
+					return info, true
+				}
+			} else if info.timestamp, info.label, err = l.timeFromName(name, prefix, e); err == nil {
+				return info, true
+			}
+		}
+	}
+	return logInfo{}, false
+}
+
+// compressedVariant is an extension compressed backups may have, and the
+// codec it goes with.
+type compressedVariant struct {
+	ext   string
+	codec *codec
+}
+
+// compressedExts returns the extensions compressed backups of a log file
+// with extension ext may have: the one compressedExt gives first, then,
+// unless CompressSuffix is set, those of the other codecs, so that backups
+// compressed before a change of Compression are still recognized.
+func (l *Logger) compressedExts(ext string) []compressedVariant {
+	current := l.codec()
+	variants := []compressedVariant{{l.compressedExt(ext), current}}
+	if l.CompressSuffix != "" {
+		return variants
+	}
+	for _, c := range codecs {
+		if c != current {
+			variants = append(variants, compressedVariant{l.compressedExtWith(ext, c.suffix), c})
+		}
+	}
+	return variants
+}
+
+// compressedExt returns the extension of compressed backups for log files
+// with extension ext: ext followed by the compressed suffix, or the suffix
+// alone if CompressReplaceExt is set.
+func (l *Logger) compressedExt(ext string) string {
+	return l.compressedExtWith(ext, l.compressSuffix())
+}
+
+func (l *Logger) compressedExtWith(ext, suffix string) string {
+	if l.CompressReplaceExt {
+		return suffix
+	}
+	return ext + suffix
+}
+
+// compressSuffix returns the suffix of compressed backups: CompressSuffix if
+// set, or else that of the codec.
+func (l *Logger) compressSuffix() string {
+	if l.CompressSuffix == "" {
+		return l.codec().suffix
+	}
+	return l.CompressSuffix
+}
+
+// compressedName returns the name of the compressed copy of the backup name.
+func (l *Logger) compressedName(name string) string {
+	if l.SequentialNames {
+		return name + l.compressSuffix()
+	}
+	ext := filepath.Ext(l.Filename)
+	if n, err := l.namer(); err == nil && n != nil {
+		prefix, _ := l.prefixAndExt()
+		if t, seq, ok := n.parse(filepath.Base(name), prefix[:len(prefix)-1], ext); ok {
+			return filepath.Join(filepath.Dir(name), n.format(prefix[:len(prefix)-1], l.compressedExt(ext), t, seq))
+		}
+	}
+	return name[:len(name)-len(ext)] + l.compressedExt(ext)
+}
+
+// timeFromName extracts the formatted time, and the label given to Rotate if
+// any, from the filename by stripping off the prefix and extension. This
+// prevents someone's filename from confusing time.parse.
+func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, string, error) {
 	if !strings.HasPrefix(filename, prefix) {
-		return time.Time{}, errors.New("mismatched prefix")
+		return time.Time{}, "", errors.New("mismatched prefix")
 	}
 	if !strings.HasSuffix(filename, ext) {
-		return time.Time{}, errors.New("mismatched extension")
+		return time.Time{}, "", errors.New("mismatched extension")
 	}
 	ts := filename[len(prefix) : len(filename)-len(ext)]
-	return time.Parse(backupTimeFormat, ts)
+	var label string
+	if n := len(backupTimeFormat); len(ts) > n+1 && ts[n] == '-' && validLabel.MatchString(ts[n+1:]) {
+		ts, label = ts[:n], ts[n+1:]
+	}
+	t, err := time.Parse(backupTimeFormat, ts)
+	return t, label, err
 }
 
 // max returns the maximum size in bytes of log files before rolling.
@@ -377,29 +1468,53 @@ func (l *Logger) max() int64 {
 	return int64(l.MaxSize) * int64(megabyte)
 }
 
-// backupName creates a new filename from the given name, inserting a timestamp
-// between the filename and the extension, using the local time if requested
-// (otherwise UTC).
-func (l *Logger) backupName(dir, filename string, local bool) string {
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	ext := filepath.Ext(filename)
-	prefix := filename[:len(filename)-len(ext)]
+// backupName creates the path in dir of a new backup of the log file, named
+// after the schedule boundary the rotation is for, or else the current time,
+// local if requested (otherwise UTC), by
+// BackupNamePattern if set or else by inserting the time between the
+// filename and the extension.
+func (l *Logger) backupName(dir string, local bool) string {
 	t := currentTime()
+	if !l.rotateAt.IsZero() {
+		t = l.rotateAt.Local()
+	}
 	if !local {
 		t = t.UTC()
 	}
+	seq := l.freeSeq(dir, t)
+	name := l.withLabel(l.plainBackupName(t, seq), l.rotateLabel)
+	// without a sequence number, rotations within the same millisecond, as
+	// when splitting a write, are told apart by the time rather than have
+	// one backup replace the other
+	for seq == 0 {
+		if fi, err := os.Lstat(filepath.Join(dir, name)); err != nil || !fi.Mode().IsRegular() {
+			break
+		}
+		t = t.Add(time.Millisecond)
+		name = l.withLabel(l.plainBackupName(t, 0), l.rotateLabel)
+	}
 
+	l.lock.Lock()
 	l.startAt = time.Now()
-
-	timestamp := t.Format(backupTimeFormat)
-	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, timestamp, ext))
+	l.lock.Unlock()
+	return filepath.Join(dir, name)
 }
 
 // logInfo is a convenience struct to return the filename and its embedded
 // timestamp.
 type logInfo struct {
 	timestamp time.Time
+	seq       int
+	// label is what Rotate was given when making the backup.
+	label string
+	// dir is the directory the backup was found in, if listed.
+	dir        string
+	compressed bool
+	// codec is what a compressed backup's name says it is compressed with.
+	codec     *codec
+	encrypted bool
+	// age is set for backups encrypted with age rather than keys.
+	age bool
 	os.FileInfo
 }
 
@@ -407,6 +1522,9 @@ type logInfo struct {
 type byFormatTime []logInfo
 
 func (b byFormatTime) Less(i, j int) bool {
+	if b[i].timestamp.Equal(b[j].timestamp) {
+		return b[i].seq > b[j].seq
+	}
 	return b[i].timestamp.After(b[j].timestamp)
 }
 
@@ -1,10 +1,14 @@
 package rolling
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -466,8 +470,12 @@ func TestMaxAge(t *testing.T) {
 }
 
 func TestWrite(t *testing.T) {
-	writer, _ := NewWriter(
-		WithLogPath("E:\\public\\public_project\\files\\log"),
+	megabyte = 1024 * 1024
+	dir := makeTempDir("TestWrite", t)
+	defer os.RemoveAll(dir)
+
+	writer, err := NewWriter(
+		WithLogPath(dir),
 		WithFilename("all.log"),
 		WithMaxRemain(20), // 保留 10 个文件
 		WithMaxSize(10),   // 每个文件最大为 10M
@@ -477,20 +485,25 @@ func TestWrite(t *testing.T) {
 		WithTimeRolling(),
 		WithTimePattern("*/5 * * * * ?"),
 	)
-	_, _ = fmt.Fprintf(writer, "now :%s \n", time.Now().Format("2006-01-02T15-04-05.000"))
-	//wg := sync.WaitGroup{}
+	isNil(err, t)
+	_, err = fmt.Fprintf(writer, "now :%s \n", time.Now().Format("2006-01-02T15-04-05.000"))
+	isNil(err, t)
+	var wg sync.WaitGroup
 	for i := 0; i < 5; i++ {
-		go func(int) {
-			for {
-				_, err := fmt.Fprintf(writer, "now :%s \n", time.Now().Format("2006-01-02T15-04-05.000"))
-				if err != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if _, err := fmt.Fprintf(writer, "now :%s \n", time.Now().Format("2006-01-02T15-04-05.000")); err != nil {
+					t.Error(err)
 					return
 				}
 			}
-		}(i)
+		}()
 	}
-	select {}
-	//wg.Wait()
+	wg.Wait()
+	isNil(writer.Close(), t)
+	exists(filepath.Join(dir, "all.log"), t)
 }
 
 // makeTempDir creates a file with a semi-unique name in the OS temp directory.
@@ -556,3 +569,660 @@ func exists(path string, t testing.TB) {
 	_, err := os.Stat(path)
 	assertUp(err == nil, t, 1, "expected file to exist, but got error from os.Stat: %v", err)
 }
+
+func TestRotateFailureBackoff(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRotateFailureBackoff", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	filename := logFile(dir)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithRotateBackoff(time.Minute, time.Hour))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+
+	// a non-empty directory in the way of the backup makes the rename fail
+	blocker := backupFile(dir)
+	isNil(os.Mkdir(blocker, 0700), t)
+	isNil(ioutil.WriteFile(filepath.Join(blocker, "x"), []byte("x"), 0644), t)
+
+	b2 := []byte("0000000!")
+	n, err = l.Write(b2)
+	isNil(err, t)
+	equals(len(b2), n, t)
+	equals(1, l.rotateFailures, t)
+	existsWithContent(filename, append(b, b2...), t)
+
+	// the failure isn't returned to the writer, but is reported
+	select {
+	case err := <-l.Errors():
+		notNil(err, t)
+	default:
+		t.Fatal("rotation failure not reported on Errors")
+	}
+
+	// still cooling down, so no new attempt is made
+	b3 := []byte("1!")
+	n, err = l.Write(b3)
+	isNil(err, t)
+	equals(len(b3), n, t)
+	equals(1, l.rotateFailures, t)
+	existsWithContent(filename, append(append(b, b2...), b3...), t)
+
+	newFakeTime()
+
+	b4 := []byte("2222222!")
+	n, err = l.Write(b4)
+	isNil(err, t)
+	equals(len(b4), n, t)
+	equals(0, l.rotateFailures, t)
+	existsWithContent(filename, b4, t)
+	existsWithContent(backupFile(dir), append(append(b, b2...), b3...), t)
+}
+
+func TestMinFreeSpace(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	free := int64(100)
+	diskFree = func(string) (int64, error) { return free, nil }
+	defer func() { diskFree = freeSpace }()
+
+	dir := makeTempDir("TestMinFreeSpace", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	filename := logFile(dir)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMinFreeSpace(90))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+	existsWithContent(filename, b, t)
+
+	// only 5 bytes are left above the reserve
+	free = 95
+	b2 := []byte("0000000!")
+	n, err = l.Write(b2)
+	isNil(err, t)
+	equals(len(b2), n, t)
+	existsWithContent(filename, b, t)
+	equals(uint64(1), l.Stats().DroppedWrites, t)
+	equals(uint64(len(b2)), l.Stats().DroppedBytes, t)
+
+	free = 1000
+	n, err = l.Write(b2)
+	isNil(err, t)
+	equals(len(b2), n, t)
+	existsWithContent(filename, b2, t)
+	equals(uint64(1), l.Stats().DroppedWrites, t)
+}
+
+func TestMinFreeSpaceRetention(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMinFreeSpaceRetention", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	// the older backup takes up the space the reserve needs
+	older := filepath.Join(dir, "foobar-"+fakeTime().Add(-time.Hour).UTC().Format(backupTimeFormat)+".log")
+	isNil(ioutil.WriteFile(older, []byte("older"), 0644), t)
+	backup := backupFile(dir)
+	isNil(ioutil.WriteFile(backup, []byte("old"), 0644), t)
+	diskFree = func(string) (int64, error) {
+		if _, err := os.Stat(older); err == nil {
+			return 95, nil
+		}
+		return 1000, nil
+	}
+	defer func() { diskFree = freeSpace }()
+
+	filename := logFile(dir)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithMinFreeSpace(90), WithMaxRemain(1))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	// retention makes room before the write, which goes through
+	b := []byte("0000000!")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+	notExist(older, t)
+	exists(backup, t)
+	existsWithContent(filename, b, t)
+	equals(uint64(0), l.Stats().DroppedWrites, t)
+
+	// when it can't, the write is dropped
+	diskFree = func(string) (int64, error) { return 95, nil }
+	newFakeTime()
+	n, err = l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+	existsWithContent(filename, b, t)
+	exists(backup, t)
+	equals(uint64(1), l.Stats().DroppedWrites, t)
+}
+
+func TestSizeSinceOpen(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSizeSinceOpen", t)
+	filename := logFile(dir)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	start := []byte("123456789")
+	err := ioutil.WriteFile(filename, start, 0600)
+	isNil(err, t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithSizeSinceOpen())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	newFakeTime()
+
+	// only our own writes count toward MaxSize, so this doesn't rotate
+	b := []byte("fo0o!")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+	existsWithContent(filename, append(start, b...), t)
+	fileCount(dir, 1, t)
+
+	b2 := []byte("bar!!!")
+	n, err = l.Write(b2)
+	isNil(err, t)
+	equals(len(b2), n, t)
+	existsWithContent(filename, b2, t)
+	existsWithContent(backupFile(dir), append(start, b...), t)
+	fileCount(dir, 2, t)
+}
+
+func TestMaxTotalSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMaxTotalSize", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(0),
+		WithMaxTotalSize(12), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	_, err = l.Write([]byte("00000000"))
+	isNil(err, t)
+	var backups []string
+	for _, b := range []string{"11111111", "22222222"} {
+		newFakeTime()
+		_, err := l.Write([]byte(b))
+		isNil(err, t)
+		backups = append(backups, backupFile(dir))
+	}
+	// two 8 byte backups don't fit
+	notExist(backups[0], t)
+	existsWithContent(backups[1], []byte("11111111"), t)
+	fileCount(dir, 2, t)
+
+	// a held backup takes up space but can't be removed, so newer ones go
+	info, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	isNil(l.Hold(info[0]), t)
+	newFakeTime()
+	_, err = l.Write([]byte("33333333"))
+	isNil(err, t)
+	existsWithContent(backups[1], []byte("11111111"), t)
+	notExist(backupFile(dir), t)
+	fileCount(dir, 3, t)
+}
+
+func TestWriteString(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWriteString", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10))
+	isNil(err, t)
+	defer l.Close()
+
+	n, err := io.WriteString(l, "boo!")
+	isNil(err, t)
+	equals(4, n, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+
+	// rotates like Write
+	newFakeTime()
+	n, err = l.WriteString("foo!bar!")
+	isNil(err, t)
+	equals(8, n, t)
+	existsWithContent(logFile(dir), []byte("foo!bar!"), t)
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+}
+
+func TestOnRotate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestOnRotate", t)
+	defer os.RemoveAll(dir)
+
+	var rotations [][2]string
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithOnRotate(func(oldPath, newPath string) {
+			rotations = append(rotations, [2]string{oldPath, newPath})
+		}))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals(0, len(rotations), t)
+
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	equals(1, len(rotations), t)
+	equals(logFile(dir), rotations[0][0], t)
+	equals(backupFile(dir), rotations[0][1], t)
+	existsWithContent(rotations[0][1], []byte("boo!"), t)
+}
+
+func TestOnRemove(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestOnRemove", t)
+	defer os.RemoveAll(dir)
+
+	removed := make(map[string]PruneReason)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(1),
+		WithSynchronousMill(), WithOnRemove(func(path string, reason PruneReason) {
+			removed[path] = reason
+		}))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := backupFile(dir)
+	equals(0, len(removed), t)
+
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	notExist(first, t)
+	equals(1, len(removed), t)
+	equals(PruneMaxRemain, removed[first], t)
+	equals("max_remain", removed[first].String(), t)
+}
+
+func TestMaxAgeDuration(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMaxAgeDuration", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithMaxAgeDuration(6*time.Hour), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := backupFile(dir)
+	existsWithContent(first, []byte("boo!"), t)
+
+	// newFakeTime moves two days ahead, well past the 6 hours, while the
+	// default MaxAge of 30 days would have kept the backup
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	notExist(first, t)
+	existsWithContent(backupFile(dir), []byte("foo!bar!"), t)
+}
+
+func TestAtomicRecords(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestAtomicRecords", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithAtomicRecords())
+	isNil(err, t)
+	defer l.Close()
+
+	// a line written in pieces isn't split by the rotation due meanwhile
+	_, err = l.Write([]byte("boo! "))
+	isNil(err, t)
+	_, err = l.Write([]byte("foo!bar!\n"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("boo! foo!bar!\n"), t)
+	fileCount(dir, 1, t)
+
+	// but done before the next one
+	newFakeTime()
+	_, err = l.Write([]byte("baz!\n"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("boo! foo!bar!\n"), t)
+	existsWithContent(logFile(dir), []byte("baz!\n"), t)
+
+	// an oversized record gets a file of its own rather than an error
+	newFakeTime()
+	big := []byte("0123456789abcdef\n")
+	_, err = l.Write(big)
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("baz!\n"), t)
+	existsWithContent(logFile(dir), big, t)
+	fileCount(dir, 3, t)
+}
+
+func TestTee(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestTee", t)
+	defer os.RemoveAll(dir)
+
+	var tee bytes.Buffer
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithTee(&tee))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	existsWithContent(backupFile(dir), []byte("boo!"), t)
+	existsWithContent(logFile(dir), []byte("foo!bar!"), t)
+	equals("boo!foo!bar!", tee.String(), t)
+
+	// a write refused as oversized isn't copied either
+	_, err = l.Write(make([]byte, 11))
+	notNil(err, t)
+	equals("boo!foo!bar!", tee.String(), t)
+}
+
+func TestFallback(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestFallback", t)
+	defer os.RemoveAll(dir)
+
+	var fallback bytes.Buffer
+	var errs []error
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithFallback(&fallback),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// the file can't be written to anymore
+	isNil(l.file.Close(), t)
+	n, err := l.Write([]byte("foo!"))
+	isNil(err, t)
+	equals(4, n, t)
+	equals("foo!", fallback.String(), t)
+	equals(1, len(errs), t)
+	s := l.Stats()
+	equals(uint64(1), s.FallbackWrites, t)
+	equals(uint64(1), s.WriteErrors, t)
+	equals(uint64(4), s.BytesWritten, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+}
+
+func TestReopenOnMove(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestReopenOnMove", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithReopenOnMove())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	moved := filepath.Join(dir, "moved.log")
+	isNil(os.Rename(logFile(dir), moved), t)
+
+	// noticed on the first write once the check is due
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	existsWithContent(moved, []byte("boo!foo!"), t)
+	newFakeTime()
+	_, err = l.Write([]byte("bar!"))
+	isNil(err, t)
+	existsWithContent(moved, []byte("boo!foo!"), t)
+	existsWithContent(logFile(dir), []byte("bar!"), t)
+
+	// and the same goes for a deleted file
+	isNil(os.Remove(logFile(dir)), t)
+	newFakeTime()
+	_, err = l.Write([]byte("baz!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("baz!"), t)
+}
+
+func TestFileAndDirMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on windows")
+	}
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestFileAndDirMode", t)
+	defer os.RemoveAll(dir)
+	logDir := filepath.Join(dir, "sub")
+
+	l, err := NewWriter(WithLogPath(logDir), WithFilename(logName()), WithMaxSize(10),
+		WithFileMode(0600), WithDirMode(0700))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	info, err := os.Stat(logDir)
+	isNil(err, t)
+	equals(os.FileMode(0700), info.Mode().Perm(), t)
+	info, err = os.Stat(logFile(logDir))
+	isNil(err, t)
+	equals(os.FileMode(0600), info.Mode().Perm(), t)
+
+	// the mode applies to files after a rotation too, whatever the old one's
+	isNil(os.Chmod(logFile(logDir), 0640), t)
+	newFakeTime()
+	_, err = l.Write([]byte("foobarbaz!"))
+	isNil(err, t)
+	info, err = os.Stat(logFile(logDir))
+	isNil(err, t)
+	equals(os.FileMode(0600), info.Mode().Perm(), t)
+}
+
+func TestFileFlags(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestFileFlags", t)
+	defer os.RemoveAll(dir)
+
+	isNil(ioutil.WriteFile(logFile(dir), []byte("old!"), 0644), t)
+	isNil(ioutil.WriteFile(filepath.Join(dir, "other.log"), []byte("old!"), 0644), t)
+
+	// truncating for one Logger doesn't affect another in the same process
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100),
+		WithFileFlags(os.O_WRONLY|os.O_CREATE|os.O_TRUNC))
+	isNil(err, t)
+	defer l.Close()
+	other, err := NewWriter(WithLogPath(dir), WithFilename("other.log"), WithMaxSize(100))
+	isNil(err, t)
+	defer other.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	_, err = other.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+	existsWithContent(filepath.Join(dir, "other.log"), []byte("old!boo!"), t)
+}
+
+func TestLowDiskWatermark(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	free := int64(1000)
+	diskFree = func(string) (int64, error) { return free, nil }
+	defer func() { diskFree = freeSpace }()
+	dir := makeTempDir("TestLowDiskWatermark", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithMaxRemain(10), WithLowDiskWatermark(100), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+
+	var backups []string
+	for i := 0; i < 3; i++ {
+		_, err = l.Write([]byte("foobarbaz!"))
+		isNil(err, t)
+		newFakeTime()
+		backups = append(backups, backupFile(dir))
+	}
+	_, err = l.Write([]byte("foobarbaz!"))
+	isNil(err, t)
+	fileCount(dir, 4, t)
+
+	// the oldest backups go until the watermark is met again, though
+	// MaxRemain would keep them
+	free = 85
+	isNil(l.millRunOnce(), t)
+	notExist(backups[0], t)
+	notExist(backups[1], t)
+	exists(backups[2], t)
+	equals(uint64(2), l.Stats().Pruned["low_disk"], t)
+}
+
+func TestCloseRunsQueuedMill(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCloseRunsQueuedMill", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithCompress())
+	isNil(err, t)
+
+	// hold the pass up until Close
+	l.millMu.Lock()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foobarbaz!"))
+	isNil(err, t)
+	l.millMu.Unlock()
+
+	isNil(l.Close(), t)
+	notExist(backupFile(dir), t)
+	exists(backupFile(dir)+compressSuffix, t)
+	defaultMill.mu.Lock()
+	queued := defaultMill.queued[l]
+	defaultMill.mu.Unlock()
+	assert(!queued, t, "mill pass still queued after Close")
+}
+
+func TestSynchronousIO(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSynchronousIO", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithSynchronousIO())
+	isNil(err, t)
+	defer l.Close()
+	equals(oDsync, l.fileFlag()&oDsync, t)
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+}
+
+func TestCurrentFile(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestCurrentFile", t)
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	isNil(err, t)
+	isNil(os.Chdir(dir), t)
+	defer os.Chdir(wd)
+
+	l, err := NewWriter(WithLogPath("logs"), WithFilename(logName()), WithArchiveDir("archive"))
+	isNil(err, t)
+	defer l.Close()
+	equals(filepath.Join(dir, "logs", logName()), l.CurrentFile(), t)
+	equals(filepath.Join(dir, "archive"), l.BackupDir(), t)
+
+	// the file stays where it was opened
+	isNil(os.Chdir(wd), t)
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(filepath.Join(dir, "logs", logName()), []byte("boo!"), t)
+	equals(filepath.Join(dir, "logs", logName()), l.CurrentFile(), t)
+}
@@ -0,0 +1,214 @@
+package rolling
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultMaxOpen is how many files a Router keeps open when not told.
+const defaultMaxOpen = 64
+
+// Router writes to one Logger per key, such as a tenant ID, each with its own
+// file and backups in a subdirectory of LogPath named after the key, and of
+// ArchiveDir if set. Loggers
+// are created on the first write for their key and share the Router's
+// configuration. To bound the number of open files, only the most recently
+// used are kept open; the others are closed, and reopened when written to
+// again.
+type Router struct {
+	cfg     Config
+	options []Option
+	maxOpen int
+
+	mu      sync.Mutex
+	loggers map[string]*list.Element
+	lru     *list.List // of *route, most recently used first
+	// closing holds the keys of the evicted Loggers being closed, each with
+	// a channel closed once it's done, so that a key isn't reopened before.
+	closing map[string]chan struct{}
+	closed  bool
+}
+
+// route is a Router's Logger for a key. Writes hold rw for reading, so that
+// evicting the Logger waits for them before closing it.
+type route struct {
+	key    string
+	l      *Logger
+	rw     sync.RWMutex
+	closed bool
+}
+
+type routeKey struct{}
+
+// ContextWithKey returns a copy of ctx carrying the key Router.WriteContext
+// routes by.
+func ContextWithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routeKey{}, key)
+}
+
+// NewRouter returns a Router creating Loggers from cfg and options, as New
+// does, keeping at most maxOpen of them open at once, or 64 if maxOpen isn't
+// positive. Nothing is opened until the first write.
+func NewRouter(cfg Config, maxOpen int, options ...Option) *Router {
+	if maxOpen <= 0 {
+		maxOpen = defaultMaxOpen
+	}
+	if cfg.LogPath == "" {
+		cfg.LogPath = DefaultConfig().LogPath
+	}
+	return &Router{
+		cfg:     cfg,
+		options: options,
+		maxOpen: maxOpen,
+		loggers: make(map[string]*list.Element),
+		lru:     list.New(),
+		closing: make(map[string]chan struct{}),
+	}
+}
+
+// WriteKey writes p to the Logger for key, opening it if needed. Keys are
+// used as directory names, so they can't be empty, "." or "..", or contain
+// path separators.
+func (r *Router) WriteKey(key string, p []byte) (int, error) {
+	for {
+		rt, err := r.route(key)
+		if err != nil {
+			return 0, err
+		}
+		rt.rw.RLock()
+		if rt.closed {
+			// evicted since it was looked up
+			rt.rw.RUnlock()
+			continue
+		}
+		n, err := rt.l.Write(p)
+		rt.rw.RUnlock()
+		return n, err
+	}
+}
+
+// WriteContext writes p to the Logger for the key carried by ctx, as set by
+// ContextWithKey.
+func (r *Router) WriteContext(ctx context.Context, p []byte) (int, error) {
+	key, ok := ctx.Value(routeKey{}).(string)
+	if !ok {
+		return 0, errors.New("context carries no routing key")
+	}
+	return r.WriteKey(key, p)
+}
+
+// route returns the open route for key, creating it and evicting the least
+// recently used ones if needed. Evicted Loggers are closed once r.mu is
+// released, so that a slow one doesn't hold up the other keys.
+func (r *Router) route(key string) (*route, error) {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return nil, fmt.Errorf("invalid routing key %q", key)
+	}
+
+	for {
+		r.mu.Lock()
+		if done, ok := r.closing[key]; ok {
+			// evicted, and still being closed
+			r.mu.Unlock()
+			<-done
+			continue
+		}
+		rt, evicted, err := r.openRoute(key)
+		for _, old := range evicted {
+			r.closing[old.key] = make(chan struct{})
+		}
+		r.mu.Unlock()
+
+		for _, old := range evicted {
+			_ = old.close()
+			r.mu.Lock()
+			close(r.closing[old.key])
+			delete(r.closing, old.key)
+			r.mu.Unlock()
+		}
+		return rt, err
+	}
+}
+
+// openRoute returns the open route for key, creating it if needed, along
+// with the least recently used routes it evicted, which the caller must
+// close. r.mu must be held.
+func (r *Router) openRoute(key string) (*route, []*route, error) {
+	if r.closed {
+		return nil, nil, errClosed
+	}
+	if e, ok := r.loggers[key]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*route), nil, nil
+	}
+
+	options := append(r.options[:len(r.options):len(r.options)], WithLogPath(filepath.Join(r.cfg.LogPath, key)),
+		func(logger *Logger) {
+			// the backups and manifest of each key are archived apart too
+			if logger.ArchiveDir != "" {
+				logger.ArchiveDir = filepath.Join(logger.ArchiveDir, key)
+			}
+		}, withExpvarSuffix(key))
+	l, err := New(r.cfg, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	rt := &route{key: key, l: l}
+	r.loggers[key] = r.lru.PushFront(rt)
+	var evicted []*route
+	for r.lru.Len() > r.maxOpen {
+		old := r.lru.Remove(r.lru.Back()).(*route)
+		delete(r.loggers, old.key)
+		evicted = append(evicted, old)
+	}
+	return rt, evicted, nil
+}
+
+func (rt *route) close() error {
+	rt.rw.Lock()
+	defer rt.rw.Unlock()
+	rt.closed = true
+	return rt.l.Close()
+}
+
+// Open returns the keys of the Loggers currently open, most recently used
+// first.
+func (r *Router) Open() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]string, 0, r.lru.Len())
+	for e := r.lru.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*route).key)
+	}
+	return keys
+}
+
+// Close closes all open Loggers, and waits for those evicted to be closed
+// too. Writes fail from then on.
+func (r *Router) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	var err error
+	for e := r.lru.Front(); e != nil; e = e.Next() {
+		if errClose := e.Value.(*route).close(); err == nil {
+			err = errClose
+		}
+	}
+	r.lru.Init()
+	r.loggers = make(map[string]*list.Element)
+	pending := make([]chan struct{}, 0, len(r.closing))
+	for _, done := range r.closing {
+		pending = append(pending, done)
+	}
+	r.mu.Unlock()
+
+	for _, done := range pending {
+		<-done
+	}
+	return err
+}
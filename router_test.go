@@ -0,0 +1,134 @@
+package rolling
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRouter(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestRouter", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	cfg := DefaultConfig()
+	cfg.LogPath = dir
+	cfg.Filename = logName()
+	r := NewRouter(cfg, 2, WithMaxSize(10000))
+	defer func() {
+		err := r.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		_, err := r.WriteKey(key, []byte(key+"\n"))
+		isNil(err, t)
+	}
+	equals([]string{"c", "a"}, r.Open(), t)
+
+	// b was evicted and is reopened, appending to its file
+	_, err := r.WriteContext(ContextWithKey(context.Background(), "b"), []byte("b\n"))
+	isNil(err, t)
+	equals([]string{"b", "c"}, r.Open(), t)
+
+	existsWithContent(filepath.Join(dir, "a", logName()), []byte("a\na\n"), t)
+	existsWithContent(filepath.Join(dir, "b", logName()), []byte("b\nb\n"), t)
+	existsWithContent(filepath.Join(dir, "c", logName()), []byte("c\n"), t)
+
+	_, err = r.WriteKey("../x", []byte("x"))
+	notNil(err, t)
+	_, err = r.WriteContext(context.Background(), []byte("x"))
+	notNil(err, t)
+
+	isNil(r.Close(), t)
+	_, err = r.WriteKey("a", []byte("a"))
+	notNil(err, t)
+}
+
+func TestRouterArchiveDir(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestRouterArchiveDir", t)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	cfg := DefaultConfig()
+	cfg.LogPath = dir
+	cfg.Filename = logName()
+	cfg.ArchiveDir = archive
+	r := NewRouter(cfg, 2, WithSynchronousMill())
+	defer r.Close()
+
+	for _, key := range []string{"a", "b"} {
+		_, err := r.WriteKey(key, []byte(key+"\n"))
+		isNil(err, t)
+	}
+	// both tenants rotate at the same time, into backups of the same name
+	for _, key := range []string{"a", "b"} {
+		e := r.loggers[key]
+		isNil(e.Value.(*route).l.Rotate(""), t)
+	}
+
+	name := filepath.Base(backupFile(dir))
+	existsWithContent(filepath.Join(archive, "a", name), []byte("a\n"), t)
+	existsWithContent(filepath.Join(archive, "b", name), []byte("b\n"), t)
+}
+
+func TestRouterSlowEviction(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestRouterSlowEviction", t)
+	defer os.RemoveAll(dir)
+
+	cfg := DefaultConfig()
+	cfg.LogPath = dir
+	cfg.Filename = logName()
+	r := NewRouter(cfg, 1)
+	defer r.Close()
+
+	_, err := r.WriteKey("a", []byte("a\n"))
+	isNil(err, t)
+	// a write still under way holds up closing a once evicted
+	a := r.loggers["a"].Value.(*route)
+	a.rw.RLock()
+	evicted := make(chan error)
+	go func() {
+		_, err := r.WriteKey("b", []byte("b\n"))
+		evicted <- err
+	}()
+
+	// which doesn't keep other keys from being routed
+	done := make(chan error)
+	go func() {
+		for {
+			r.mu.Lock()
+			_, closing := r.closing["a"]
+			r.mu.Unlock()
+			if closing {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		_, err := r.WriteKey("c", []byte("c\n"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		isNil(err, t)
+	case <-time.After(5 * time.Second):
+		t.Fatal("routing blocked by a Logger being closed")
+	}
+
+	a.rw.RUnlock()
+	isNil(<-evicted, t)
+	_, err = r.WriteKey("a", []byte("a\n"))
+	isNil(err, t)
+	existsWithContent(filepath.Join(dir, "a", logName()), []byte("a\na\n"), t)
+}
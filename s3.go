@@ -0,0 +1,58 @@
+package rolling
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// S3Client is the one S3 operation the S3 archiver needs. It's kept this
+// small so that any SDK can be adapted to it without this package depending
+// on one; with aws-sdk-go-v2 for instance:
+//
+//	type s3Uploader struct{ client *s3.Client }
+//
+//	func (u s3Uploader) PutObject(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) error {
+//		_, err := u.client.PutObject(ctx, &s3.PutObjectInput{
+//			Bucket:        aws.String(bucket),
+//			Key:           aws.String(key),
+//			Body:          body,
+//			ContentLength: size,
+//		})
+//		return err
+//	}
+//
+// body may be read more than once by the SDK's own retries, hence the
+// ReadSeeker.
+type S3Client interface {
+	PutObject(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) error
+}
+
+// s3Archiver uploads backups to bucket, under prefix.
+type s3Archiver struct {
+	client S3Client
+	bucket string
+	prefix string
+}
+
+func (a *s3Archiver) Archive(ctx context.Context, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	key := path.Join(a.prefix, filepath.Base(localPath))
+	return retry(ctx, uploadAttempts, uploadBackoff, func() error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return a.client.PutObject(ctx, a.bucket, key, f, fi.Size())
+	})
+}
@@ -0,0 +1,116 @@
+package rolling
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeS3 is an S3Client keeping objects in memory, failing while down.
+type fakeS3 struct {
+	objects map[string][]byte
+	down    bool
+}
+
+func (s *fakeS3) PutObject(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) error {
+	if s.down {
+		return errors.New("service unavailable")
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) != size {
+		return errors.New("short body")
+	}
+	s.objects[bucket+"/"+key] = b
+	return nil
+}
+
+func TestS3Archive(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestS3Archive", t)
+	defer os.RemoveAll(dir)
+
+	s3 := &fakeS3{objects: make(map[string][]byte), down: true}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithSynchronousMill(),
+		WithS3Archive(s3, "bucket", "logs/app"), WithKeepUploaded(1))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := backupFile(dir)
+
+	// the upload failed, and the backup is kept
+	equals(0, len(s3.objects), t)
+	equals(uint64(1), l.Stats().UploadErrors, t)
+	existsWithContent(first, []byte("boo!"), t)
+
+	// and retried on the next pass, after which only the newest uploaded
+	// backup is kept
+	s3.down = false
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	second := backupFile(dir)
+	equals(2, len(s3.objects), t)
+	equals([]byte("boo!"), s3.objects["bucket/logs/app/"+filepath.Base(first)], t)
+	equals([]byte("foo!bar!"), s3.objects["bucket/logs/app/"+filepath.Base(second)], t)
+	notExist(first, t)
+	existsWithContent(second, []byte("foo!bar!"), t)
+	equals(uint64(1), l.Stats().Pruned["uploaded"], t)
+
+	// uploads are remembered
+	m, err := l.loadManifest()
+	isNil(err, t)
+	equals(1, len(m.Uploaded), t)
+	isNil(l.millRunOnce(), t)
+	equals(uint64(2), l.Stats().Uploads, t)
+}
+
+func TestDeleteAfterUpload(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDeleteAfterUpload", t)
+	defer os.RemoveAll(dir)
+
+	s3 := &fakeS3{objects: make(map[string][]byte), down: true}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(1),
+		WithSynchronousMill(), WithS3Archive(s3, "bucket", "logs"), WithDeleteAfterUpload())
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := backupFile(dir)
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	second := backupFile(dir)
+
+	// MaxRemain doesn't remove backups which weren't uploaded
+	existsWithContent(first, []byte("boo!"), t)
+	existsWithContent(second, []byte("foo!bar!"), t)
+	equals(uint64(0), l.Stats().Pruned["max_remain"], t)
+
+	// once they are, retention applies again
+	s3.down = false
+	isNil(l.millRunOnce(), t)
+	equals(2, len(s3.objects), t)
+	isNil(l.millRunOnce(), t)
+	notExist(first, t)
+	existsWithContent(second, []byte("foo!bar!"), t)
+	equals(uint64(1), l.Stats().Pruned["max_remain"], t)
+}
@@ -0,0 +1,81 @@
+package rolling
+
+import (
+	"fmt"
+	"time"
+)
+
+// zonedSchedule computes the activation times of a schedule in loc rather
+// than in the zone of the time it's given.
+type zonedSchedule struct {
+	timeSchedule
+	loc *time.Location
+}
+
+func (s zonedSchedule) Next(t time.Time) time.Time {
+	return s.timeSchedule.Next(t.In(s.loc))
+}
+
+// cronSchedule parses TimePattern, to be evaluated in the Logger's time zone
+// if one is set.
+func (l *Logger) cronSchedule() (timeSchedule, error) {
+	schedule, err := parseCron(l.TimePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time pattern %q: %v", l.TimePattern, err)
+	}
+	if l.location != nil {
+		return zonedSchedule{schedule, l.location}, nil
+	}
+	return schedule, nil
+}
+
+// startSchedule starts time rolling: the schedule is followed by the
+// Logger's Scheduler if it has one, by the default scheduler otherwise, or,
+// in synchronous mode, checked on Write. A Scheduler doesn't say which
+// activation it is running, so the time it runs at is taken as the boundary.
+func (l *Logger) startSchedule() error {
+	if l.scheduler != nil {
+		stop, err := l.scheduler.Schedule(l.TimePattern, func() { go l.rotateAtBoundary(currentTime()) })
+		if err != nil {
+			return err
+		}
+		l.stopSched = stop
+		return nil
+	}
+	schedule, err := l.cronSchedule()
+	if err != nil {
+		return err
+	}
+	if l.SynchronousMill {
+		l.schedule = schedule
+		l.nextRotateAt = schedule.Next(currentTime())
+		return nil
+	}
+	l.stopSched = defaultScheduler.add(schedule, func(at time.Time) { go l.rotateAtBoundary(at) })
+	return nil
+}
+
+// rotateAtBoundary rotates the file for time rolling, naming the backup
+// after boundary. It runs on a goroutine of its own, so that a write holding
+// l.mu never holds up the scheduler. While nothing has been written since
+// the file was opened, or a line is under way with AtomicRecords, the
+// rotation is left to the next write, so that idle Loggers don't make empty
+// backups.
+func (l *Logger) rotateAtBoundary(boundary time.Time) {
+	l.lockWrite()
+	defer l.unlockWrite()
+	if l.file == nil {
+		return
+	}
+	l.pendingRotate = true
+	l.rotateAt = boundary
+	if l.written == 0 || (l.AtomicRecords && l.midLine) {
+		return
+	}
+	l.reportError(l.tryRotate())
+}
+
+// dailyPattern returns the cron expression for every day at hour:min.
+func dailyPattern(hour, min int) string {
+	return fmt.Sprintf("0 %d %d * * *", min, hour)
+}
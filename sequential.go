@@ -0,0 +1,85 @@
+package rolling
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseSequentialName parses the name of a backup with SequentialNames:
+// the log file's name, a dot and the backup's index, possibly followed by
+// the compressed and encrypted suffixes.
+func (l *Logger) parseSequentialName(name string) (logInfo, bool) {
+	rest := strings.TrimPrefix(name, l.Filename+".")
+	if len(rest) == len(name) {
+		return logInfo{}, false
+	}
+	i := 0
+	for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+		i++
+	}
+	index, err := strconv.Atoi(rest[:i])
+	if err != nil || index < 1 {
+		return logInfo{}, false
+	}
+
+	info := logInfo{seq: index}
+	suffix := rest[i:]
+	if strings.HasSuffix(suffix, encryptSuffix) {
+		info.encrypted = true
+		suffix = suffix[:len(suffix)-len(encryptSuffix)]
+	} else if strings.HasSuffix(suffix, ageSuffix) {
+		info.encrypted, info.age = true, true
+		suffix = suffix[:len(suffix)-len(ageSuffix)]
+	}
+	if suffix == "" {
+		return info, true
+	}
+	// with an empty ext the variants are just the suffixes
+	for _, v := range l.compressedExts("") {
+		if suffix == v.ext {
+			info.compressed, info.codec = true, v.codec
+			return info, true
+		}
+	}
+	return logInfo{}, false
+}
+
+// sortSequential sorts backups with SequentialNames newest, that is lowest
+// index, first.
+func sortSequential(files []logInfo) {
+	sort.SliceStable(files, func(i, j int) bool { return files[i].seq < files[j].seq })
+}
+
+// shiftBackups renames every backup with SequentialNames to the index n
+// higher, from the oldest down so nothing is overwritten, keeping their
+// suffixes. l.millMu must be held.
+func (l *Logger) shiftBackups(n int) error {
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		suffix := strings.TrimPrefix(f.Name(), l.Filename+"."+strconv.Itoa(f.seq))
+		newName := l.Filename + "." + strconv.Itoa(f.seq+n) + suffix
+		if err := l.rename(filepath.Join(l.LogPath, f.Name()), filepath.Join(l.LogPath, newName)); err != nil {
+			return fmt.Errorf("can't shift backup %s: %v", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// rotateSequential moves the log file at name to index 1 after shifting the
+// other backups up, and returns its new path.
+func (l *Logger) rotateSequential(name string) (string, error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	if err := l.shiftBackups(1); err != nil {
+		return "", err
+	}
+	backup := filepath.Join(l.LogPath, l.plainBackupName(currentTime(), 1))
+	return backup, l.rename(name, backup)
+}
@@ -0,0 +1,63 @@
+package rolling
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSequentialNames(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSequentialNames", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename("app.log"), WithMaxSize(10), WithMaxRemain(2), WithMaxAge(0),
+		WithSequentialNames(), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	backup := func(i string) string { return filepath.Join(dir, "app.log."+i) }
+	for _, b := range []string{"00000000", "11111111", "22222222"} {
+		_, err := l.Write([]byte(b))
+		isNil(err, t)
+	}
+	existsWithContent(backup("1"), []byte("11111111"), t)
+	existsWithContent(backup("2"), []byte("00000000"), t)
+
+	_, err = l.Write([]byte("33333333"))
+	isNil(err, t)
+	existsWithContent(backup("1"), []byte("22222222"), t)
+	existsWithContent(backup("2"), []byte("11111111"), t)
+	notExist(backup("3"), t)
+
+	backups, err := NewInspector(dir, "app.log", WithSequentialNames()).ListBackups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(backup("1"), backups[0].Path, t)
+
+	// compressed backups keep their suffix as they move up
+	l.Compress = true
+	_, err = l.Write([]byte("44444444"))
+	isNil(err, t)
+	exists(backup("1")+compressSuffix, t)
+	exists(backup("2")+compressSuffix, t)
+	_, err = l.Write([]byte("55555555"))
+	isNil(err, t)
+	exists(backup("2")+compressSuffix, t)
+	fileCount(dir, 3, t)
+
+	notNil(l.Hold(backups[0]), t)
+	_, err = NewWriter(WithLogPath(dir), WithFilename("bad.log"), WithSequentialNames(), WithSplitOversized(2))
+	notNil(err, t)
+}
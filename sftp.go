@@ -0,0 +1,145 @@
+package rolling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SFTPClient is what the SFTP archiver needs from an SFTP session. It is
+// opened by the dial function given to WithSFTPArchive for every backup and
+// closed once the backup is uploaded, so connecting to the host and
+// authenticating, with a key or otherwise, are up to the caller. With
+// golang.org/x/crypto/ssh and github.com/pkg/sftp:
+//
+//	signer, err := ssh.ParsePrivateKey(key)
+//	config := &ssh.ClientConfig{
+//		User:            "logs",
+//		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+//		HostKeyCallback: ssh.FixedHostKey(hostKey),
+//	}
+//	dial := func(ctx context.Context) (rolling.SFTPClient, error) {
+//		conn, err := ssh.Dial("tcp", "archive.example.com:22", config)
+//		if err != nil {
+//			return nil, err
+//		}
+//		client, err := sftp.NewClient(conn)
+//		if err != nil {
+//			conn.Close()
+//			return nil, err
+//		}
+//		return sftpSession{client, conn}, nil
+//	}
+//
+//	type sftpSession struct {
+//		*sftp.Client
+//		conn *ssh.Client
+//	}
+//
+//	func (s sftpSession) Create(path string) (io.WriteCloser, error) { return s.Client.Create(path) }
+//	func (s sftpSession) Rename(oldpath, newpath string) error    { return s.PosixRename(oldpath, newpath) }
+//	func (s sftpSession) Close() error                             { s.Client.Close(); return s.conn.Close() }
+type SFTPClient interface {
+	MkdirAll(dir string) error
+	Create(path string) (io.WriteCloser, error)
+	// Rename replaces newpath if it exists, as posix-rename@openssh.com does.
+	Rename(oldpath, newpath string) error
+	Remove(path string) error
+	Close() error
+}
+
+// sftpPartSuffix is appended to the name of backups while they are being
+// uploaded, so that an interrupted upload is never taken for a whole one.
+const sftpPartSuffix = ".part"
+
+// sftpDir is what the remote directory template of the SFTP archiver is
+// executed with: the time of the backup, also split into zero-padded Year,
+// Month and Day for the common case.
+type sftpDir struct {
+	Time             time.Time
+	Year, Month, Day string
+}
+
+// sftpArchiver uploads backups over SFTP into the directory given by dir for
+// the backup's time.
+type sftpArchiver struct {
+	l    *Logger
+	dial func(ctx context.Context) (SFTPClient, error)
+	dir  *template.Template
+	err  error // from parsing dir, reported by open
+}
+
+func (a *sftpArchiver) Archive(ctx context.Context, localPath string) error {
+	dir, err := a.remoteDir(localPath)
+	if err != nil {
+		return err
+	}
+	remote := path.Join(dir, filepath.Base(localPath))
+	return retry(ctx, uploadAttempts, uploadBackoff, func() error {
+		client, err := a.dial(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if err := client.MkdirAll(dir); err != nil {
+			return err
+		}
+		if err := sftpPut(client, localPath, remote+sftpPartSuffix); err != nil {
+			client.Remove(remote + sftpPartSuffix)
+			return err
+		}
+		return client.Rename(remote+sftpPartSuffix, remote)
+	})
+}
+
+// remoteDir returns the remote directory of the backup at localPath, dated
+// by its name, or by its modification time if the name has none.
+func (a *sftpArchiver) remoteDir(localPath string) (string, error) {
+	prefix, ext := a.l.prefixAndExt()
+	// checksum sidecars go with their backup
+	name := strings.TrimSuffix(filepath.Base(localPath), checksumSuffix)
+	info, ok := a.l.parseBackupName(name, prefix, ext)
+	t := info.timestamp
+	if !ok || t.IsZero() {
+		fi, err := os.Stat(localPath)
+		if err != nil {
+			return "", err
+		}
+		t = fi.ModTime()
+	}
+	var b strings.Builder
+	err := a.dir.Execute(&b, sftpDir{
+		Time:  t,
+		Year:  t.Format("2006"),
+		Month: t.Format("01"),
+		Day:   t.Format("02"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't expand SFTP directory: %v", err)
+	}
+	return b.String(), nil
+}
+
+// sftpPut copies the local file at localPath to remote.
+func sftpPut(client SFTPClient, localPath, remote string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := client.Create(remote)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,107 @@
+package rolling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSFTP is an SFTPClient keeping files in memory.
+type fakeSFTP struct {
+	dirs  map[string]bool
+	files map[string][]byte
+}
+
+type fakeSFTPFile struct {
+	bytes.Buffer
+	c    *fakeSFTP
+	path string
+}
+
+func (f *fakeSFTPFile) Close() error {
+	f.c.files[f.path] = f.Bytes()
+	return nil
+}
+
+func (c *fakeSFTP) MkdirAll(dir string) error {
+	c.dirs[dir] = true
+	return nil
+}
+
+func (c *fakeSFTP) Create(path string) (io.WriteCloser, error) {
+	if !c.dirs[filepath.Dir(path)] {
+		return nil, os.ErrNotExist
+	}
+	return &fakeSFTPFile{c: c, path: path}, nil
+}
+
+func (c *fakeSFTP) Rename(oldpath, newpath string) error {
+	b, ok := c.files[oldpath]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(c.files, oldpath)
+	c.files[newpath] = b
+	return nil
+}
+
+func (c *fakeSFTP) Remove(path string) error {
+	delete(c.files, path)
+	return nil
+}
+
+func (c *fakeSFTP) Close() error { return nil }
+
+func TestSFTPArchive(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSFTPArchive", t)
+	defer os.RemoveAll(dir)
+
+	server := &fakeSFTP{dirs: make(map[string]bool), files: make(map[string][]byte)}
+	dials := 0
+	dial := func(ctx context.Context) (SFTPClient, error) {
+		// the first connection attempt fails, and is retried
+		if dials++; dials == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return server, nil
+	}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithSynchronousMill(),
+		WithSFTPArchive(dial, "/srv/logs/{{.Year}}/{{.Month}}/{{.Day}}"))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	// backups are dated by their name
+	name := filepath.Base(backupFile(dir))
+	remote := "/srv/logs/" + fakeTime().UTC().Format("2006/01/02") + "/" + name
+	b, ok := server.files[remote]
+	assert(ok, t, "backup not uploaded: %v", server.files)
+	local, err := ioutil.ReadFile(backupFile(dir))
+	isNil(err, t)
+	equals(local, b, t)
+	equals(1, len(server.files), t)
+	equals(2, dials, t)
+}
+
+func TestSFTPArchiveInvalidDir(t *testing.T) {
+	dir := makeTempDir("TestSFTPArchiveInvalidDir", t)
+	defer os.RemoveAll(dir)
+
+	dial := func(ctx context.Context) (SFTPClient, error) { return nil, errors.New("unused") }
+	_, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithSynchronousMill(),
+		WithSFTPArchive(dial, "/srv/logs/{{.Year"))
+	notNil(err, t)
+	fileCount(dir, 0, t)
+}
@@ -0,0 +1,78 @@
+package rolling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+var _ io.WriteCloser = (*Sharded)(nil)
+
+// Sharded spreads writes round-robin over several Loggers, app-0.log to
+// app-<n-1>.log for a Filename of app.log, each rolled and retained on its
+// own. It lets output be spread over several devices, or keeps each file
+// small enough for a downstream processor without rotating more often.
+// Every write goes to a single shard whole, so records aren't split.
+type Sharded struct {
+	next   uint64 // first, to be aligned for sync/atomic
+	shards []*Logger
+}
+
+// NewSharded creates n Loggers configured by options as NewWriter would, but
+// with the shard number appended to their Filename. If dirs is not empty,
+// shard i is put in dirs[i%len(dirs)] rather than LogPath.
+func NewSharded(dirs []string, n int, options ...Option) (*Sharded, error) {
+	if n <= 0 {
+		return nil, errors.New("at least one shard is needed")
+	}
+	base := defaultLogWriter()
+	base.apply(options)
+	ext := filepath.Ext(base.Filename)
+	prefix := base.Filename[:len(base.Filename)-len(ext)]
+
+	s := &Sharded{shards: make([]*Logger, 0, n)}
+	for i := 0; i < n; i++ {
+		opts := append(options[:len(options):len(options)], WithFilename(fmt.Sprintf("%s-%d%s", prefix, i, ext)),
+			withExpvarSuffix(strconv.Itoa(i)))
+		if len(dirs) > 0 {
+			opts = append(opts, WithLogPath(dirs[i%len(dirs)]))
+		}
+		l, err := NewWriter(opts...)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.shards = append(s.shards, l)
+	}
+	return s, nil
+}
+
+// Write writes p to the next shard in turn.
+func (s *Sharded) Write(p []byte) (int, error) {
+	i := (atomic.AddUint64(&s.next, 1) - 1) % uint64(len(s.shards))
+	return s.shards[i].Write(p)
+}
+
+// Shard returns the Logger of shard i.
+func (s *Sharded) Shard(i int) *Logger {
+	return s.shards[i]
+}
+
+// Len returns the number of shards.
+func (s *Sharded) Len() int {
+	return len(s.shards)
+}
+
+// Close closes all shards.
+func (s *Sharded) Close() error {
+	var err error
+	for _, l := range s.shards {
+		if errClose := l.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
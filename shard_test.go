@@ -0,0 +1,41 @@
+package rolling
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharded(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestSharded", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+	other := filepath.Join(dir, "other")
+
+	s, err := NewSharded([]string{dir, other}, 3, WithFilename("app.log"), WithMaxSize(10000))
+	isNil(err, t)
+	defer func() {
+		err := s.Close()
+		if err != nil {
+			return
+		}
+	}()
+	equals(3, s.Len(), t)
+
+	for _, b := range []string{"a\n", "b\n", "c\n", "d\n"} {
+		_, err := s.Write([]byte(b))
+		isNil(err, t)
+	}
+	existsWithContent(filepath.Join(dir, "app-0.log"), []byte("a\nd\n"), t)
+	existsWithContent(filepath.Join(other, "app-1.log"), []byte("b\n"), t)
+	existsWithContent(filepath.Join(dir, "app-2.log"), []byte("c\n"), t)
+	equals("app-1.log", s.Shard(1).Filename, t)
+
+	_, err = NewSharded(nil, 0)
+	notNil(err, t)
+}
@@ -0,0 +1,146 @@
+package rolling
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// uploadAttempts is how many times an archiver tries to upload a backup
+// before giving up until the next mill pass, and uploadBackoff how long it
+// waits before the first retry, doubling every time.
+const (
+	uploadAttempts = 3
+	uploadBackoff  = 100 * time.Millisecond
+)
+
+// Archiver ships backups to remote storage. Archive is called from the mill
+// with the path of every backup, once compressed and encrypted as configured,
+// and must not return until the backup is safely stored, or has failed to
+// be. A backup is archived once: successes are recorded in the manifest,
+// while failures are retried on later mill passes. Archive may retry on its
+// own, within reason, since it holds up the mill meanwhile.
+type Archiver interface {
+	Archive(ctx context.Context, localPath string) error
+}
+
+// uploaded returns the set of uploaded backups, as keyed by holdKey.
+func (m manifest) uploaded() map[string]bool {
+	uploaded := make(map[string]bool, len(m.Uploaded))
+	for _, key := range m.Uploaded {
+		uploaded[key] = true
+	}
+	return uploaded
+}
+
+// shipBackups uploads the backups which haven't been yet, once they are
+// compressed and encrypted as configured, and records them in the manifest.
+// Uploaded backups beyond the newest KeepUploaded are then removed. Backups
+// which fail to upload are retried on the next mill pass, which
+// retryMillLater brings forward. l.millMu must be held.
+func (l *Logger) shipBackups() error {
+	if l.archiver == nil {
+		return nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	m, err := l.loadManifest()
+	if err != nil {
+		return err
+	}
+	done, held := m.uploaded(), m.held()
+
+	// uploaded backups still around, newest first, so that the manifest
+	// forgets the others
+	present := make(map[string]bool)
+	var shipped []logInfo
+	for _, f := range files {
+		key := holdKey(f)
+		if !done[key] {
+			if l.needsProcessing(f) {
+				continue
+			}
+			if errUpload := l.upload(f); errUpload != nil {
+				if err == nil {
+					err = errUpload
+				}
+				continue
+			}
+			done[key] = true
+		}
+		present[key] = true
+		if !held[key] {
+			shipped = append(shipped, f)
+		}
+	}
+
+	if l.KeepUploaded > 0 && len(shipped) > l.KeepUploaded {
+		for _, f := range shipped[l.KeepUploaded:] {
+			errRemove := l.prune(f, PruneUploaded)
+			if errRemove == nil {
+				delete(present, holdKey(f))
+			} else if err == nil {
+				err = errRemove
+			}
+		}
+	}
+
+	uploaded := make([]string, 0, len(present))
+	for key := range present {
+		uploaded = append(uploaded, key)
+	}
+	sort.Strings(uploaded)
+	if strings.Join(uploaded, "\n") != strings.Join(m.Uploaded, "\n") {
+		m.Uploaded = uploaded
+		if errSave := l.saveManifest(m); err == nil {
+			err = errSave
+		}
+	}
+	return err
+}
+
+// upload hands the backup f to the archiver.
+func (l *Logger) upload(f logInfo) error {
+	path := filepath.Join(l.backupDir(), f.Name())
+	err := l.background(func() error {
+		if err := l.archiver.Archive(context.Background(), path); err != nil {
+			return err
+		}
+		if l.Checksums {
+			return l.archiver.Archive(context.Background(), path+checksumSuffix)
+		}
+		return nil
+	})
+	if l.onArchive != nil {
+		l.onArchive(path, err)
+	}
+	if err != nil {
+		atomic.AddUint64(&l.stats.uploadErrors, 1)
+		return fmt.Errorf("can't upload backup %s: %v", f.Name(), err)
+	}
+	atomic.AddUint64(&l.stats.uploads, 1)
+	return nil
+}
+
+// retry calls fn up to attempts times until it succeeds, waiting backoff
+// before the first retry and twice as long before every other.
+func retry(ctx context.Context, attempts int, backoff time.Duration, fn func() error) error {
+	for i := 1; ; i++ {
+		err := fn()
+		if err == nil || i >= attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,66 @@
+package rolling
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// archiverFunc adapts a function to the Archiver interface.
+type archiverFunc func(ctx context.Context, localPath string) error
+
+func (f archiverFunc) Archive(ctx context.Context, localPath string) error {
+	return f(ctx, localPath)
+}
+
+func TestArchiver(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestArchiver", t)
+	defer os.RemoveAll(dir)
+
+	archived := make(map[string]int)
+	results := make(map[string]error)
+	down := false
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithSynchronousMill(),
+		WithArchiver(archiverFunc(func(ctx context.Context, localPath string) error {
+			if down {
+				return errors.New("unreachable")
+			}
+			archived[filepath.Base(localPath)]++
+			return nil
+		})),
+		WithOnArchive(func(localPath string, err error) {
+			results[filepath.Base(localPath)] = err
+		}))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+	first := filepath.Base(backupFile(dir))
+	equals(1, archived[first], t)
+	isNil(results[first], t)
+
+	// failures are reported per backup and retried on the next pass
+	down = true
+	newFakeTime()
+	_, err = l.Write([]byte("baz!qux!"))
+	isNil(err, t)
+	second := filepath.Base(backupFile(dir))
+	notNil(results[second], t)
+	equals(0, archived[second], t)
+	equals(uint64(1), l.Stats().UploadErrors, t)
+
+	down = false
+	isNil(l.millRunOnce(), t)
+	isNil(results[second], t)
+	equals(1, archived[second], t)
+	equals(1, archived[first], t)
+	equals(uint64(2), l.Stats().Uploads, t)
+}
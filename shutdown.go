@@ -0,0 +1,31 @@
+package rolling
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Shutdown closes the Logger gracefully. It turns writes away from then on,
+// writes out what the async queue and the write buffer hold, closes the file,
+// and runs a last mill pass, once any under way is over, so that backups are
+// pruned, compressed, encrypted and uploaded as configured. If ctx is done
+// first, Shutdown returns ctx.Err() and the rest carries on in the
+// background; calling Shutdown again waits for the same work, for instance
+// with a longer deadline.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	l.shutdownOnce.Do(func() {
+		atomic.StoreInt32(&l.shut, 1)
+		l.shutdownDone = make(chan struct{})
+		go func() {
+			defer close(l.shutdownDone)
+			l.shutdownErr = l.closeLogger(true)
+		}()
+	})
+
+	select {
+	case <-l.shutdownDone:
+		return l.shutdownErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
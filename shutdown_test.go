@@ -0,0 +1,87 @@
+package rolling
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestShutdown", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithCompress(), WithBufferSize(64))
+	isNil(err, t)
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foobarbaz!"))
+	isNil(err, t)
+
+	// the buffer is written out and the backup compressed before it returns
+	isNil(l.Shutdown(context.Background()), t)
+	existsWithContent(logFile(dir), []byte("foobarbaz!"), t)
+	exists(backupFile(dir)+compressSuffix, t)
+	notExist(backupFile(dir), t)
+
+	_, err = l.Write([]byte("late"))
+	equals(errClosed, err, t)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestShutdownTimeout", t)
+	defer os.RemoveAll(dir)
+
+	stall := make(stallWriter)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithTee(stall), WithAsync())
+	isNil(err, t)
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// the queue can't be drained while the tee is stuck
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	equals(context.DeadlineExceeded, l.Shutdown(ctx), t)
+	// a second call waits for the work of the first
+	close(stall)
+	isNil(l.Shutdown(context.Background()), t)
+}
+
+func TestShutdownFailedMill(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestShutdownFailedMill", t)
+	defer os.RemoveAll(dir)
+
+	var attempts int32
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithArchiver(archiverFunc(func(ctx context.Context, localPath string) error {
+			atomic.AddInt32(&attempts, 1)
+			return errors.New("unreachable")
+		})))
+	isNil(err, t)
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	_, err = l.Write([]byte("foo!bar!"))
+	isNil(err, t)
+
+	// the last pass fails too, and isn't retried once Shutdown returns, as it
+	// would be after twice the backoff
+	notNil(l.Shutdown(context.Background()), t)
+	n := atomic.LoadInt32(&attempts)
+	time.Sleep(3 * defaultRotateBackoff)
+	equals(n, atomic.LoadInt32(&attempts), t)
+}
@@ -0,0 +1,85 @@
+package rolling
+
+import (
+	"os"
+)
+
+// Reopen is what a Logger does on SIGHUP with WithRotateOnHUP: if the log
+// file has been moved away or deleted, as logrotate and similar tools do
+// before signalling, it opens a new one at the configured path; otherwise it
+// rotates the file as usual. Either way it happens under the write lock, so
+// every write ends up whole in either the old or the new file.
+func (l *Logger) Reopen() error {
+	l.lockWrite()
+	defer l.unlockWrite()
+	if l.file == nil {
+		return errClosed
+	}
+
+	current, errCurrent := l.file.Stat()
+	onDisk, err := os.Stat(l.absPath)
+	if err == nil && errCurrent == nil && os.SameFile(current, onDisk) {
+		l.pendingRotate = true
+		return l.tryRotate()
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return l.reopen()
+}
+
+// reopen opens a new file at the configured path in place of the current
+// one, which has been moved away or deleted. l.mu must be held.
+func (l *Logger) reopen() error {
+	if err := os.MkdirAll(l.LogPath, l.dirMode()); err != nil {
+		return err
+	}
+	if err := l.chownDirs(); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.absPath, l.fileFlag(), l.fileMode())
+	if err != nil {
+		return err
+	}
+	if err := l.chown(l.absPath); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if l.AppendOnly {
+		if err := setAppendOnly(l.absPath, true); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	l.reportError(l.close())
+	l.file = f
+	l.written = 0
+	l.midLine = false
+	l.lines = 0
+	l.startAt = currentTime()
+	l.preallocateFile()
+	return l.writeHeader()
+}
+
+// checkMoved reopens the file if it is no longer at the configured path, as
+// when moved away or deleted by another process, for ReopenOnMove. It looks
+// at most every fileCheckInterval. l.mu must be held.
+func (l *Logger) checkMoved() {
+	now := currentTime()
+	if now.Sub(l.fileCheckedAt) < fileCheckInterval {
+		return
+	}
+	l.fileCheckedAt = now
+	current, err := l.file.Stat()
+	if err != nil {
+		return
+	}
+	onDisk, err := os.Stat(l.absPath)
+	if err == nil && os.SameFile(current, onDisk) {
+		return
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	l.reportError(l.reopen())
+}
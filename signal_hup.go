@@ -0,0 +1,59 @@
+//go:build !js && !wasip1
+// +build !js,!wasip1
+
+package rolling
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// hupWatcher rotates the Loggers created with WithRotateOnHUP when the
+// process receives SIGHUP. Like the other background work it is shared by
+// all Loggers, and only listens while any are registered.
+type hupWatcher struct {
+	mu      sync.Mutex
+	loggers map[*Logger]bool
+	signals chan os.Signal
+}
+
+var defaultHUP = &hupWatcher{loggers: make(map[*Logger]bool)}
+
+func (w *hupWatcher) register(l *Logger) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loggers[l] = true
+	if w.signals == nil {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, syscall.SIGHUP)
+		go w.run(w.signals)
+	}
+}
+
+func (w *hupWatcher) unregister(l *Logger) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.loggers, l)
+	if len(w.loggers) == 0 && w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.signals)
+		w.signals = nil
+	}
+}
+
+func (w *hupWatcher) run(signals chan os.Signal) {
+	for range signals {
+		w.mu.Lock()
+		loggers := make([]*Logger, 0, len(w.loggers))
+		for l := range w.loggers {
+			loggers = append(loggers, l)
+		}
+		w.mu.Unlock()
+
+		for _, l := range loggers {
+			l.reportError(l.Reopen())
+		}
+	}
+}
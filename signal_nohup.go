@@ -0,0 +1,14 @@
+//go:build js || wasip1
+// +build js wasip1
+
+package rolling
+
+// hupWatcher does nothing where there is no SIGHUP: WithRotateOnHUP has no
+// effect, though Reopen can still be called.
+type hupWatcher struct{}
+
+var defaultHUP = &hupWatcher{}
+
+func (w *hupWatcher) register(l *Logger) {}
+
+func (w *hupWatcher) unregister(l *Logger) {}
@@ -0,0 +1,56 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package rolling
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotateOnHUP(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestRotateOnHUP", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10000), WithRotateOnHUP())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	_, err = l.Write([]byte("before\n"))
+	isNil(err, t)
+
+	// logrotate style: the file is moved away, then the process signalled
+	moved := logFile(dir) + ".1"
+	isNil(os.Rename(logFile(dir), moved), t)
+	isNil(syscall.Kill(os.Getpid(), syscall.SIGHUP), t)
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(logFile(dir)); err == nil {
+			break
+		}
+		<-time.After(10 * time.Millisecond)
+	}
+	_, err = l.Write([]byte("after\n"))
+	isNil(err, t)
+	existsWithContent(moved, []byte("before\n"), t)
+	existsWithContent(logFile(dir), []byte("after\n"), t)
+	fileCount(dir, 2, t)
+
+	// without the move, the file is rotated
+	newFakeTime()
+	isNil(l.Reopen(), t)
+	existsWithContent(backupFile(dir), []byte("after\n"), t)
+	existsWithContent(logFile(dir), []byte{}, t)
+}
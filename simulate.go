@@ -0,0 +1,134 @@
+package rolling
+
+import (
+	"errors"
+	"time"
+)
+
+// defaultCompressionRatio is the compressed to original size ratio Simulate
+// assumes for text logs when the traffic profile doesn't say.
+const defaultCompressionRatio = 0.1
+
+// TrafficProfile describes the write load assumed by Simulate.
+type TrafficProfile struct {
+	// BytesPerSecond is the average write rate.
+	BytesPerSecond float64
+	// CompressionRatio is the size of a compressed backup relative to the
+	// original, used when compression is enabled. Defaults to 0.1.
+	CompressionRatio float64
+}
+
+// Simulation is the steady state Simulate expects a configuration to reach.
+type Simulation struct {
+	// RotationInterval is the average time between rotations, or zero if the
+	// file never rotates.
+	RotationInterval time.Duration `json:"rotation_interval"`
+	RotationsPerDay  float64       `json:"rotations_per_day"`
+	// BackupSize is the average size of a backup, after compression if it is
+	// enabled.
+	BackupSize int64 `json:"backup_size"`
+	// Backups is the number of backups retention keeps around, and DiskUsage
+	// the space they take together with a full active file. Both are only
+	// meaningful if Unbounded is false.
+	Backups   int   `json:"backups"`
+	DiskUsage int64 `json:"disk_usage"`
+	// Unbounded reports that nothing limits the number of backups, or that
+	// the active file is never rotated, so disk usage keeps growing.
+	Unbounded bool `json:"unbounded"`
+}
+
+// Simulate estimates how often a Logger configured with cfg would rotate and
+// how much disk it would use in the long run under the given traffic, to help
+// pick MaxSize, MaxRemain, MaxAge or MaxAgeDuration and MaxTotalSize before
+// deploying.
+func Simulate(cfg Config, traffic TrafficProfile) (Simulation, error) {
+	if traffic.BytesPerSecond <= 0 {
+		return Simulation{}, errors.New("traffic must have a positive write rate")
+	}
+	ratio := traffic.CompressionRatio
+	if ratio <= 0 {
+		ratio = defaultCompressionRatio
+	}
+	l := &Logger{Config: cfg}
+	maxSize := float64(l.max())
+
+	var interval time.Duration
+	switch cfg.RollingPolicy {
+	case VolumeRolling:
+		interval = secondsToDuration(maxSize / traffic.BytesPerSecond)
+	case TimeRolling, HybridRolling:
+		pattern := cfg.TimePattern
+		if pattern == "" {
+			pattern = rollingTimePattern
+		}
+		schedule, err := parseCron(pattern)
+		if err != nil {
+			return Simulation{}, err
+		}
+		first := schedule.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		interval = schedule.Next(first).Sub(first)
+		if bySize := secondsToDuration(maxSize / traffic.BytesPerSecond); l.sizeRolling() && bySize < interval {
+			interval = bySize
+		}
+	default:
+		return Simulation{Unbounded: true}, nil
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	sim := Simulation{
+		RotationInterval: interval,
+		RotationsPerDay:  float64(24*time.Hour) / float64(interval),
+	}
+	size := traffic.BytesPerSecond * interval.Seconds()
+	if l.sizeRolling() && size > maxSize {
+		size = maxSize
+	}
+	backupSize := size
+	plain := 0 // backups left uncompressed by CompressAfter
+	if cfg.Compress {
+		backupSize *= ratio
+		plain = cfg.CompressAfter
+	}
+	sim.BackupSize = int64(backupSize)
+
+	backups := -1
+	if cfg.MaxRemain > 0 {
+		backups = cfg.MaxRemain
+	}
+	if maxAge := l.maxAge(); maxAge > 0 {
+		byAge := int(maxAge / interval)
+		if backups < 0 || byAge < backups {
+			backups = byAge
+		}
+	}
+	if cfg.MaxTotalSize > 0 {
+		bySize := 0
+		room := float64(cfg.MaxTotalSize) - size
+		switch {
+		case room <= 0 || backupSize <= 0:
+		case room < float64(plain)*size:
+			bySize = int(room / size)
+		default:
+			bySize = plain + int((room-float64(plain)*size)/backupSize)
+		}
+		if backups < 0 || bySize < backups {
+			backups = bySize
+		}
+	}
+	if backups < 0 {
+		sim.Unbounded = true
+		return sim, nil
+	}
+	sim.Backups = backups
+	if plain > backups {
+		plain = backups
+	}
+	sim.DiskUsage = int64(float64(plain)*size + float64(backups-plain)*backupSize + size)
+	return sim, nil
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,53 @@
+package rolling
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	megabyte = 1024 * 1024
+	defer func() { megabyte = 1 }()
+
+	// 100MB files at 1MB/s roll every 100s; MaxAge allows a day's worth of
+	// backups but MaxRemain only keeps 50
+	cfg := Config{RollingPolicy: VolumeRolling, MaxSize: 100, MaxRemain: 50, MaxAge: 1}
+	sim, err := Simulate(cfg, TrafficProfile{BytesPerSecond: 1 << 20})
+	isNil(err, t)
+	equals(100*time.Second, sim.RotationInterval, t)
+	equals(864.0, sim.RotationsPerDay, t)
+	equals(int64(100<<20), sim.BackupSize, t)
+	equals(50, sim.Backups, t)
+	equals(int64(51*100<<20), sim.DiskUsage, t)
+	equals(false, sim.Unbounded, t)
+
+	// daily rolling well under MaxSize, compressed, kept a week
+	cfg = Config{RollingPolicy: TimeRolling, MaxSize: 1 << 10, MaxAge: 7, Compress: true}
+	sim, err = Simulate(cfg, TrafficProfile{BytesPerSecond: 1 << 10, CompressionRatio: 0.5})
+	isNil(err, t)
+	equals(24*time.Hour, sim.RotationInterval, t)
+	equals(int64(86400<<10/2), sim.BackupSize, t)
+	equals(7, sim.Backups, t)
+
+	// the total size cap leaves room for 6 compressed backups next to a full
+	// active file
+	cfg.MaxTotalSize = 4 * 86400 << 10
+	sim, err = Simulate(cfg, TrafficProfile{BytesPerSecond: 1 << 10, CompressionRatio: 0.5})
+	isNil(err, t)
+	equals(6, sim.Backups, t)
+
+	// keeping the newest backup uncompressed costs one compressed backup
+	cfg.CompressAfter = 1
+	sim, err = Simulate(cfg, TrafficProfile{BytesPerSecond: 1 << 10, CompressionRatio: 0.5})
+	isNil(err, t)
+	equals(5, sim.Backups, t)
+	equals(int64(86400<<10+4*86400<<10/2+86400<<10), sim.DiskUsage, t)
+	cfg.CompressAfter = 0
+
+	sim, err = Simulate(Config{RollingPolicy: VolumeRolling}, TrafficProfile{BytesPerSecond: 1})
+	isNil(err, t)
+	equals(true, sim.Unbounded, t)
+
+	_, err = Simulate(cfg, TrafficProfile{})
+	notNil(err, t)
+}
@@ -0,0 +1,27 @@
+//go:build go1.21
+// +build go1.21
+
+package rolling
+
+import "log/slog"
+
+// SlogOptions configures the handler NewSlogHandler returns.
+type SlogOptions struct {
+	slog.HandlerOptions
+	// JSON formats records as JSON objects rather than as key=value pairs.
+	JSON bool
+}
+
+// NewSlogHandler returns a slog.Handler formatting records with
+// slog.TextHandler, or slog.JSONHandler if opts.JSON is set, into l, one line
+// per record. Each record takes a single Write, so it's never split between
+// two files. opts may be nil for the defaults.
+func NewSlogHandler(l *Logger, opts *SlogOptions) slog.Handler {
+	if opts == nil {
+		opts = &SlogOptions{}
+	}
+	if opts.JSON {
+		return slog.NewJSONHandler(l, &opts.HandlerOptions)
+	}
+	return slog.NewTextHandler(l, &opts.HandlerOptions)
+}
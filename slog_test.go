@@ -0,0 +1,36 @@
+//go:build go1.21
+// +build go1.21
+
+package rolling
+
+import (
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSlogHandler", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1000))
+	isNil(err, t)
+	defer l.Close()
+
+	logger := slog.New(NewSlogHandler(l, &SlogOptions{JSON: true}))
+	logger.Info("hello", "user", "bob")
+	logger.Debug("hidden")
+	text := slog.New(NewSlogHandler(l, &SlogOptions{HandlerOptions: slog.HandlerOptions{Level: slog.LevelDebug}}))
+	text.Debug("shown", "n", 1)
+
+	b, err := ioutil.ReadFile(logFile(dir))
+	isNil(err, t)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	equals(2, len(lines), t)
+	equals(true, strings.HasSuffix(lines[0], `"level":"INFO","msg":"hello","user":"bob"}`), t)
+	equals(true, strings.HasSuffix(lines[1], `level=DEBUG msg=shown n=1`), t)
+}
@@ -0,0 +1,152 @@
+package rolling
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// splitSearchBlock is how much of a file is read at a time looking back for
+// the end of a line to cut it at.
+const splitSearchBlock = 64 * 1024
+
+// splitPath returns where an oversized log file is set aside until split.
+func (l *Logger) splitPath() string {
+	return filepath.Join(l.LogPath, "."+l.Filename+".split")
+}
+
+// setAsideOversized moves the log file at fp out of the way to be split if
+// SplitOversized says it's too big, and reports whether there's a file to
+// split, possibly left over by a previous run.
+func (l *Logger) setAsideOversized(fp string) (bool, error) {
+	if l.SplitOversized <= 0 {
+		return false, nil
+	}
+	if _, err := os.Stat(l.splitPath()); err == nil {
+		return true, nil
+	}
+	info, err := os.Stat(fp)
+	if err != nil || info.Size() <= int64(l.SplitOversized)*l.max() {
+		return false, nil
+	}
+	if err := l.rename(fp, l.splitPath()); err != nil {
+		return false, fmt.Errorf("can't set aside oversized log file: %s", err)
+	}
+	return true, nil
+}
+
+// splitSetAside splits the file set aside by setAsideOversized, if any, into
+// backups of at most MaxSize each, cut after a newline where possible. The
+// backups are named after the file's modification time, a millisecond apart,
+// and so sort before anything rotated since. l.millMu must be held.
+//
+// The split is deterministic, so if it's interrupted it is simply done again,
+// over the same backups, on the next mill pass.
+func (l *Logger) splitSetAside() error {
+	src := l.splitPath()
+	f, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = l.background(func() error {
+		cuts, err := splitPoints(f, fi.Size(), l.max())
+		if err != nil {
+			return err
+		}
+		var start int64
+		for i, end := range cuts {
+			t := fi.ModTime().Add(-time.Duration(len(cuts)-1-i) * time.Millisecond)
+			if !l.LocalTime {
+				t = t.UTC()
+			}
+			name := filepath.Join(l.backupDir(), l.plainBackupName(t, 0))
+			if l.AppendOnly {
+				// left by an interrupted split
+				_ = setAppendOnly(name, false)
+			}
+			if err := writeChunk(name, io.NewSectionReader(f, start, end-start), fi.Mode(), t); err != nil {
+				return fmt.Errorf("can't split oversized log file: %v", err)
+			}
+			if l.AppendOnly {
+				if err := setAppendOnly(name, true); err != nil {
+					return err
+				}
+			}
+			start = end
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return l.remove(src)
+}
+
+// splitPoints returns the offsets at which to end each chunk of at most max
+// bytes of the first size bytes of r: after the last newline that fits, or
+// at max bytes if a line is longer than that.
+func splitPoints(r io.ReaderAt, size, max int64) ([]int64, error) {
+	var cuts []int64
+	buf := make([]byte, splitSearchBlock)
+	for start := int64(0); start < size; {
+		end := start + max
+		if end >= size {
+			cuts = append(cuts, size)
+			break
+		}
+		cut := end
+		for hi := end; hi > start; {
+			lo := hi - int64(len(buf))
+			if lo < start {
+				lo = start
+			}
+			if _, err := r.ReadAt(buf[:hi-lo], lo); err != nil {
+				return nil, err
+			}
+			if i := bytes.LastIndexByte(buf[:hi-lo], '\n'); i >= 0 {
+				cut = lo + int64(i) + 1
+				break
+			}
+			hi = lo
+		}
+		cuts = append(cuts, cut)
+		start = cut
+	}
+	return cuts, nil
+}
+
+// writeChunk writes the content of r to a new file name with mode and
+// modification time t.
+func writeChunk(name string, r io.Reader, mode os.FileMode, t time.Time) error {
+	out, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(name, t, t)
+}
@@ -0,0 +1,62 @@
+package rolling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitOversized(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSplitOversized", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	// one line longer than MaxSize, which has to be cut
+	content := strings.Repeat("abcd\n", 5) + "0123456789abc\n" + "ef\n"
+	isNil(ioutil.WriteFile(logFile(dir), []byte(content), 0644), t)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	isNil(os.Chtimes(logFile(dir), modTime, modTime), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10), WithMaxRemain(0), WithMaxAge(0),
+		WithSplitOversized(2), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	existsWithContent(logFile(dir), []byte{}, t)
+	notExist(l.splitPath(), t)
+
+	backups, err := NewInspector(dir, logName()).ListBackups()
+	isNil(err, t)
+	want := []string{"abcd\nabcd\n", "abcd\nabcd\n", "abcd\n", "0123456789", "abc\nef\n"}
+	equals(len(want), len(backups), t)
+	var got [][]byte
+	for i := range backups {
+		b := backups[len(backups)-1-i]
+		data, err := ioutil.ReadFile(b.Path)
+		isNil(err, t)
+		equals(want[i], string(data), t)
+		got = append(got, data)
+	}
+	equals(content, string(bytes.Join(got, nil)), t)
+	equals(true, backups[0].Timestamp.Equal(modTime), t)
+	equals(filepath.Join(dir, "foobar-2020-01-02T03-04-04.996.log"), backups[4].Path, t)
+
+	// a file within the limit is left alone
+	l2, err := l.Clone(WithFilename("small.log"))
+	isNil(err, t)
+	isNil(l2.Close(), t)
+}
@@ -0,0 +1,278 @@
+package rolling
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// lockWaitBounds are the upper bounds of the lock wait histogram buckets. A
+// final, unbounded bucket catches everything slower.
+var lockWaitBounds = [...]time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Stats is a point-in-time snapshot of a Logger's counters.
+type Stats struct {
+	// DroppedWrites counts writes discarded because they would have eaten into
+	// the MinFreeSpace reserve, didn't fit in the async queue, went over
+	// RateLimit with RateLimitDrop, or found the disk full with
+	// WriteErrorDrop.
+	DroppedWrites uint64 `json:"dropped_writes"`
+	// DroppedBytes is the total size of those writes.
+	DroppedBytes uint64 `json:"dropped_bytes"`
+
+	// OpenedAt is when the active file was opened or last rotated.
+	OpenedAt time.Time `json:"opened_at"`
+	// NextRotation is when the next scheduled rotation is expected with time
+	// rolling, or zero if none is known.
+	NextRotation time.Time `json:"next_rotation"`
+	// BytesUntilRotation is how much more can be written before the active
+	// file reaches MaxSize, or -1 if the rolling policy ignores size.
+	// RotationSize is the size it is rotated at, which is MaxSize unless
+	// adaptive rotation has tightened it.
+	BytesUntilRotation int64 `json:"bytes_until_rotation"`
+	RotationSize       int64 `json:"rotation_size"`
+
+	// FallbackWrites counts the writes, or what was left of them, forwarded
+	// to the fallback writer because the file couldn't take them.
+	FallbackWrites uint64 `json:"fallback_writes"`
+
+	// Syncs counts the fsyncs issued for SyncEveryWrite, SyncInterval and
+	// Sync.
+	Syncs uint64 `json:"syncs"`
+
+	// Writes and BytesWritten count the writes made to the file and their
+	// total size, WriteErrors the writes that failed.
+	Writes       uint64 `json:"writes"`
+	BytesWritten uint64 `json:"bytes_written"`
+	WriteErrors  uint64 `json:"write_errors"`
+	// Size is the size of the active file, buffered writes included.
+	Size int64 `json:"size"`
+
+	// Rotations counts the successful rotations, the last of which happened
+	// at LastRotation.
+	Rotations    uint64    `json:"rotations"`
+	LastRotation time.Time `json:"last_rotation"`
+
+	// Compressions counts the backups compressed, and CompressionTime the
+	// total time spent compressing them.
+	Compressions    uint64        `json:"compressions"`
+	CompressionTime time.Duration `json:"compression_time"`
+
+	// Uploads counts the backups uploaded by the archiver, UploadErrors
+	// the failed attempts.
+	Uploads      uint64 `json:"uploads"`
+	UploadErrors uint64 `json:"upload_errors"`
+
+	// Pruned counts the backups removed by retention, by PruneReason.
+	Pruned map[string]uint64 `json:"pruned"`
+
+	// LockWait describes how long writers waited for the write mutex. It is
+	// only populated when LockMetrics is enabled.
+	LockWait LockWaitStats `json:"lock_wait"`
+}
+
+// LockWaitStats is a histogram of the time spent waiting for the write mutex.
+type LockWaitStats struct {
+	// Count is the number of lock acquisitions observed and Total the time
+	// spent waiting across all of them.
+	Count uint64        `json:"count"`
+	Total time.Duration `json:"total"`
+	// Buckets holds the number of waits that took at most UpperBound and more
+	// than the previous bucket's bound. The last bucket has no upper bound and
+	// reports a zero UpperBound.
+	Buckets []LockWaitBucket `json:"buckets"`
+	// MaxQueueDepth is the largest number of writers seen waiting for, or
+	// holding, the mutex at the same time.
+	MaxQueueDepth int64 `json:"max_queue_depth"`
+}
+
+// LockWaitBucket is a single LockWaitStats histogram bucket.
+type LockWaitBucket struct {
+	UpperBound time.Duration `json:"upper_bound"`
+	Count      uint64        `json:"count"`
+}
+
+// counters are updated while holding the write mutex but may be read at any
+// time, so every access goes through sync/atomic.
+type counters struct {
+	droppedWrites uint64
+	droppedBytes  uint64
+	syncs         uint64
+	traceCount    uint64
+
+	fallbackWrites uint64
+
+	uploads       uint64
+	uploadErrors  uint64
+	writes        uint64
+	bytesWritten  uint64
+	writeErrors   uint64
+	rotations     uint64
+	lastRotation  int64
+	compressions  uint64
+	compressNanos int64
+	pruned        [numPruneReasons]uint64
+
+	lockWaitCount   uint64
+	lockWaitTotal   int64
+	lockWaitBuckets [len(lockWaitBounds) + 1]uint64
+	queueDepth      int64
+	maxQueueDepth   int64
+}
+
+// Stats returns a snapshot of the Logger's counters.
+func (l *Logger) Stats() Stats {
+	s := Stats{
+		DroppedWrites: atomic.LoadUint64(&l.stats.droppedWrites),
+		DroppedBytes:  atomic.LoadUint64(&l.stats.droppedBytes),
+		Syncs:         atomic.LoadUint64(&l.stats.syncs),
+
+		FallbackWrites: atomic.LoadUint64(&l.stats.fallbackWrites),
+
+		Writes:          atomic.LoadUint64(&l.stats.writes),
+		BytesWritten:    atomic.LoadUint64(&l.stats.bytesWritten),
+		WriteErrors:     atomic.LoadUint64(&l.stats.writeErrors),
+		Rotations:       atomic.LoadUint64(&l.stats.rotations),
+		Compressions:    atomic.LoadUint64(&l.stats.compressions),
+		Uploads:         atomic.LoadUint64(&l.stats.uploads),
+		UploadErrors:    atomic.LoadUint64(&l.stats.uploadErrors),
+		CompressionTime: time.Duration(atomic.LoadInt64(&l.stats.compressNanos)),
+		Pruned:          make(map[string]uint64, numPruneReasons),
+	}
+	if at := atomic.LoadInt64(&l.stats.lastRotation); at != 0 {
+		s.LastRotation = time.Unix(0, at)
+	}
+	for r := PruneReason(0); r < numPruneReasons; r++ {
+		s.Pruned[r.String()] = atomic.LoadUint64(&l.stats.pruned[r])
+	}
+	l.fileStats(&s)
+	if l.LockMetrics {
+		s.LockWait = LockWaitStats{
+			Count:         atomic.LoadUint64(&l.stats.lockWaitCount),
+			Total:         time.Duration(atomic.LoadInt64(&l.stats.lockWaitTotal)),
+			Buckets:       make([]LockWaitBucket, len(l.stats.lockWaitBuckets)),
+			MaxQueueDepth: atomic.LoadInt64(&l.stats.maxQueueDepth),
+		}
+		for i := range s.LockWait.Buckets {
+			if i < len(lockWaitBounds) {
+				s.LockWait.Buckets[i].UpperBound = lockWaitBounds[i]
+			}
+			s.LockWait.Buckets[i].Count = atomic.LoadUint64(&l.stats.lockWaitBuckets[i])
+		}
+	}
+	return s
+}
+
+// fileState is the part of Stats describing the active file. The writer
+// keeps it up to date under its own small lock, so that Stats, the expvar
+// publisher and metric scrapes don't queue up behind a write stuck on a hung
+// file system.
+type fileState struct {
+	openedAt      time.Time
+	size          int64
+	written       int64
+	rotationSize  int64
+	nextRotateAt  time.Time
+	pendingRotate bool
+}
+
+// publishFileState updates l.state from the Logger's current state. l.mu must
+// be held.
+func (l *Logger) publishFileState() {
+	var size int64
+	if l.file != nil {
+		size = l.openSize + l.written
+	}
+	rotationSize := l.rotateSize()
+
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	l.state = fileState{
+		openedAt:      l.startAt,
+		size:          size,
+		written:       l.written,
+		rotationSize:  rotationSize,
+		nextRotateAt:  l.nextRotateAt,
+		pendingRotate: l.pendingRotate,
+	}
+}
+
+// fileStats fills in the parts of s that describe the active file, from
+// what the writer last published.
+func (l *Logger) fileStats(s *Stats) {
+	l.stateMu.Lock()
+	st := l.state
+	l.stateMu.Unlock()
+
+	s.OpenedAt = st.openedAt
+	s.Size = st.size
+	s.BytesUntilRotation = -1
+	if l.sizeRolling() {
+		s.RotationSize = st.rotationSize
+		size := s.Size
+		if l.SizeSinceOpen {
+			size = st.written
+		}
+		if s.BytesUntilRotation = st.rotationSize - size; s.BytesUntilRotation < 0 {
+			s.BytesUntilRotation = 0
+		}
+	}
+	if l.timeRolling() {
+		switch {
+		case !st.nextRotateAt.IsZero():
+			s.NextRotation = st.nextRotateAt
+		case st.pendingRotate:
+			s.NextRotation = currentTime()
+		default:
+			if schedule, err := l.cronSchedule(); err == nil {
+				s.NextRotation = schedule.Next(time.Now())
+			}
+		}
+	}
+}
+
+// lockWrite acquires the write mutex, recording how long it took and how many
+// writers were queued if LockMetrics is enabled. Pair it with unlockWrite.
+func (l *Logger) lockWrite() {
+	if !l.LockMetrics {
+		l.mu.Lock()
+		return
+	}
+
+	depth := atomic.AddInt64(&l.stats.queueDepth, 1)
+	for {
+		cur := atomic.LoadInt64(&l.stats.maxQueueDepth)
+		if depth <= cur || atomic.CompareAndSwapInt64(&l.stats.maxQueueDepth, cur, depth) {
+			break
+		}
+	}
+
+	start := time.Now()
+	l.mu.Lock()
+	wait := time.Since(start)
+
+	i := 0
+	for i < len(lockWaitBounds) && wait > lockWaitBounds[i] {
+		i++
+	}
+	atomic.AddUint64(&l.stats.lockWaitBuckets[i], 1)
+	atomic.AddUint64(&l.stats.lockWaitCount, 1)
+	atomic.AddInt64(&l.stats.lockWaitTotal, int64(wait))
+}
+
+// unlockWrite releases the write mutex acquired by lockWrite, publishing the
+// file state left by whatever was done under it.
+func (l *Logger) unlockWrite() {
+	l.publishFileState()
+	if l.LockMetrics {
+		atomic.AddInt64(&l.stats.queueDepth, -1)
+	}
+	l.mu.Unlock()
+}
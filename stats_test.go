@@ -0,0 +1,113 @@
+package rolling
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockMetrics(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestLockMetrics", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithLockMetrics())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = l.Write([]byte("boo!\n"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := l.Stats().LockWait
+	equals(uint64(800), s.Count, t)
+	equals(len(lockWaitBounds)+1, len(s.Buckets), t)
+	var total uint64
+	for _, b := range s.Buckets {
+		total += b.Count
+	}
+	equals(s.Count, total, t)
+	assert(s.MaxQueueDepth >= 1 && s.MaxQueueDepth <= 8, t, "unexpected queue depth %d", s.MaxQueueDepth)
+	equals(int64(0), l.stats.queueDepth, t)
+}
+
+func TestStatsRotationCountdown(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestStatsRotationCountdown", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithHybridRolling(), WithSynchronousMill())
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	s := l.Stats()
+	equals(fakeTime(), s.OpenedAt, t)
+	equals(int64(6), s.BytesUntilRotation, t)
+	y, m, d := fakeTime().Date()
+	equals(time.Date(y, m, d+1, 0, 0, 0, 0, fakeTime().Location()), s.NextRotation, t)
+
+	newFakeTime()
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	equals(fakeTime(), l.Stats().OpenedAt, t)
+}
+
+func TestStatsWhileWriteBlocked(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestStatsWhileWriteBlocked", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10))
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// a write stuck on the file holds the write lock
+	l.mu.Lock()
+	done := make(chan Stats)
+	go func() { done <- l.Stats() }()
+	select {
+	case s := <-done:
+		equals(int64(4), s.Size, t)
+		equals(int64(6), s.BytesUntilRotation, t)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stats waited for the write lock")
+	}
+	l.mu.Unlock()
+}
@@ -0,0 +1,165 @@
+package rolling
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// groupCommit batches the fsyncs of concurrent writers when SyncEveryWrite is
+// set. Every write gets a sequence number; the first writer to find no fsync
+// in progress becomes the leader, waits GroupCommitWindow for others to
+// join, and fsyncs once on behalf of every write made up to that point.
+type groupCommit struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	syncing bool
+	// synced is the sequence number of the last write known to be on stable
+	// storage, failed that of the last write whose fsync failed with err.
+	synced uint64
+	failed uint64
+	err    error
+}
+
+// commit waits until the write with sequence number seq has been fsynced.
+func (l *Logger) commit(seq uint64) error {
+	gc := &l.group
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	for {
+		if gc.synced >= seq {
+			return nil
+		}
+		if gc.failed >= seq {
+			return gc.err
+		}
+		if gc.syncing {
+			gc.cond.Wait()
+			continue
+		}
+
+		gc.syncing = true
+		gc.mu.Unlock()
+		if l.GroupCommitWindow > 0 {
+			time.Sleep(l.GroupCommitWindow)
+		}
+		l.mu.Lock()
+		f, target := l.file, l.writeSeq
+		err := l.flush()
+		l.mu.Unlock()
+		if f != nil && err == nil {
+			err = f.Sync()
+			atomic.AddUint64(&l.stats.syncs, 1)
+		}
+		gc.mu.Lock()
+		gc.syncing = false
+		gc.done(target, err)
+	}
+}
+
+// done records the outcome of an fsync covering every write up to target
+// and wakes up the writers waiting for it. gc.mu must be held.
+func (gc *groupCommit) done(target uint64, err error) {
+	switch {
+	case target <= gc.synced:
+		// a rotation or Close already synced these writes before closing
+		// the file the fsync was made on
+	case err != nil:
+		gc.failed, gc.err = target, err
+	default:
+		gc.synced = target
+	}
+	gc.cond.Broadcast()
+}
+
+// Sync writes out the write buffer, if any, and fsyncs the file, so that
+// everything written so far survives a crash; with Async, it first waits for
+// the writes queued before the call to be written. A closed Logger has
+// nothing left to sync.
+//
+// Sync returns straight away, without taking the write lock, when nothing
+// was written since the last one, so it is cheap to call often. With Write,
+// it makes a Logger a zapcore.WriteSyncer as is: pass it to zapcore.NewCore,
+// or through zapcore.Lock, and zap's Sync on Panic and Fatal entries, and on
+// Logger.Sync, reaches the file, which wrapping with zapcore.AddSync a writer
+// without Sync would turn into a no-op.
+func (l *Logger) Sync() error {
+	if l.async != nil {
+		l.async.wait()
+	}
+	if atomic.LoadUint32(&l.unsynced) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sync()
+}
+
+// sync implements Sync. l.mu must be held.
+func (l *Logger) sync() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.flush()
+	if err == nil {
+		err = l.file.Sync()
+		atomic.AddUint64(&l.stats.syncs, 1)
+	}
+	if err == nil {
+		atomic.StoreUint32(&l.unsynced, 0)
+	}
+	l.dirtySince = time.Time{}
+	if l.SyncEveryWrite {
+		l.group.mu.Lock()
+		l.group.done(l.writeSeq, err)
+		l.group.mu.Unlock()
+	}
+	return err
+}
+
+// scheduleSync arranges for a write just made to be fsynced within
+// SyncInterval. A timer is only armed while there are unsynced writes; in
+// synchronous mode, which has none, the file is fsynced by the first write
+// made once the interval has elapsed. l.mu must be held.
+func (l *Logger) scheduleSync() {
+	if l.SyncInterval <= 0 {
+		return
+	}
+	if l.SynchronousMill {
+		now := currentTime()
+		if l.dirtySince.IsZero() {
+			l.dirtySince = now
+		} else if now.Sub(l.dirtySince) >= l.SyncInterval {
+			l.reportError(l.sync())
+		}
+		return
+	}
+	if l.syncPending {
+		return
+	}
+	l.syncPending = true
+	if l.syncTimer == nil {
+		l.syncTimer = time.AfterFunc(l.SyncInterval, l.timedSync)
+		return
+	}
+	l.syncTimer.Reset(l.SyncInterval)
+}
+
+// timedSync is run by the sync timer.
+func (l *Logger) timedSync() {
+	l.mu.Lock()
+	l.syncPending = false
+	err := l.sync()
+	l.mu.Unlock()
+	l.reportError(err)
+}
+
+// syncBeforeClose fsyncs the current file before it is closed, so no write is
+// left waiting on a group commit for a file that's gone. l.mu must be held.
+func (l *Logger) syncBeforeClose() {
+	err := l.file.Sync()
+	atomic.AddUint64(&l.stats.syncs, 1)
+	l.group.mu.Lock()
+	l.group.done(l.writeSeq, err)
+	l.group.mu.Unlock()
+}
@@ -0,0 +1,127 @@
+package rolling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupCommit(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestGroupCommit", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10000), WithGroupCommit(5*time.Millisecond))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	const writers, writes = 20, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if _, err := l.Write([]byte("boo!\n")); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		isNil(err, t)
+	}
+
+	b, err := ioutil.ReadFile(logFile(dir))
+	isNil(err, t)
+	equals(writers*writes*5, len(b), t)
+
+	syncs := l.Stats().Syncs
+	assert(syncs > 0 && syncs < writers*writes, t, "expected batched fsyncs, got %d", syncs)
+	equals(l.writeSeq, l.group.synced, t)
+}
+
+func TestSync(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSync", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithBufferSize(64))
+	isNil(err, t)
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte{}, t)
+	isNil(l.Sync(), t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+	equals(uint64(1), l.Stats().Syncs, t)
+
+	// nothing new to sync
+	isNil(l.Sync(), t)
+	equals(uint64(1), l.Stats().Syncs, t)
+
+	isNil(l.Close(), t)
+	isNil(l.Sync(), t)
+}
+
+func TestSyncAsync(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSyncAsync", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(1000), WithAsync())
+	isNil(err, t)
+	defer l.Close()
+
+	for i := 0; i < 100; i++ {
+		_, err = l.Write([]byte("boo!"))
+		isNil(err, t)
+	}
+	// queued writes are written before the fsync
+	isNil(l.Sync(), t)
+	existsWithContent(logFile(dir), bytes.Repeat([]byte("boo!"), 100), t)
+	equals(uint64(1), l.Stats().Syncs, t)
+}
+
+func TestSyncInterval(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestSyncInterval", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100), WithSyncInterval(10*time.Millisecond))
+	isNil(err, t)
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err = l.Write([]byte("boo!"))
+		isNil(err, t)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Stats().Syncs == 0 {
+		assert(time.Now().Before(deadline), t, "file not synced after the interval")
+		time.Sleep(5 * time.Millisecond)
+	}
+	// the writes made before the timer fired share its fsync
+	syncs := l.Stats().Syncs
+	assert(syncs < 10, t, "expected writes to share fsyncs, got %d", syncs)
+}
@@ -0,0 +1,38 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package rolling
+
+import (
+	"log/syslog"
+	"sync"
+)
+
+// WithSyslogFallback forwards writes the file can't take to the local syslog
+// daemon, at priority and with tag, see WithFallback. The connection is only
+// made when first needed, and remade after failures.
+func WithSyslogFallback(priority syslog.Priority, tag string) Option {
+	return WithFallback(&syslogWriter{priority: priority, tag: tag})
+}
+
+// syslogWriter writes to the local syslog daemon, connecting on first use.
+type syslogWriter struct {
+	priority syslog.Priority
+	tag      string
+
+	mu sync.Mutex
+	w  *syslog.Writer
+}
+
+func (s *syslogWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		w, err := syslog.New(s.priority, s.tag)
+		if err != nil {
+			return 0, err
+		}
+		s.w = w
+	}
+	return s.w.Write(p)
+}
@@ -0,0 +1,51 @@
+package rolling
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriteTimeout is returned by writes which didn't complete within
+// WriteTimeout. The data may still be written once the file responds again.
+var ErrWriteTimeout = errors.New("write timed out")
+
+// writeResult is the outcome of a write made by writeWithTimeout.
+type writeResult struct {
+	n   int
+	err error
+}
+
+// writeWithTimeout writes p on another goroutine, giving up on it after
+// WriteTimeout. While a write given up on is still under way, the file is
+// taken to be stalled and writes fail at once.
+func (l *Logger) writeWithTimeout(p []byte) (int, error) {
+	if atomic.LoadInt32(&l.stalled) > 0 {
+		atomic.AddUint64(&l.stats.writeErrors, 1)
+		return 0, ErrWriteTimeout
+	}
+
+	// the caller may reuse p as soon as Write returns
+	b := make([]byte, len(p))
+	copy(b, p)
+	done := make(chan writeResult, 1)
+	go func() {
+		n, err := l.writeNow(b)
+		done <- writeResult{n, err}
+	}()
+
+	timer := time.NewTimer(l.WriteTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-timer.C:
+		atomic.AddInt32(&l.stalled, 1)
+		go func() {
+			<-done
+			atomic.AddInt32(&l.stalled, -1)
+		}()
+		atomic.AddUint64(&l.stats.writeErrors, 1)
+		return 0, ErrWriteTimeout
+	}
+}
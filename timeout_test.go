@@ -0,0 +1,49 @@
+package rolling
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stallWriter blocks writes until released.
+type stallWriter chan struct{}
+
+func (w stallWriter) Write(p []byte) (int, error) {
+	<-w
+	return len(p), nil
+}
+
+func TestWriteTimeout(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWriteTimeout", t)
+	defer os.RemoveAll(dir)
+
+	stall := make(stallWriter)
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100),
+		WithTee(stall), WithWriteTimeout(20*time.Millisecond))
+	isNil(err, t)
+	defer l.Close()
+
+	n, err := l.Write([]byte("boo!"))
+	equals(ErrWriteTimeout, err, t)
+	equals(0, n, t)
+
+	// further writes fail at once while the first is stuck
+	start := time.Now()
+	_, err = l.Write([]byte("foo!"))
+	equals(ErrWriteTimeout, err, t)
+	assert(time.Since(start) < 20*time.Millisecond, t, "write waited while stalled")
+
+	close(stall)
+	for i := 0; i < 100 && atomic.LoadInt32(&l.stalled) > 0; i++ {
+		<-time.After(10 * time.Millisecond)
+	}
+	equals(uint64(2), l.Stats().WriteErrors, t)
+	n, err = l.Write([]byte("bar!"))
+	isNil(err, t)
+	equals(4, n, t)
+	existsWithContent(logFile(dir), []byte("boo!bar!"), t)
+}
@@ -0,0 +1,85 @@
+package rolling
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WriteTrace describes where the time went in a single call to Write, so that
+// tail latency can be attributed to waiting for other writers, rotation
+// stalls, file I/O or fsyncs.
+type WriteTrace struct {
+	// Size is the number of bytes passed to Write.
+	Size int
+	// Wait is the time spent waiting for the write mutex.
+	Wait time.Duration
+	// Rotated reports whether the write had to rotate the file first, and
+	// Rotate how long that took.
+	Rotated bool
+	Rotate  time.Duration
+	// Write is the time spent writing to the file.
+	Write time.Duration
+	// Sync is the time spent waiting for an fsync with SyncEveryWrite.
+	Sync time.Duration
+	// Total is the time spent in Write, Err what it returned.
+	Total time.Duration
+	Err   error
+}
+
+type tracePhase int
+
+const (
+	traceWait tracePhase = iota
+	traceRotate
+	traceWrite
+	traceSync
+)
+
+// startTrace returns a trace to fill in for a write of size bytes, or nil if
+// tracing is off or this write isn't sampled.
+func (l *Logger) startTrace(size int) *WriteTrace {
+	if l.traceFn == nil {
+		return nil
+	}
+	if l.traceEvery > 1 && atomic.AddUint64(&l.stats.traceCount, 1)%l.traceEvery != 0 {
+		return nil
+	}
+	return &WriteTrace{Size: size}
+}
+
+// finishTrace reports tr, started at start, to the trace hook.
+func (l *Logger) finishTrace(tr *WriteTrace, start time.Time, err error) {
+	if tr == nil {
+		return
+	}
+	tr.Total = time.Since(start)
+	tr.Err = err
+	l.traceFn(*tr)
+}
+
+// now returns the current time if tr is being recorded, and the zero time
+// otherwise to save the clock read.
+func (tr *WriteTrace) now() time.Time {
+	if tr == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observe records the time since start as the duration of phase.
+func (tr *WriteTrace) observe(phase tracePhase, start time.Time) {
+	if tr == nil {
+		return
+	}
+	d := time.Since(start)
+	switch phase {
+	case traceWait:
+		tr.Wait = d
+	case traceRotate:
+		tr.Rotated, tr.Rotate = true, d
+	case traceWrite:
+		tr.Write = d
+	case traceSync:
+		tr.Sync = d
+	}
+}
@@ -0,0 +1,58 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteTrace(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWriteTrace", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var traces []WriteTrace
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithWriteTrace(func(tr WriteTrace) { traces = append(traces, tr) }, 2))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	for _, s := range []string{"a", "bb", "ccc", "dddddddd"} {
+		newFakeTime()
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+	_, err = l.Write(make([]byte, 11))
+	notNil(err, t)
+
+	equals(2, len(traces), t)
+	equals(2, traces[0].Size, t)
+	equals(false, traces[0].Rotated, t)
+	equals(8, traces[1].Size, t)
+	equals(true, traces[1].Rotated, t)
+	assert(traces[1].Total >= traces[1].Rotate+traces[1].Write, t, "inconsistent trace %+v", traces[1])
+
+	c, err := l.Clone(WithFilename("other.log"))
+	isNil(err, t)
+	defer func() {
+		err := c.Close()
+		if err != nil {
+			return
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		_, err = c.Write([]byte("x"))
+		isNil(err, t)
+	}
+	equals(3, len(traces), t)
+}
@@ -0,0 +1,40 @@
+package rolling
+
+import (
+	"errors"
+	"io"
+)
+
+var errTruncateAppendOnly = errors.New("the log file can't be truncated in append-only mode")
+
+// Truncate empties the log file in place, without rotating it, as a test
+// harness or a "clear logs" admin action may want: what it holds, buffered
+// writes included, is discarded, and the size and line counts rotation goes
+// by start over, as do the header and preallocated space of a new file. It
+// happens under the write lock, so every write ends up whole either before
+// or after it. It is refused with AppendOnly.
+func (l *Logger) Truncate() error {
+	if l.AppendOnly {
+		return errTruncateAppendOnly
+	}
+	l.lockWrite()
+	defer l.unlockWrite()
+	if l.file == nil {
+		return errClosed
+	}
+
+	l.buf = l.buf[:0]
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	// without O_APPEND, the next write would leave a hole up to the old
+	// offset
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	l.written = 0
+	l.lines = 0
+	l.midLine = false
+	l.preallocateFile()
+	return l.writeHeader()
+}
@@ -0,0 +1,35 @@
+package rolling
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestTruncate", t)
+	defer os.RemoveAll(dir)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(20),
+		WithBufferSize(100), WithFileHeader(func() []byte { return []byte("# hdr") }))
+	isNil(err, t)
+	defer l.Close()
+
+	_, err = l.Write([]byte("0123456789"))
+	isNil(err, t)
+	isNil(l.Truncate(), t)
+	// the buffered write is gone too
+	isNil(l.Flush(), t)
+	existsWithContent(logFile(dir), []byte("# hdr\n"), t)
+
+	// and the size starts over, so this doesn't rotate
+	_, err = l.Write([]byte("abcdefghij"))
+	isNil(err, t)
+	isNil(l.Flush(), t)
+	existsWithContent(logFile(dir), []byte("# hdr\nabcdefghij"), t)
+	fileCount(dir, 1, t)
+
+	isNil(l.Close(), t)
+	equals(errClosed, l.Truncate(), t)
+}
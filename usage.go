@@ -0,0 +1,86 @@
+package rolling
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// DiskUsage breaks down the space taken by a Logger's files, in bytes.
+type DiskUsage struct {
+	// Active is the size of the file currently written to.
+	Active int64 `json:"active"`
+	// Backups and Compressed are the sizes of the rotated files, before and
+	// after compression respectively.
+	Backups    int64 `json:"backups"`
+	Compressed int64 `json:"compressed"`
+	// Sidecars is the size of the files kept next to backups, named after
+	// the backup with an extra extension (such as app-<time>.log.sha256).
+	Sidecars int64 `json:"sidecars"`
+	// Free is the space left on the log volume, or -1 if it can't be
+	// determined on this platform.
+	Free int64 `json:"free"`
+}
+
+// Total returns the space taken by all of the Logger's files.
+func (u DiskUsage) Total() int64 {
+	return u.Active + u.Backups + u.Compressed + u.Sidecars
+}
+
+// DiskUsage reports how much space the Logger's files take up, in LogPath
+// and ArchiveDir, and how much is left on the volume of LogPath.
+func (l *Logger) DiskUsage() (DiskUsage, error) {
+	var u DiskUsage
+	if err := l.diskUsageIn(l.LogPath, &u); err != nil {
+		return DiskUsage{}, err
+	}
+	if l.backupDir() != l.LogPath {
+		if err := l.diskUsageIn(l.backupDir(), &u); err != nil {
+			return DiskUsage{}, err
+		}
+	}
+
+	var err error
+	if u.Free, err = diskFree(l.LogPath); err != nil {
+		u.Free = -1
+	}
+	return u, nil
+}
+
+// diskUsageIn adds the sizes of the Logger's files in dir to u.
+func (l *Logger) diskUsageIn(dir string, u *DiskUsage) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("can't read log file directory: %s", err)
+	}
+
+	prefix, ext := l.prefixAndExt()
+	backups := make(map[string]bool)
+	var rest []int
+	for i, f := range files {
+		switch {
+		case f.IsDir():
+		case f.Name() == l.Filename && dir == l.LogPath:
+			u.Active += f.Size()
+		default:
+			info, ok := l.parseBackupName(f.Name(), prefix, ext)
+			switch {
+			case !ok:
+				rest = append(rest, i)
+				continue
+			case info.compressed:
+				u.Compressed += f.Size()
+			default:
+				u.Backups += f.Size()
+			}
+			backups[f.Name()] = true
+		}
+	}
+	for _, i := range rest {
+		name := files[i].Name()
+		if backups[name[:len(name)-len(filepath.Ext(name))]] {
+			u.Sidecars += files[i].Size()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package rolling
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDiskUsage", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	backup := backupFile(dir)
+	isNil(ioutil.WriteFile(backup, []byte("12345"), 0644), t)
+	isNil(ioutil.WriteFile(backup+".sha256", []byte("123"), 0644), t)
+	newFakeTime()
+	isNil(ioutil.WriteFile(backupFile(dir)+compressSuffix, []byte("12"), 0644), t)
+	isNil(ioutil.WriteFile(logFile(dir)+".foo", []byte("not ours"), 0644), t)
+	isNil(os.Mkdir(backupFile(dir), 0700), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(100))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	u, err := l.DiskUsage()
+	isNil(err, t)
+	equals(int64(4), u.Active, t)
+	equals(int64(5), u.Backups, t)
+	equals(int64(2), u.Compressed, t)
+	equals(int64(3), u.Sidecars, t)
+	equals(int64(14), u.Total(), t)
+	assert(u.Free > 0, t, "expected free space, got %d", u.Free)
+}
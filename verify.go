@@ -0,0 +1,198 @@
+package rolling
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// quarantineDir is the subdirectory of the backup directory corrupt backups are moved to.
+// Being a directory, it is ignored by retention.
+const quarantineDir = "quarantine"
+
+// CorruptBackupError reports a backup which failed verification.
+type CorruptBackupError struct {
+	// Path is where the backup was found, Quarantined where it was moved to,
+	// or "" if moving it failed too.
+	Path        string
+	Quarantined string
+	Err         error
+}
+
+func (e *CorruptBackupError) Error() string {
+	return fmt.Sprintf("corrupt backup %s: %v", e.Path, e.Err)
+}
+
+func (e *CorruptBackupError) Unwrap() error {
+	return e.Err
+}
+
+// Verify reads every compressed or encrypted backup in full to check that it
+// is intact: gzip, zstd and lz4 checksums and recorded sizes must match,
+// every gzip member be complete, encrypted chunks must authenticate. Backups
+// that fail are moved to a quarantine subdirectory, out of the way of
+// retention and processing, and each is reported to the error handler as a
+// *CorruptBackupError. Verify returns the quarantined backups, as they were
+// before being moved.
+//
+// Only damaged content gets a backup quarantined. One that can't be read, as
+// when permissions or the disk fail, the key it was encrypted with can't be
+// had, or there are no age identities to open it with, is left in place, and
+// the first such error returned.
+//
+// Plain backups carry no checksum and aren't checked. Verify can be run
+// periodically, so that corruption is found when it happens rather than when
+// the backup is needed.
+func (l *Logger) Verify() ([]BackupInfo, error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	var corrupt []BackupInfo
+	for _, f := range files {
+		if !f.compressed && !f.encrypted {
+			continue
+		}
+		path := filepath.Join(l.backupDir(), f.Name())
+		damaged, errVerify := l.verifyBackup(path, f)
+		if errVerify == nil || os.IsNotExist(errVerify) {
+			continue
+		}
+		if !damaged {
+			if err == nil {
+				err = fmt.Errorf("can't verify %s: %v", f.Name(), errVerify)
+			}
+			continue
+		}
+		corrupt = append(corrupt, l.backupInfo(f))
+		cerr := &CorruptBackupError{Path: path, Err: errVerify}
+		if errMove := l.quarantine(path); errMove != nil {
+			if err == nil {
+				err = errMove
+			}
+		} else {
+			cerr.Quarantined = filepath.Join(l.backupDir(), quarantineDir, f.Name())
+		}
+		l.reportError(cerr)
+	}
+	return corrupt, err
+}
+
+// verifyBackup reads the backup at path, described by f, to the end, and
+// reports whether an error is down to damaged content rather than to the
+// backup being unreadable, or its key unavailable.
+func (l *Logger) verifyBackup(path string, f logInfo) (damaged bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	src := &readErrRecorder{r: file}
+	err = l.verifyContent(src, f)
+	if err == nil {
+		return false, nil
+	}
+	if src.err != nil {
+		return false, src.err
+	}
+	var kerr *keyError
+	if errors.As(err, &kerr) || errors.Is(err, errNoKeys) || errors.Is(err, errNoAgeIdentities) {
+		return false, err
+	}
+	return true, err
+}
+
+// readErrRecorder reads from r, keeping the first error other than io.EOF,
+// to tell a file which can't be read from one whose content is damaged.
+type readErrRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (rr *readErrRecorder) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	if err != nil && err != io.EOF && rr.err == nil {
+		rr.err = err
+	}
+	return n, err
+}
+
+// verifyContent reads the backup described by f from r to the end.
+func (l *Logger) verifyContent(r io.Reader, f logInfo) (err error) {
+	if f.encrypted {
+		if r, err = l.decrypt(r, f); err != nil {
+			return err
+		}
+	}
+	if !f.compressed {
+		_, err = io.Copy(ioutil.Discard, r)
+		return err
+	}
+
+	// gzip only stops at the end of a member if it can read byte by byte
+	br := bufio.NewReader(r)
+	c, err := detectCodec(br)
+	if err != nil {
+		return err
+	}
+	if c != gzipCodec {
+		// zstd and lz4 check frame checksums and content sizes themselves
+		dr, err := c.newReader(br)
+		if err != nil {
+			return err
+		}
+		defer dr.Close()
+		_, err = io.Copy(ioutil.Discard, dr)
+		return err
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return err
+	}
+	// read member by member, as only the first one records the size
+	gz.Multistream(false)
+	n, err := io.Copy(ioutil.Discard, gz)
+	if err != nil {
+		return err
+	}
+	if size, ok := sizeFromExtra(gz.Extra); ok && size != n {
+		return fmt.Errorf("uncompressed size is %d, expected %d", n, size)
+	}
+	for {
+		if err := gz.Reset(br); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		gz.Multistream(false)
+		if _, err := io.Copy(ioutil.Discard, gz); err != nil {
+			return err
+		}
+	}
+}
+
+// quarantine moves the file at path to the quarantine directory.
+func (l *Logger) quarantine(path string) error {
+	dir := filepath.Join(l.backupDir(), quarantineDir)
+	if err := os.MkdirAll(dir, l.dirMode()); err != nil {
+		return err
+	}
+	if err := l.rename(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+		return err
+	}
+	// the sidecar goes along, to tell whether the backup changed since
+	if err := os.Rename(path+checksumSuffix, filepath.Join(dir, filepath.Base(path)+checksumSuffix)); err != nil &&
+		!os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package rolling
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestVerify", t)
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return
+		}
+	}()
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		newFakeTime()
+		name := backupFile(dir)
+		isNil(ioutil.WriteFile(name, []byte("some log data, some log data\n"), 0644), t)
+		isNil(compressLogFile(name, name+compressSuffix, gzipCodec, 0), t)
+		names = append(names, name+compressSuffix)
+	}
+	b, err := ioutil.ReadFile(names[0])
+	isNil(err, t)
+	// two members, as left by an appending compressor, are fine
+	isNil(ioutil.WriteFile(names[1], append(b, b...), 0644), t)
+	// truncated
+	isNil(ioutil.WriteFile(names[2], b[:len(b)-6], 0644), t)
+	// flipped bit in the compressed data
+	b[len(b)-12] ^= 1
+	isNil(ioutil.WriteFile(names[3], b, 0644), t)
+
+	var reported []error
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithSynchronousMill(),
+		WithErrorHandler(func(err error) { reported = append(reported, err) }))
+	isNil(err, t)
+	defer func() {
+		err := l.Close()
+		if err != nil {
+			return
+		}
+	}()
+
+	corrupt, err := l.Verify()
+	isNil(err, t)
+	equals(2, len(corrupt), t)
+	equals(names[3], corrupt[0].Path, t)
+	equals(names[2], corrupt[1].Path, t)
+	equals(2, len(reported), t)
+
+	var cerr *CorruptBackupError
+	equals(true, errors.As(reported[0], &cerr), t)
+	equals(filepath.Join(dir, quarantineDir, filepath.Base(names[3])), cerr.Quarantined, t)
+	exists(cerr.Quarantined, t)
+	notExist(names[2], t)
+	notExist(names[3], t)
+	exists(names[0], t)
+	exists(names[1], t)
+
+	corrupt, err = l.Verify()
+	isNil(err, t)
+	equals(0, len(corrupt), t)
+}
+
+func TestVerifyMissingKey(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestVerifyMissingKey", t)
+	defer os.RemoveAll(dir)
+
+	// a sound backup encrypted with a key the Logger doesn't have
+	name := backupFile(dir)
+	isNil(ioutil.WriteFile(name, []byte("some log data\n"), 0644), t)
+	old, err := newKeyring([]EncryptionKey{testKey("old")})
+	isNil(err, t)
+	isNil(encryptLogFile(name, name+encryptSuffix, old), t)
+
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithSynchronousMill(),
+		WithEncryption(testKey("new")))
+	isNil(err, t)
+	defer l.Close()
+
+	corrupt, err := l.Verify()
+	notNil(err, t)
+	equals(0, len(corrupt), t)
+	exists(name+encryptSuffix, t)
+}
+
+func TestVerifyAgeWithoutIdentities(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestVerifyAgeWithoutIdentities", t)
+	defer os.RemoveAll(dir)
+
+	age := ageRecipientsOnly{fakeAge{recipient: "age1recipient"}}
+	l, err := NewWriter(WithLogPath(dir), WithFilename(logName()), WithMaxSize(10),
+		WithAgeEncryption(age), WithSynchronousMill())
+	isNil(err, t)
+	defer l.Close()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(""), t)
+	exists(backupFile(dir)+ageSuffix, t)
+
+	corrupt, err := l.Verify()
+	notNil(err, t)
+	equals(0, len(corrupt), t)
+	exists(backupFile(dir)+ageSuffix, t)
+}
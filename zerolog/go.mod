@@ -0,0 +1,17 @@
+module github.com/cnof/rolling/zerolog
+
+go 1.17
+
+require (
+	github.com/cnof/rolling v0.0.0-20261016004947-c07b60171102
+	github.com/rs/zerolog v1.33.0
+)
+
+require (
+	github.com/klauspost/compress v1.15.15 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/pierrec/lz4/v4 v4.1.18 // indirect
+	github.com/robfig/cron v1.2.0 // indirect
+	golang.org/x/sys v0.12.0 // indirect
+)
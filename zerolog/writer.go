@@ -0,0 +1,64 @@
+// Package zerolog adapts a rolling.Logger, or a rolling.LevelWriter to have
+// a file per level, to zerolog.LevelWriter. It is a module of its own, so
+// that using rolling doesn't bring in zerolog.
+package zerolog
+
+import (
+	"io"
+
+	"github.com/cnof/rolling"
+	zl "github.com/rs/zerolog"
+)
+
+var _ zl.LevelWriter = (*Writer)(nil)
+
+// Writer is a zerolog.LevelWriter that syncs the file as soon as an event at
+// or above a given level is written, while lower levels are left to the
+// write buffer, if any, and the usual syncing. It is used as
+//
+//	zerolog.New(NewWriter(l, zerolog.ErrorLevel))
+//
+// zerolog's diode.Writer only calls Write, so wrapping a Writer in one loses
+// the levels: wrap the Logger in the diode, or use rolling.WithAsync instead,
+// which keeps them. With a diode, rotation and the writes that go with it
+// happen on the diode's goroutine, so they never hold up the application but
+// make the diode more likely to drop messages while they last; closing the
+// diode closes the Logger too.
+type Writer struct {
+	w      io.Writer
+	syncAt zl.Level
+}
+
+// NewWriter returns a Writer writing to w, normally a *rolling.Logger or a
+// *rolling.LevelWriter, and syncing it after every event at level syncAt or
+// above.
+func NewWriter(w io.Writer, syncAt zl.Level) *Writer {
+	return &Writer{w: w, syncAt: syncAt}
+}
+
+// Write writes p, an event without a level, as is.
+func (z *Writer) Write(p []byte) (int, error) {
+	return z.w.Write(p)
+}
+
+// WriteLevel writes p, an event at the given level. Events without a level
+// known to rolling are written as Write does.
+func (z *Writer) WriteLevel(level zl.Level, p []byte) (int, error) {
+	lv, err := rolling.ParseLevel(level.String())
+	if err != nil {
+		return z.Write(p)
+	}
+	var n int
+	if lw, ok := z.w.(*rolling.LevelWriter); ok {
+		n, err = lw.WriteLevel(lv, p)
+	} else {
+		n, err = z.w.Write(p)
+	}
+	if err != nil || level < z.syncAt {
+		return n, err
+	}
+	if s, ok := z.w.(interface{ Sync() error }); ok {
+		return n, s.Sync()
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package zerolog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnof/rolling"
+	zl "github.com/rs/zerolog"
+)
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestWriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := rolling.NewWriter(rolling.WithLogPath(dir), rolling.WithFilename("foobar.log"),
+		rolling.WithBufferSize(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	log := zl.New(NewWriter(l, zl.ErrorLevel))
+
+	log.Info().Msg("info")
+	log.Log().Msg("no level")
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "foobar.log")); err != nil || len(b) != 0 {
+		t.Fatalf("buffered events written early: %q, %v", b, err)
+	}
+	if n := l.Stats().Syncs; n != 0 {
+		t.Fatalf("got %d syncs, want 0", n)
+	}
+
+	// an error is synced straight away, along with what came before
+	log.Error().Msg("error")
+	b, err := ioutil.ReadFile(filepath.Join(dir, "foobar.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"level":"info","message":"info"}` + "\n" +
+		`{"message":"no level"}` + "\n" +
+		`{"level":"error","message":"error"}` + "\n"
+	if string(b) != want {
+		t.Fatalf("got %q, want %q", b, want)
+	}
+	if n := l.Stats().Syncs; n != 1 {
+		t.Fatalf("got %d syncs, want 1", n)
+	}
+}
+
+func TestWriterLevels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestWriterLevels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lw, err := rolling.NewLevelWriter(nil, map[rolling.Level][]rolling.Option{
+		rolling.WarnLevel:  nil,
+		rolling.ErrorLevel: nil,
+	}, rolling.WithLogPath(dir), rolling.WithFilename("foobar.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lw.Close()
+	log := zl.New(NewWriter(lw, zl.ErrorLevel))
+
+	log.Warn().Msg("warn")
+	log.Error().Msg("error")
+	for name, want := range map[string]string{
+		"warn.log":  `{"level":"warn","message":"warn"}` + "\n",
+		"error.log": `{"level":"error","message":"error"}` + "\n",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Fatalf("%s: got %q, want %q", name, b, want)
+		}
+	}
+}